@@ -1,13 +1,26 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"gpu-cloud-manager/internal/api"
 	"gpu-cloud-manager/internal/config"
 	"gpu-cloud-manager/internal/database"
+	"gpu-cloud-manager/internal/lifecycle"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/notify"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/internal/secrets"
 	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/logging"
+	"gpu-cloud-manager/pkg/retry"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -16,20 +29,87 @@ import (
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: .env file not found")
+		slog.Warn(".env file not found")
 	}
 
 	// Load configuration
 	cfg := config.Load()
 
+	// Log JSON records at LOG_LEVEL; log.Printf output goes through the same logger
+	level, ok := logging.ParseLevel(cfg.LogLevel)
+	slog.SetDefault(logging.New(os.Stdout, level))
+	if !ok {
+		slog.Warn("Unknown LOG_LEVEL, logging at info", "log_level", cfg.LogLevel)
+	}
+
+	// Configure encryption of stored provider credentials
+	keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKeyID)
+	if err != nil {
+		fatal("Failed to load encryption keys", err)
+	}
+	if keyring == nil {
+		slog.Warn("ENCRYPTION_KEYS not set, provider credentials cannot be stored")
+	}
+	models.SetKeyring(keyring)
+
+	// Retry provider calls that are safe to repeat
+	retryPolicy := retry.DefaultPolicy()
+	retryPolicy.MaxRetries = cfg.UpstreamMaxRetries
+	retryPolicy.BaseDelay = cfg.UpstreamRetryBaseDelay
+	providers.SetRetryPolicy(retryPolicy)
+
 	// Initialize database
 	db, err := database.Initialize(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("Failed to initialize database", err)
+	}
+
+	// Metrics are only collected when enabled; services record nothing into a nil *Metrics
+	var m *metrics.Metrics
+	if cfg.EnableMetrics {
+		m = metrics.New()
 	}
 
 	// Initialize services
-	gpuService := services.NewGPUService(db, cfg)
+	providerPool := services.NewProviderPool(db, cfg, m)
+	gpuService := services.NewGPUService(db, cfg, providerPool, m)
+	m.WatchInstances(gpuService.RunningInstanceStats)
+	authService := services.NewAuthService(db)
+	credentialService := services.NewCredentialService(db, providerPool)
+	priceHistoryService := services.NewPriceHistoryService(db, cfg, m)
+	sender := notify.NewSender()
+	alertService := services.NewAlertService(db, cfg, gpuService.OfferCache(), sender, m)
+	idempotencyService := services.NewIdempotencyService(db, cfg, m)
+	idlePolicyService := services.NewIdlePolicyService(db, cfg, gpuService, m)
+	reportService := services.NewReportService(db)
+	budgetService := services.NewBudgetService(db, cfg, gpuService, sender, m)
+
+	if cfg.AdminAPIKey != "" {
+		if err := authService.EnsureAdminUser(cfg.AdminEmail, cfg.AdminAPIKey); err != nil {
+			fatal("Failed to bootstrap admin user", err)
+		}
+	}
+
+	// Background workers are started in this order and stopped in reverse on shutdown
+	workers := lifecycle.NewManager()
+	workers.Add("reconciler", services.NewReconciler(db, cfg, providerPool, m))
+
+	if cfg.PriceSnapshotInterval > 0 {
+		workers.Add("price_history", priceHistoryService)
+	}
+
+	if cfg.AlertRefreshInterval > 0 {
+		workers.Add("alerts", alertService)
+	}
+
+	workers.Add("idempotency", idempotencyService)
+
+	// Idle policies, expiry and budget caps are promises made to users, so these always run
+	workers.Add("idle_policies", idlePolicyService)
+	workers.Add("expiry", services.NewExpiryScheduler(db, cfg, gpuService, m))
+	workers.Add("budgets", budgetService)
+
+	workers.Start(context.Background())
 
 	// Setup Gin router
 	if cfg.Environment == "production" {
@@ -37,11 +117,21 @@ func main() {
 	}
 
 	router := gin.New()
-	router.Use(gin.Logger())
+	router.Use(api.RequestID())
+	router.Use(api.RequestLogger())
 	router.Use(gin.Recovery())
+	if m != nil {
+		router.Use(api.RequestMetrics(m))
+		router.GET("/metrics", gin.WrapH(m.Handler()))
+	}
+
+	var rateLimiter *api.RateLimiter
+	if cfg.RateLimitEnabled {
+		rateLimiter = api.NewRateLimiter(cfg.RateLimitRPM, cfg.RateLimitWriteRPM)
+	}
 
 	// Setup API routes
-	api.SetupRoutes(router, gpuService)
+	api.SetupRoutes(router, gpuService, authService, credentialService, priceHistoryService, alertService, idempotencyService, idlePolicyService, reportService, budgetService, rateLimiter)
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -53,13 +143,58 @@ func main() {
 	})
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "port", cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	// Wait for SIGINT or SIGTERM, then drain in-flight requests before stopping the
+	// workers and webhook deliveries and closing the database, so no request or
+	// delivery loses track of what it did
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := false
+	select {
+	case err := <-serveErr:
+		slog.Error("Server failed", "error", err)
+		failed = true
+	case <-signals.Done():
+		slog.Info("Shutting down, waiting for in-flight requests", "timeout", cfg.ShutdownTimeout.String())
 	}
+	stop()
 
-	log.Printf("Starting server on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("Error draining requests", "error", err)
+	}
+	if err := workers.Stop(ctx); err != nil {
+		slog.Error("Error stopping workers", "error", err)
+	}
+	if err := sender.Close(ctx); err != nil {
+		slog.Error("Error finishing webhook deliveries", "error", err)
+	}
+	if err := database.Close(db); err != nil {
+		slog.Error("Error closing database", "error", err)
 	}
+	slog.Info("Server stopped")
+
+	if failed {
+		cancel()
+		os.Exit(1)
+	}
+}
+
+// fatal logs why the server can't start and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
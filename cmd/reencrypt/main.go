@@ -0,0 +1,104 @@
+// Command reencrypt rewrites stored provider API keys under the active encryption key.
+//
+// Run it after adding a new key to ENCRYPTION_KEYS and pointing ENCRYPTION_ACTIVE_KEY_ID
+// at it; old keys can be removed once it completes. Plaintext keys stored before
+// encryption was enabled are encrypted as well.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/database"
+	"gpu-cloud-manager/internal/secrets"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+// storedCredential is a raw user_providers row, read without the encrypting serializer
+type storedCredential struct {
+	ID     uint
+	APIKey string
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report how many rows would change without writing them")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env file not found")
+	}
+
+	if err := run(config.Load(), *dryRun); err != nil {
+		log.Printf("Error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// run re-encrypts every stored credential that isn't under the active key yet. It fails
+// when some credentials can't be decrypted, after re-encrypting the rest.
+func run(cfg *config.Config, dryRun bool) error {
+	keyring, err := secrets.ParseKeyring(cfg.EncryptionKeys, cfg.EncryptionActiveKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to load encryption keys: %v", err)
+	}
+	if keyring == nil {
+		return errors.New("ENCRYPTION_KEYS must be set")
+	}
+
+	// Migrations would rewrite data of their own, which a re-encryption run (let alone a
+	// dry run) must not do
+	db, err := database.Open(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer database.Close(db)
+
+	var rows []storedCredential
+	if err := db.Table("user_providers").Select("id", "api_key").Order("id").Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load provider credentials: %v", err)
+	}
+
+	updated, failed := 0, 0
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			value, changed, err := keyring.Reencrypt(row.APIKey)
+			if err != nil {
+				log.Printf("Skipping credentials %d: %v", row.ID, err)
+				failed++
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			updated++
+			if dryRun {
+				continue
+			}
+			if err := tx.Table("user_providers").Where("id = ?", row.ID).Update("api_key", value).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt provider credentials: %v", err)
+	}
+
+	if dryRun {
+		log.Printf("%d of %d credentials would be re-encrypted with key %s", updated, len(rows), keyring.ActiveKeyID())
+	} else {
+		log.Printf("Re-encrypted %d of %d credentials with key %s", updated, len(rows), keyring.ActiveKeyID())
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d credentials could not be decrypted with the configured keys", failed)
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultDeliveryLimit is how many deliveries are listed when no limit is given
+const defaultDeliveryLimit = 50
+
+// AlertHandler handles price alert HTTP requests
+type AlertHandler struct {
+	alertService *services.AlertService
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(alertService *services.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+	}
+}
+
+// createdAlertRule includes the webhook secret, which is only returned when a rule is created
+type createdAlertRule struct {
+	*models.AlertRule
+	WebhookSecret string `json:"webhook_secret"`
+}
+
+// ListAlertRules returns the user's alert rules
+// @Summary List alert rules
+// @Description List the authenticated user's price alert rules
+// @Tags Alerts
+// @Produce json
+// @Success 200 {object} types.APIResponse{data=[]models.AlertRule}
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/alerts [get]
+func (h *AlertHandler) ListAlertRules(c *gin.Context) {
+	user := CurrentUser(c)
+
+	rules, err := h.alertService.List(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Alert rules retrieved successfully",
+		Data:    rules,
+	})
+}
+
+// GetAlertRule returns one of the user's alert rules
+// @Summary Get alert rule
+// @Description Get a price alert rule
+// @Tags Alerts
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} types.APIResponse{data=models.AlertRule}
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/alerts/{id} [get]
+func (h *AlertHandler) GetAlertRule(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+
+	user := CurrentUser(c)
+
+	rule, err := h.alertService.Get(c.Request.Context(), user.ID, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Alert rule retrieved successfully",
+		Data:    rule,
+	})
+}
+
+// CreateAlertRule adds a price alert rule for the user
+// @Summary Create alert rule
+// @Description Notify a webhook when an offer matching the filter is priced at or below the threshold. The response includes the secret webhook deliveries are signed with, which is not shown again.
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param request body types.AlertRuleRequest true "Alert rule"
+// @Success 201 {object} types.APIResponse{data=models.AlertRule}
+// @Failure 400 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/alerts [post]
+func (h *AlertHandler) CreateAlertRule(c *gin.Context) {
+	var req types.AlertRuleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	user := CurrentUser(c)
+
+	rule, err := h.alertService.Create(c.Request.Context(), user.ID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.APIResponse{
+		Success: true,
+		Message: "Alert rule created successfully",
+		Data:    createdAlertRule{AlertRule: rule, WebhookSecret: rule.WebhookSecret},
+	})
+}
+
+// UpdateAlertRule replaces an alert rule's settings
+// @Summary Update alert rule
+// @Description Replace a price alert rule's name, filter, threshold, webhook URL or enabled state
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Param request body types.AlertRuleRequest true "Alert rule"
+// @Success 200 {object} types.APIResponse{data=models.AlertRule}
+// @Failure 400 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/alerts/{id} [put]
+func (h *AlertHandler) UpdateAlertRule(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+
+	var req types.AlertRuleRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	user := CurrentUser(c)
+
+	rule, err := h.alertService.Update(c.Request.Context(), user.ID, id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Alert rule updated successfully",
+		Data:    rule,
+	})
+}
+
+// DeleteAlertRule removes an alert rule
+// @Summary Delete alert rule
+// @Description Remove a price alert rule and its delivery log
+// @Tags Alerts
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/alerts/{id} [delete]
+func (h *AlertHandler) DeleteAlertRule(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+
+	user := CurrentUser(c)
+
+	if err := h.alertService.Delete(c.Request.Context(), user.ID, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Alert rule deleted successfully",
+	})
+}
+
+// ListAlertDeliveries returns the delivery log of an alert rule
+// @Summary List alert deliveries
+// @Description List the most recent webhook notifications sent for a price alert rule
+// @Tags Alerts
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Param limit query int false "Maximum number of deliveries (default 50)"
+// @Success 200 {object} types.APIResponse{data=[]models.AlertDelivery}
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/alerts/{id}/deliveries [get]
+func (h *AlertHandler) ListAlertDeliveries(c *gin.Context) {
+	id, ok := alertRuleID(c)
+	if !ok {
+		return
+	}
+
+	user := CurrentUser(c)
+
+	deliveries, err := h.alertService.Deliveries(c.Request.Context(), user.ID, id, limitParam(c, defaultDeliveryLimit))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Alert deliveries retrieved successfully",
+		Data:    deliveries,
+	})
+}
+
+// alertRuleID parses the alert rule ID path parameter, responding with 400 when invalid
+func alertRuleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperr.New(apperr.InvalidArgument, "Invalid alert rule ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
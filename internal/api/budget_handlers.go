@@ -0,0 +1,378 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultNotificationLimit is how many budget notifications are listed when no limit is given
+const defaultNotificationLimit = 50
+
+// BudgetHandler handles team and budget HTTP requests
+type BudgetHandler struct {
+	budgetService *services.BudgetService
+}
+
+// NewBudgetHandler creates a new budget handler
+func NewBudgetHandler(budgetService *services.BudgetService) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService: budgetService,
+	}
+}
+
+// createdBudget includes the webhook secret, which is only returned when a budget is created
+type createdBudget struct {
+	*models.Budget
+	WebhookSecret string `json:"webhook_secret"`
+}
+
+// GetMyBudgets returns the budgets applying to the user
+// @Summary Get my budgets
+// @Description Get the budgets applying to the authenticated user, their own and their team's, with how much of each is used this month
+// @Tags Budgets
+// @Produce json
+// @Success 200 {object} types.APIResponse{data=[]models.Budget}
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/budget [get]
+func (h *BudgetHandler) GetMyBudgets(c *gin.Context) {
+	user := CurrentUser(c)
+
+	budgets, err := h.budgetService.ForUser(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Budgets retrieved successfully",
+		Data:    budgets,
+	})
+}
+
+// ListBudgets returns every budget
+// @Summary List budgets
+// @Description List every user and team budget with how much of it is used this month. Admin only.
+// @Tags Budgets
+// @Produce json
+// @Success 200 {object} types.APIResponse{data=[]models.Budget}
+// @Failure 403 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/budgets [get]
+func (h *BudgetHandler) ListBudgets(c *gin.Context) {
+	budgets, err := h.budgetService.List(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Budgets retrieved successfully",
+		Data:    budgets,
+	})
+}
+
+// GetBudget returns a budget
+// @Summary Get budget
+// @Description Get a budget with how much of it is used this month. Admin only.
+// @Tags Budgets
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Success 200 {object} types.APIResponse{data=models.Budget}
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/budgets/{id} [get]
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
+
+	budget, err := h.budgetService.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Budget retrieved successfully",
+		Data:    budget,
+	})
+}
+
+// CreateBudget adds a budget for a user or a team
+// @Summary Create budget
+// @Description Cap the monthly spend, GPUs in use and hourly spend of a user or a team. The response includes the secret threshold notifications are signed with, which is not shown again. Admin only.
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Param request body types.BudgetRequest true "Budget"
+// @Success 201 {object} types.APIResponse{data=models.Budget}
+// @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/budgets [post]
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	var req types.BudgetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	budget, err := h.budgetService.Create(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.APIResponse{
+		Success: true,
+		Message: "Budget created successfully",
+		Data:    createdBudget{Budget: budget, WebhookSecret: budget.WebhookSecret},
+	})
+}
+
+// UpdateBudget replaces a budget's limits
+// @Summary Update budget
+// @Description Replace a budget's limits, hard cap and webhook URL. The user or team it applies to can't be changed. Admin only.
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Param request body types.BudgetRequest true "Budget"
+// @Success 200 {object} types.APIResponse{data=models.Budget}
+// @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/budgets/{id} [put]
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
+
+	var req types.BudgetRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	budget, err := h.budgetService.Update(c.Request.Context(), id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Budget updated successfully",
+		Data:    budget,
+	})
+}
+
+// DeleteBudget removes a budget
+// @Summary Delete budget
+// @Description Remove a budget and its notification log. Admin only.
+// @Tags Budgets
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Success 200 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/budgets/{id} [delete]
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
+
+	if err := h.budgetService.Delete(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Budget deleted successfully",
+	})
+}
+
+// ListBudgetNotifications returns the notification log of a budget
+// @Summary List budget notifications
+// @Description List the most recent notifications sent when a budget's monthly spend reached 80% or 100% of its limit. Admin only.
+// @Tags Budgets
+// @Produce json
+// @Param id path int true "Budget ID"
+// @Param limit query int false "Maximum number of notifications (default 50)"
+// @Success 200 {object} types.APIResponse{data=[]models.BudgetNotification}
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/budgets/{id}/notifications [get]
+func (h *BudgetHandler) ListBudgetNotifications(c *gin.Context) {
+	id, ok := pathID(c, "id", "budget")
+	if !ok {
+		return
+	}
+
+	notifications, err := h.budgetService.Notifications(c.Request.Context(), id, limitParam(c, defaultNotificationLimit))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Budget notifications retrieved successfully",
+		Data:    notifications,
+	})
+}
+
+// ListTeams returns every team
+// @Summary List teams
+// @Description List every team. Admin only.
+// @Tags Budgets
+// @Produce json
+// @Success 200 {object} types.APIResponse{data=[]models.Team}
+// @Failure 403 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/teams [get]
+func (h *BudgetHandler) ListTeams(c *gin.Context) {
+	teams, err := h.budgetService.Teams(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Teams retrieved successfully",
+		Data:    teams,
+	})
+}
+
+// CreateTeam adds a team
+// @Summary Create team
+// @Description Create a team whose members can share a budget. Admin only.
+// @Tags Budgets
+// @Accept json
+// @Produce json
+// @Param request body types.TeamRequest true "Team"
+// @Success 201 {object} types.APIResponse{data=models.Team}
+// @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/teams [post]
+func (h *BudgetHandler) CreateTeam(c *gin.Context) {
+	var req types.TeamRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	team, err := h.budgetService.CreateTeam(c.Request.Context(), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.APIResponse{
+		Success: true,
+		Message: "Team created successfully",
+		Data:    team,
+	})
+}
+
+// AddTeamMember moves a user into a team
+// @Summary Add team member
+// @Description Move a user into a team, out of any team they were in. Admin only.
+// @Tags Budgets
+// @Produce json
+// @Param id path int true "Team ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} types.APIResponse
+// @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/teams/{id}/members/{user_id} [put]
+func (h *BudgetHandler) AddTeamMember(c *gin.Context) {
+	teamID, ok := pathID(c, "id", "team")
+	if !ok {
+		return
+	}
+	userID, ok := pathID(c, "user_id", "user")
+	if !ok {
+		return
+	}
+
+	if err := h.budgetService.SetTeamMember(c.Request.Context(), teamID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Team member added successfully",
+	})
+}
+
+// RemoveTeamMember takes a user out of a team
+// @Summary Remove team member
+// @Description Take a user out of a team. Admin only.
+// @Tags Budgets
+// @Produce json
+// @Param id path int true "Team ID"
+// @Param user_id path int true "User ID"
+// @Success 200 {object} types.APIResponse
+// @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/teams/{id}/members/{user_id} [delete]
+func (h *BudgetHandler) RemoveTeamMember(c *gin.Context) {
+	teamID, ok := pathID(c, "id", "team")
+	if !ok {
+		return
+	}
+	userID, ok := pathID(c, "user_id", "user")
+	if !ok {
+		return
+	}
+
+	if err := h.budgetService.RemoveTeamMember(c.Request.Context(), teamID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Team member removed successfully",
+	})
+}
+
+// pathID parses a numeric ID path parameter, responding with 400 when invalid
+func pathID(c *gin.Context, param, what string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid %s ID", what))
+		return 0, false
+	}
+	return uint(id), true
+}
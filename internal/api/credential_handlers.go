@@ -0,0 +1,194 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CredentialHandler handles provider credential HTTP requests
+type CredentialHandler struct {
+	credentialService *services.CredentialService
+}
+
+// NewCredentialHandler creates a new credential handler
+func NewCredentialHandler(credentialService *services.CredentialService) *CredentialHandler {
+	return &CredentialHandler{
+		credentialService: credentialService,
+	}
+}
+
+// ListCredentials returns the user's provider credentials
+// @Summary List provider credentials
+// @Description List the authenticated user's provider credentials (keys are never returned)
+// @Tags Credentials
+// @Produce json
+// @Success 200 {object} types.APIResponse{data=[]models.UserProvider}
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/credentials [get]
+func (h *CredentialHandler) ListCredentials(c *gin.Context) {
+	user := CurrentUser(c)
+
+	credentials, err := h.credentialService.List(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Credentials retrieved successfully",
+		Data:    credentials,
+	})
+}
+
+// CreateCredential adds provider credentials for the user
+// @Summary Add provider credentials
+// @Description Store an API key for a GPU provider
+// @Tags Credentials
+// @Accept json
+// @Produce json
+// @Param request body types.ProviderCredentialRequest true "Provider credentials"
+// @Success 201 {object} types.APIResponse{data=models.UserProvider}
+// @Failure 400 {object} types.APIResponse
+// @Failure 409 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/credentials [post]
+func (h *CredentialHandler) CreateCredential(c *gin.Context) {
+	var req types.ProviderCredentialRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	user := CurrentUser(c)
+
+	credential, err := h.credentialService.Create(c.Request.Context(), user.ID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, types.APIResponse{
+		Success: true,
+		Message: "Credentials created successfully",
+		Data:    credential,
+	})
+}
+
+// UpdateCredential rotates, enables or disables provider credentials
+// @Summary Update provider credentials
+// @Description Rotate the API key, enable/disable or reconfigure provider credentials
+// @Tags Credentials
+// @Accept json
+// @Produce json
+// @Param id path int true "Credential ID"
+// @Param request body types.UpdateProviderCredentialRequest true "Credential changes"
+// @Success 200 {object} types.APIResponse{data=models.UserProvider}
+// @Failure 400 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/credentials/{id} [put]
+func (h *CredentialHandler) UpdateCredential(c *gin.Context) {
+	id, ok := credentialID(c)
+	if !ok {
+		return
+	}
+
+	var req types.UpdateProviderCredentialRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	user := CurrentUser(c)
+
+	credential, err := h.credentialService.Update(c.Request.Context(), user.ID, id, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Credentials updated successfully",
+		Data:    credential,
+	})
+}
+
+// DeleteCredential removes provider credentials
+// @Summary Delete provider credentials
+// @Description Remove stored provider credentials
+// @Tags Credentials
+// @Produce json
+// @Param id path int true "Credential ID"
+// @Success 200 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/credentials/{id} [delete]
+func (h *CredentialHandler) DeleteCredential(c *gin.Context) {
+	id, ok := credentialID(c)
+	if !ok {
+		return
+	}
+
+	user := CurrentUser(c)
+
+	if err := h.credentialService.Delete(c.Request.Context(), user.ID, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Credentials deleted successfully",
+	})
+}
+
+// ValidateCredential checks provider credentials against the provider
+// @Summary Validate provider credentials
+// @Description Make an authenticated call to the provider to check the stored key
+// @Tags Credentials
+// @Produce json
+// @Param id path int true "Credential ID"
+// @Success 200 {object} types.APIResponse{data=types.CredentialValidation}
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/credentials/{id}/validate [post]
+func (h *CredentialHandler) ValidateCredential(c *gin.Context) {
+	id, ok := credentialID(c)
+	if !ok {
+		return
+	}
+
+	user := CurrentUser(c)
+
+	result, err := h.credentialService.Validate(c.Request.Context(), user.ID, id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Credentials validated",
+		Data:    result,
+	})
+}
+
+// credentialID parses the credential ID path parameter, responding with 400 when invalid
+func credentialID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(apperr.New(apperr.InvalidArgument, "Invalid credential ID"))
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler answers requests that failed without writing a response. Handlers and
+// middleware record the failure with c.Error and return (middleware also aborts); the
+// last recorded error is answered with the status and code of its apperr kind, and
+// unclassified errors with 500. A types.APIResponse set as the error's meta carries
+// data or metadata alongside the error. Must run before every handler that reports
+// errors this way.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		writeError(c)
+	}
+}
+
+// writeError writes the response for the last error recorded on c, unless a response
+// was already written
+func writeError(c *gin.Context) {
+	if c.Writer.Written() || len(c.Errors) == 0 {
+		return
+	}
+
+	last := c.Errors.Last()
+	response, _ := last.Meta.(types.APIResponse)
+	kind := apperr.KindOf(last.Err)
+	response.Success = false
+	response.Error = last.Err.Error()
+	response.Code = string(kind)
+
+	c.AbortWithStatusJSON(kind.HTTPStatus(), response)
+}
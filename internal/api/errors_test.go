@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", fmt.Errorf("lookup: %w", services.ErrInstanceNotFound), 404, "not_found"},
+		{"invalid argument", apperr.New(apperr.InvalidArgument, "invalid instance ID"), 400, "invalid_argument"},
+		{"provider rate limit", apperr.Wrap(apperr.RateLimited, errors.New("API error: 429")), 429, "rate_limited"},
+		{"provider rejected key", apperr.Wrap(apperr.ProviderUnauthorized, errors.New("API error: 401")), 502, "provider_unauthorized"},
+		{"provider down", apperr.Wrap(apperr.Unavailable, errors.New("connection refused")), 503, "unavailable"},
+		{"unclassified", errors.New("database is locked"), 500, "internal"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		router := gin.New()
+		router.Use(ErrorHandler())
+		router.GET("/instances", func(c *gin.Context) {
+			c.Error(tt.err)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/instances", nil))
+
+		var response types.APIResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: invalid response body: %v", tt.name, err)
+		}
+		if w.Code != tt.status || response.Code != tt.code || response.Error != tt.err.Error() || response.Success {
+			t.Errorf("%s: expected status %d with code %s, got %d %+v", tt.name, tt.status, tt.code, w.Code, response)
+		}
+	}
+}
+
+func TestErrorHandlerKeepsMetaAndWrittenResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/search", func(c *gin.Context) {
+		c.Error(services.ErrAllProvidersFailed).SetMeta(types.APIResponse{Meta: &types.SearchMeta{}})
+	})
+	router.GET("/written", func(c *gin.Context) {
+		c.Error(errors.New("logged only"))
+		c.JSON(http.StatusOK, types.APIResponse{Success: true})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/search", nil))
+	var response types.APIResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response body: %v", err)
+	}
+	if w.Code != http.StatusBadGateway || response.Meta == nil || response.Code != string(apperr.Upstream) {
+		t.Errorf("expected a 502 carrying the search metadata, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/written", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"success":true`) {
+		t.Errorf("expected the handler's response to be kept, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"strconv"
 
 	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
 	"gpu-cloud-manager/pkg/types"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultEventLimit is how many instance events or audit entries are listed when no limit is given
+const defaultEventLimit = 50
+
 // GPUHandler handles all GPU-related HTTP requests
 type GPUHandler struct {
 	gpuService *services.GPUService
@@ -28,15 +32,17 @@ func NewGPUHandler(gpuService *services.GPUService) *GPUHandler {
 // @Tags GPU
 // @Accept json
 // @Produce json
-// @Param provider query string false "GPU provider (vast_ai, runpod)"
+// @Param provider query string false "GPU provider (vast_ai, runpod, lambda_labs, paperspace)"
 // @Param gpu_model query string false "GPU model filter"
 // @Param min_gpu_count query int false "Minimum GPU count"
 // @Param max_price query number false "Maximum price per hour"
 // @Param region query string false "Region filter"
 // @Param available query bool false "Show only available instances"
-// @Success 200 {object} types.APIResponse{data=[]types.GPUInstance}
+// @Success 200 {object} types.APIResponse{data=[]types.GPUInstance,meta=types.SearchMeta}
+// @Header 200 {integer} X-Data-Age "Age in seconds of the oldest provider data used"
 // @Failure 400 {object} types.APIResponse
 // @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse{meta=types.SearchMeta}
 // @Router /api/v1/offers/search [get]
 func (h *GPUHandler) SearchOffers(c *gin.Context) {
 	var filter types.SearchFilter
@@ -67,12 +73,12 @@ func (h *GPUHandler) SearchOffers(c *gin.Context) {
 		}
 	}
 	
-	offers, err := h.gpuService.SearchOffers(&filter)
+	user := CurrentUser(c)
+	
+	offers, statuses, err := h.gpuService.SearchOffers(c.Request.Context(), user.ID, &filter)
+	setDataAge(c, statuses)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err).SetMeta(types.APIResponse{Meta: searchMeta(statuses)})
 		return
 	}
 	
@@ -80,6 +86,7 @@ func (h *GPUHandler) SearchOffers(c *gin.Context) {
 		Success: true,
 		Message: "Offers retrieved successfully",
 		Data:    offers,
+		Meta:    searchMeta(statuses),
 	})
 }
 
@@ -90,27 +97,26 @@ func (h *GPUHandler) SearchOffers(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param body body types.AdvancedSearchFilter true "Advanced search filters"
-// @Success 200 {object} types.APIResponse{data=[]types.GPUInstance}
+// @Success 200 {object} types.APIResponse{data=[]types.GPUInstance,meta=types.SearchMeta}
+// @Header 200 {integer} X-Data-Age "Age in seconds of the oldest provider data used"
 // @Failure 400 {object} types.APIResponse
 // @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse{meta=types.SearchMeta}
 // @Router /api/v1/offers/search/advanced [post]
 func (h *GPUHandler) SearchOffersAdvanced(c *gin.Context) {
 	var filter types.AdvancedSearchFilter
 	
 	if err := c.ShouldBindJSON(&filter); err != nil {
-		c.JSON(http.StatusBadRequest, types.APIResponse{
-			Success: false,
-			Error:   "Invalid request body: " + err.Error(),
-		})
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
 		return
 	}
 	
-	offers, err := h.gpuService.SearchOffersAdvanced(&filter)
+	user := CurrentUser(c)
+	
+	offers, statuses, err := h.gpuService.SearchOffersAdvanced(c.Request.Context(), user.ID, &filter)
+	setDataAge(c, statuses)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err).SetMeta(types.APIResponse{Meta: searchMeta(statuses)})
 		return
 	}
 	
@@ -118,6 +124,7 @@ func (h *GPUHandler) SearchOffersAdvanced(c *gin.Context) {
 		Success: true,
 		Message: "Advanced search completed successfully",
 		Data:    offers,
+		Meta:    searchMeta(statuses),
 	})
 }
 
@@ -130,12 +137,11 @@ func (h *GPUHandler) SearchOffersAdvanced(c *gin.Context) {
 // @Failure 500 {object} types.APIResponse
 // @Router /api/v1/instances [get]
 func (h *GPUHandler) GetInstances(c *gin.Context) {
-	instances, err := h.gpuService.GetInstances()
+	user := CurrentUser(c)
+	
+	instances, err := h.gpuService.GetInstances(c.Request.Context(), user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 	
@@ -155,16 +161,16 @@ func (h *GPUHandler) GetInstances(c *gin.Context) {
 // @Success 200 {object} types.APIResponse{data=types.GPUInstance}
 // @Failure 404 {object} types.APIResponse
 // @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse
+// @Failure 503 {object} types.APIResponse
 // @Router /api/v1/instances/{id} [get]
 func (h *GPUHandler) GetInstance(c *gin.Context) {
+	user := CurrentUser(c)
 	instanceID := c.Param("id")
 	
-	instance, err := h.gpuService.GetInstance(instanceID)
+	instance, err := h.gpuService.GetInstance(c.Request.Context(), user.ID, instanceID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 	
@@ -184,25 +190,24 @@ func (h *GPUHandler) GetInstance(c *gin.Context) {
 // @Param request body types.CreateInstanceRequest true "Instance creation request"
 // @Success 201 {object} types.APIResponse{data=types.GPUInstance}
 // @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
 // @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse
+// @Failure 503 {object} types.APIResponse
 // @Router /api/v1/instances [post]
 func (h *GPUHandler) CreateInstance(c *gin.Context) {
 	var req types.CreateInstanceRequest
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, types.APIResponse{
-			Success: false,
-			Error:   "Invalid request body: " + err.Error(),
-		})
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
 		return
 	}
 	
-	instance, err := h.gpuService.CreateInstance(&req)
+	user := CurrentUser(c)
+	
+	instance, err := h.gpuService.CreateInstance(c.Request.Context(), user.ID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 	
@@ -213,6 +218,43 @@ func (h *GPUHandler) CreateInstance(c *gin.Context) {
 	})
 }
 
+// ProvisionInstance rents the cheapest available offer matching a specification
+// @Summary Provision an instance by specification
+// @Description Search all providers for offers matching the filter and rent the best-ranked one that accepts, trying the next offer when one is refused
+// @Tags GPU
+// @Accept json
+// @Produce json
+// @Param request body types.ProvisionRequest true "Instance specification"
+// @Success 201 {object} types.APIResponse{data=types.ProvisionResult}
+// @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse{data=types.ProvisionResult}
+// @Router /api/v1/instances/provision [post]
+func (h *GPUHandler) ProvisionInstance(c *gin.Context) {
+	var req types.ProvisionRequest
+	
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+	
+	user := CurrentUser(c)
+	
+	result, err := h.gpuService.Provision(c.Request.Context(), user.ID, &req)
+	if err != nil {
+		c.Error(err).SetMeta(types.APIResponse{Data: result})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, types.APIResponse{
+		Success: true,
+		Message: "Instance provisioned successfully",
+		Data:    result,
+	})
+}
+
 // DestroyInstance terminates a GPU instance
 // @Summary Destroy a GPU instance
 // @Description Permanently terminate a GPU instance
@@ -220,18 +262,19 @@ func (h *GPUHandler) CreateInstance(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Instance ID"
 // @Success 200 {object} types.APIResponse
+// @Failure 400 {object} types.APIResponse
 // @Failure 404 {object} types.APIResponse
 // @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse
+// @Failure 503 {object} types.APIResponse
 // @Router /api/v1/instances/{id} [delete]
 func (h *GPUHandler) DestroyInstance(c *gin.Context) {
+	user := CurrentUser(c)
 	instanceID := c.Param("id")
 	
-	err := h.gpuService.DestroyInstance(instanceID)
+	err := h.gpuService.DestroyInstance(c.Request.Context(), user.ID, instanceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 	
@@ -248,18 +291,20 @@ func (h *GPUHandler) DestroyInstance(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Instance ID"
 // @Success 200 {object} types.APIResponse
+// @Failure 400 {object} types.APIResponse
+// @Failure 403 {object} types.APIResponse
 // @Failure 404 {object} types.APIResponse
 // @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse
+// @Failure 503 {object} types.APIResponse
 // @Router /api/v1/instances/{id}/start [post]
 func (h *GPUHandler) StartInstance(c *gin.Context) {
+	user := CurrentUser(c)
 	instanceID := c.Param("id")
 	
-	err := h.gpuService.StartInstance(instanceID)
+	err := h.gpuService.StartInstance(c.Request.Context(), user.ID, instanceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 	
@@ -276,18 +321,19 @@ func (h *GPUHandler) StartInstance(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Instance ID"
 // @Success 200 {object} types.APIResponse
+// @Failure 400 {object} types.APIResponse
 // @Failure 404 {object} types.APIResponse
 // @Failure 500 {object} types.APIResponse
+// @Failure 502 {object} types.APIResponse
+// @Failure 503 {object} types.APIResponse
 // @Router /api/v1/instances/{id}/stop [post]
 func (h *GPUHandler) StopInstance(c *gin.Context) {
+	user := CurrentUser(c)
 	instanceID := c.Param("id")
 	
-	err := h.gpuService.StopInstance(instanceID)
+	err := h.gpuService.StopInstance(c.Request.Context(), user.ID, instanceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err)
 		return
 	}
 	
@@ -297,15 +343,112 @@ func (h *GPUHandler) StopInstance(c *gin.Context) {
 	})
 }
 
+// UpdateInstanceExpiry changes when an instance expires
+// @Summary Update instance expiry
+// @Description Set, extend or clear the time at which an instance is automatically stopped or destroyed, or change which of the two happens
+// @Tags GPU
+// @Accept json
+// @Produce json
+// @Param id path string true "Instance ID"
+// @Param request body types.ExpiryRequest true "Expiry change"
+// @Success 200 {object} types.APIResponse{data=types.GPUInstance}
+// @Failure 400 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/instances/{id}/expiry [patch]
+func (h *GPUHandler) UpdateInstanceExpiry(c *gin.Context) {
+	var req types.ExpiryRequest
+	
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+	
+	user := CurrentUser(c)
+	instanceID := c.Param("id")
+	
+	instance, err := h.gpuService.UpdateExpiry(c.Request.Context(), user.ID, instanceID, &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Instance expiry updated successfully",
+		Data:    instance,
+	})
+}
+
+// ListInstanceEvents returns recent events of an instance
+// @Summary List instance events
+// @Description List the most recent events of a GPU instance, such as warnings that its idle policy is about to stop it
+// @Tags GPU
+// @Produce json
+// @Param id path string true "Instance ID"
+// @Param limit query int false "Maximum number of events (default 50)"
+// @Success 200 {object} types.APIResponse{data=[]models.InstanceEvent}
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/instances/{id}/events [get]
+func (h *GPUHandler) ListInstanceEvents(c *gin.Context) {
+	user := CurrentUser(c)
+	instanceID := c.Param("id")
+	
+	events, err := h.gpuService.InstanceEvents(c.Request.Context(), user.ID, instanceID, limitParam(c, defaultEventLimit))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Instance events retrieved successfully",
+		Data:    events,
+	})
+}
+
+// GetAuditLog returns recent automated actions on the user's instances
+// @Summary Get audit log
+// @Description List the most recent actions the system took on the user's instances, such as stops by idle policies, including failed attempts
+// @Tags GPU
+// @Produce json
+// @Param limit query int false "Maximum number of entries (default 50)"
+// @Success 200 {object} types.APIResponse{data=[]models.AuditEntry}
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/audit [get]
+func (h *GPUHandler) GetAuditLog(c *gin.Context) {
+	user := CurrentUser(c)
+	
+	entries, err := h.gpuService.AuditLog(c.Request.Context(), user.ID, limitParam(c, defaultEventLimit))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Audit log retrieved successfully",
+		Data:    entries,
+	})
+}
+
 // GetProviders returns supported GPU providers
 // @Summary Get supported providers
 // @Description Get list of supported GPU cloud providers with details
 // @Tags GPU
 // @Produce json
 // @Success 200 {object} types.APIResponse{data=[]types.ProviderInfo}
+// @Failure 500 {object} types.APIResponse
 // @Router /api/v1/providers [get]
 func (h *GPUHandler) GetProviders(c *gin.Context) {
-	providers := h.gpuService.GetSupportedProviders()
+	user := CurrentUser(c)
+	
+	providers, err := h.gpuService.GetSupportedProviders(c.Request.Context(), user.ID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 	
 	c.JSON(http.StatusOK, types.APIResponse{
 		Success: true,
@@ -336,16 +479,17 @@ func (h *GPUHandler) GetGPUModels(c *gin.Context) {
 // @Description Get aggregated statistics about the GPU marketplace
 // @Tags GPU
 // @Produce json
-// @Success 200 {object} types.APIResponse{data=types.MarketplaceStats}
+// @Success 200 {object} types.APIResponse{data=types.MarketplaceStats,meta=types.SearchMeta}
+// @Header 200 {integer} X-Data-Age "Age in seconds of the oldest provider data used"
 // @Failure 500 {object} types.APIResponse
 // @Router /api/v1/marketplace/stats [get]
 func (h *GPUHandler) GetMarketplaceStats(c *gin.Context) {
-	stats, err := h.gpuService.GetMarketplaceStats()
+	user := CurrentUser(c)
+	
+	stats, statuses, err := h.gpuService.GetMarketplaceStats(c.Request.Context(), user.ID)
+	setDataAge(c, statuses)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, types.APIResponse{
-			Success: false,
-			Error:   err.Error(),
-		})
+		c.Error(err).SetMeta(types.APIResponse{Meta: searchMeta(statuses)})
 		return
 	}
 	
@@ -353,5 +497,35 @@ func (h *GPUHandler) GetMarketplaceStats(c *gin.Context) {
 		Success: true,
 		Message: "Marketplace statistics retrieved successfully",
 		Data:    stats,
+		Meta:    searchMeta(statuses),
 	})
 }
+
+// searchMeta wraps per-provider search statuses for the response, omitting it when empty
+func searchMeta(statuses []types.ProviderSearchStatus) interface{} {
+	if statuses == nil {
+		return nil
+	}
+	return types.SearchMeta{
+		Providers:      statuses,
+		DataAgeSeconds: int64(services.DataAge(statuses).Seconds()),
+	}
+}
+
+// setDataAge reports how old the offers behind a response are, as the offer cache may
+// serve them without asking the providers
+func setDataAge(c *gin.Context, statuses []types.ProviderSearchStatus) {
+	if statuses == nil {
+		return
+	}
+	c.Header("X-Data-Age", strconv.FormatInt(int64(services.DataAge(statuses).Seconds()), 10))
+}
+
+// limitParam parses the limit query parameter, using defaultLimit when it is missing
+// or outside 1-500
+func limitParam(c *gin.Context, defaultLimit int) int {
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 && n <= 500 {
+		return n
+	}
+	return defaultLimit
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader lets clients retry mutating requests without repeating them
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayHeader marks responses replayed from an earlier request
+	idempotentReplayHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength matches the size of the stored key column
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyStore reserves keys and stores responses; implemented by services.IdempotencyService
+type idempotencyStore interface {
+	Begin(ctx context.Context, userID uint, key, requestHash string) (*models.IdempotencyRecord, bool, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord, statusCode int, body []byte) error
+	Release(ctx context.Context, record *models.IdempotencyRecord) error
+}
+
+// Idempotency makes mutating requests sent with an Idempotency-Key safe to retry. The
+// first response for a key is stored and replayed for repeats of the same request,
+// except 429 responses, which release the key for the retry they ask for;
+// reusing a key for a different request is rejected with 409. Must run after ErrorHandler
+// and RequireAPIKey.
+func Idempotency(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return idempotency(idempotencyService)
+}
+
+func idempotency(store idempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.Error(apperr.Errorf(apperr.InvalidArgument, "Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(apperr.Errorf(apperr.InvalidArgument, "Error reading request body: %v", err))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		user := CurrentUser(c)
+		record, replay, err := store.Begin(c.Request.Context(), user.ID, key, requestHash(c.Request, body))
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		if replay {
+			c.Header(idempotentReplayHeader, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", []byte(record.ResponseBody))
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Whatever happens to the client, the outcome of the request must be kept
+		ctx := context.WithoutCancel(c.Request.Context())
+		completed := false
+		defer func() {
+			if !completed {
+				if err := store.Release(ctx, record); err != nil {
+					slog.ErrorContext(ctx, "Idempotency: error releasing key", "error", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		// Errors the handler recorded are answered here so their response is stored too
+		writeError(c)
+		if recorder.Written() && !refusedBeforeChanges(recorder.Status()) {
+			completed = true
+			if err := store.Complete(ctx, record, recorder.Status(), recorder.body.Bytes()); err != nil {
+				slog.ErrorContext(ctx, "Idempotency: error storing response", "error", err)
+			}
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body while writing it to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// refusedBeforeChanges reports whether a response with status means the request was
+// turned away before anything changed, in which case it isn't stored so a retry runs
+// the request again. Only 429s qualify, from this API's limits or a provider's: a 5xx
+// may follow a provider call that took effect, e.g. an instance created before the
+// connection dropped, and repeating the request could rent a second one.
+func refusedBeforeChanges(status int) bool {
+	return status == http.StatusTooManyRequests
+}
+
+// isMutating reports whether requests with the method change state
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestHash fingerprints a request so a key can't be reused for a different one
+func requestHash(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, req.Method+" "+req.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
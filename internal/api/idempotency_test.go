@@ -0,0 +1,212 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore keeps idempotency records in a map keyed by user and key
+type memoryIdempotencyStore struct {
+	records map[string]*models.IdempotencyRecord
+}
+
+func (s *memoryIdempotencyStore) Begin(ctx context.Context, userID uint, key, requestHash string) (*models.IdempotencyRecord, bool, error) {
+	if existing, ok := s.records[key]; ok {
+		switch {
+		case existing.RequestHash != requestHash:
+			return nil, false, services.ErrIdempotencyKeyReused
+		case existing.StatusCode == 0:
+			return nil, false, services.ErrIdempotencyInProgress
+		}
+		return existing, true, nil
+	}
+	record := &models.IdempotencyRecord{UserID: userID, Key: key, RequestHash: requestHash}
+	s.records[key] = record
+	return record, false, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, record *models.IdempotencyRecord, statusCode int, body []byte) error {
+	record.StatusCode = statusCode
+	record.ResponseBody = string(body)
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	delete(s.records, record.Key)
+	return nil
+}
+
+func TestIdempotencyReplaysResponses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+
+	created := 0
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(func(c *gin.Context) {
+		c.Set(userContextKey, &models.User{ID: 1})
+	})
+	router.Use(idempotency(store))
+	router.POST("/instances", func(c *gin.Context) {
+		created++
+		c.JSON(http.StatusCreated, gin.H{"id": created})
+	})
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/instances", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("key-1", `{"offer_id":"1"}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `{"id":1}` {
+		t.Fatalf("unexpected first response %d %s", first.Code, first.Body.String())
+	}
+
+	retry := send("key-1", `{"offer_id":"1"}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != `{"id":1}` || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected the original response to be replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+	if created != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", created)
+	}
+
+	if conflict := send("key-1", `{"offer_id":"2"}`); conflict.Code != http.StatusConflict {
+		t.Errorf("expected reusing a key for another request to conflict, got %d", conflict.Code)
+	}
+
+	if other := send("", `{"offer_id":"1"}`); other.Code != http.StatusCreated || created != 2 {
+		t.Errorf("expected requests without a key to run, got %d", other.Code)
+	}
+
+	if long := send(strings.Repeat("k", 256), `{}`); long.Code != http.StatusBadRequest {
+		t.Errorf("expected an overlong key to be rejected, got %d", long.Code)
+	}
+}
+
+func TestIdempotencyReleasesUnansweredRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(func(c *gin.Context) {
+		c.Set(userContextKey, &models.User{ID: 1})
+	})
+	router.Use(idempotency(store))
+	router.POST("/instances", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	req := httptest.NewRequest("POST", "/instances", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(store.records) != 0 {
+		t.Errorf("expected the key to be released after a panic, got %v", store.records)
+	}
+}
+
+func TestIdempotencyReleasesRateLimitedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+
+	calls := 0
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(func(c *gin.Context) {
+		c.Set(userContextKey, &models.User{ID: 1})
+	})
+	router.Use(idempotency(store))
+	router.POST("/instances", func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			c.Error(apperr.Wrap(apperr.RateLimited, errors.New("API error 429")))
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("POST", "/instances", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if calls != 2 || store.records["key-1"].StatusCode != http.StatusCreated {
+		t.Errorf("expected the retry after a 429 to run and be stored, ran %d times", calls)
+	}
+}
+
+func TestIdempotencyStoresServerErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Each of these may follow a provider call that took effect
+	failures := []error{
+		apperr.Wrap(apperr.Unavailable, errors.New("error making request: connection reset by peer")),
+		apperr.Errorf(apperr.Upstream, "Vast.ai failed while renting the offer, it may still have been rented"),
+		errors.New("instance vast_1 was created but could not be recorded"),
+	}
+	for _, failure := range failures {
+		store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+		calls := 0
+		router := gin.New()
+		router.Use(ErrorHandler())
+		router.Use(func(c *gin.Context) {
+			c.Set(userContextKey, &models.User{ID: 1})
+		})
+		router.Use(idempotency(store))
+		router.POST("/instances", func(c *gin.Context) {
+			calls++
+			c.Error(failure)
+		})
+
+		var first, retry *httptest.ResponseRecorder
+		for _, w := range []**httptest.ResponseRecorder{&first, &retry} {
+			req := httptest.NewRequest("POST", "/instances", strings.NewReader(`{}`))
+			req.Header.Set(IdempotencyKeyHeader, "key-1")
+			*w = httptest.NewRecorder()
+			router.ServeHTTP(*w, req)
+		}
+
+		if calls != 1 || retry.Code != first.Code || retry.Header().Get("Idempotent-Replayed") != "true" {
+			t.Errorf("%v: expected the %d response to be replayed without running again, ran %d times", failure, first.Code, calls)
+		}
+	}
+}
+
+func TestIdempotencyStoresDefiniteFailures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyRecord)}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(func(c *gin.Context) {
+		c.Set(userContextKey, &models.User{ID: 1})
+	})
+	router.Use(idempotency(store))
+	router.POST("/instances", func(c *gin.Context) {
+		c.Error(services.ErrBudgetExceeded)
+	})
+
+	req := httptest.NewRequest("POST", "/instances", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if record := store.records["key-1"]; record == nil || record.StatusCode != http.StatusForbidden || !strings.Contains(record.ResponseBody, "permission_denied") {
+		t.Errorf("expected the 403 response to be stored, got %+v", record)
+	}
+}
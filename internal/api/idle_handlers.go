@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdlePolicyHandler handles instance idle policy HTTP requests
+type IdlePolicyHandler struct {
+	idlePolicyService *services.IdlePolicyService
+}
+
+// NewIdlePolicyHandler creates a new idle policy handler
+func NewIdlePolicyHandler(idlePolicyService *services.IdlePolicyService) *IdlePolicyHandler {
+	return &IdlePolicyHandler{
+		idlePolicyService: idlePolicyService,
+	}
+}
+
+// GetIdlePolicy returns an instance's idle policy
+// @Summary Get idle policy
+// @Description Get the policy that stops or destroys an instance when it is idle
+// @Tags GPU
+// @Produce json
+// @Param id path string true "Instance ID"
+// @Success 200 {object} types.APIResponse{data=models.IdlePolicy}
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/instances/{id}/idle-policy [get]
+func (h *IdlePolicyHandler) GetIdlePolicy(c *gin.Context) {
+	user := CurrentUser(c)
+
+	policy, err := h.idlePolicyService.Get(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Idle policy retrieved successfully",
+		Data:    policy,
+	})
+}
+
+// SetIdlePolicy creates or replaces an instance's idle policy
+// @Summary Set idle policy
+// @Description Stop the instance once its GPU utilization has stayed below the threshold for idle_minutes, and/or destroy it once it has been stopped for destroy_after_stopped_hours. A warning event is recorded grace_minutes before either happens. Utilization is only reported by RunPod.
+// @Tags GPU
+// @Accept json
+// @Produce json
+// @Param id path string true "Instance ID"
+// @Param request body types.IdlePolicyRequest true "Idle policy"
+// @Success 200 {object} types.APIResponse{data=models.IdlePolicy}
+// @Failure 400 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/instances/{id}/idle-policy [put]
+func (h *IdlePolicyHandler) SetIdlePolicy(c *gin.Context) {
+	var req types.IdlePolicyRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Errorf(apperr.InvalidArgument, "Invalid request body: %v", err))
+		return
+	}
+
+	user := CurrentUser(c)
+
+	policy, err := h.idlePolicyService.Set(c.Request.Context(), user.ID, c.Param("id"), &req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Idle policy saved successfully",
+		Data:    policy,
+	})
+}
+
+// DeleteIdlePolicy removes an instance's idle policy
+// @Summary Delete idle policy
+// @Description Stop enforcing an idle policy on an instance
+// @Tags GPU
+// @Produce json
+// @Param id path string true "Instance ID"
+// @Success 200 {object} types.APIResponse
+// @Failure 404 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/instances/{id}/idle-policy [delete]
+func (h *IdlePolicyHandler) DeleteIdlePolicy(c *gin.Context) {
+	user := CurrentUser(c)
+
+	if err := h.idlePolicyService.Delete(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Idle policy deleted successfully",
+	})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MarketHandler handles marketplace price history HTTP requests
+type MarketHandler struct {
+	priceHistory *services.PriceHistoryService
+}
+
+// NewMarketHandler creates a new market handler
+func NewMarketHandler(priceHistory *services.PriceHistoryService) *MarketHandler {
+	return &MarketHandler{
+		priceHistory: priceHistory,
+	}
+}
+
+// GetPriceHistory returns recorded prices of a GPU model over time
+// @Summary Get price history
+// @Description Get the min, max, median and percentile hourly prices of a GPU model per time interval
+// @Tags Marketplace
+// @Produce json
+// @Param gpu_model query string true "GPU model"
+// @Param provider query string false "Provider"
+// @Param interval query string false "Bucket size such as 15m, 1h or 1d (default 1h)"
+// @Param from query string false "Start of the range, RFC 3339 (default 7 days before to)"
+// @Param to query string false "End of the range, RFC 3339 (default now)"
+// @Success 200 {object} types.APIResponse{data=types.PriceHistory}
+// @Failure 400 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/marketplace/history [get]
+func (h *MarketHandler) GetPriceHistory(c *gin.Context) {
+	query := services.PriceHistoryQuery{
+		GPUModel: c.Query("gpu_model"),
+		Provider: types.GPUProvider(c.Query("provider")),
+	}
+
+	var err error
+	query.Interval, err = services.ParseHistoryInterval(c.DefaultQuery("interval", "1h"))
+	if err == nil {
+		query.From, err = parseTimeParam(c, "from")
+	}
+	if err == nil {
+		query.To, err = parseTimeParam(c, "to")
+	}
+	if err != nil {
+		c.Error(apperr.Wrap(apperr.InvalidArgument, err))
+		return
+	}
+
+	history, err := h.priceHistory.History(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Price history retrieved successfully",
+		Data:    history,
+	})
+}
+
+// parseTimeParam reads an optional RFC 3339 query parameter, also accepting a date
+// (YYYY-MM-DD) for midnight UTC
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err := time.Parse(time.DateOnly, value); err == nil {
+			return t, nil
+		}
+		return time.Time{}, errors.New("Invalid " + name + ": expected an RFC 3339 time or a date")
+	}
+	return t, nil
+}
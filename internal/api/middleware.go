@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userContextKey is the Gin context key holding the authenticated *models.User
+const userContextKey = "user"
+
+// RequestIDHeader carries the ID tying a request to its log records
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients
+const maxRequestIDLength = 128
+
+var (
+	// errAPIKeyRequired is reported for requests to authenticated routes without an API key
+	errAPIKeyRequired = apperr.New(apperr.Unauthenticated, "API key required")
+
+	// errAdminRequired is reported for requests to admin routes from other users
+	errAdminRequired = apperr.New(apperr.PermissionDenied, "Admin access required")
+)
+
+// authenticator resolves API keys to users; implemented by services.AuthService
+type authenticator interface {
+	Authenticate(ctx context.Context, apiKey string) (*models.User, error)
+}
+
+// RequireAPIKey authenticates requests using the Authorization or X-API-Key header.
+// Must run after ErrorHandler.
+func RequireAPIKey(authService *services.AuthService) gin.HandlerFunc {
+	return requireAPIKey(authService)
+}
+
+func requireAPIKey(authService authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := extractAPIKey(c.Request)
+		if apiKey == "" {
+			c.Error(errAPIKeyRequired)
+			c.Abort()
+			return
+		}
+
+		user, err := authService.Authenticate(c.Request.Context(), apiKey)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Set(userContextKey, user)
+		c.Next()
+	}
+}
+
+// RequireAdmin rejects requests from users who aren't admins. It must run after RequireAPIKey.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := CurrentUser(c); user == nil || !user.IsAdmin {
+			c.Error(errAdminRequired)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser returns the authenticated user stored by RequireAPIKey
+func CurrentUser(c *gin.Context) *models.User {
+	if value, exists := c.Get(userContextKey); exists {
+		if user, ok := value.(*models.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// extractAPIKey reads the API key from "Authorization: Bearer <key>" or "X-API-Key"
+func extractAPIKey(req *http.Request) string {
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+			return strings.TrimSpace(auth[7:])
+		}
+	}
+
+	return strings.TrimSpace(req.Header.Get("X-API-Key"))
+}
+
+// RequestMetrics records the count and latency of every request by its route pattern.
+// Requests matching no route are recorded under "unmatched".
+func RequestMetrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// RequestID tags the request's context with the ID sent in X-Request-ID, or a new one
+// when it is missing or malformed, and echoes it in the response. Records logged with
+// the request's context, including those of provider calls, carry the ID.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs of letters, digits and the separators - _ . :
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 128-bit request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestLogger logs every request once it has been handled, with its status, latency
+// and user. Server errors are logged as errors. Must run after RequestID.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if user := CurrentUser(c); user != nil {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(user.ID)))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(c.Request.Context(), level, "Request", attrs...)
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExtractAPIKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{"bearer token", map[string]string{"Authorization": "Bearer abc123"}, "abc123"},
+		{"lowercase bearer", map[string]string{"Authorization": "bearer abc123"}, "abc123"},
+		{"x-api-key header", map[string]string{"X-API-Key": "xyz789"}, "xyz789"},
+		{"bearer takes precedence", map[string]string{"Authorization": "Bearer abc123", "X-API-Key": "xyz789"}, "abc123"},
+		{"non-bearer authorization", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, ""},
+		{"no headers", map[string]string{}, ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/v1/instances", nil)
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+
+		if got := extractAPIKey(req); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
+
+// stubAuthenticator resolves keys like services.AuthService, from a map of users by key
+type stubAuthenticator struct {
+	users map[string]*models.User
+}
+
+func (a *stubAuthenticator) Authenticate(ctx context.Context, apiKey string) (*models.User, error) {
+	user, ok := a.users[apiKey]
+	if !ok || !user.IsActive {
+		return nil, services.ErrInvalidAPIKey
+	}
+	return user, nil
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := &stubAuthenticator{users: map[string]*models.User{
+		"active-key":      {ID: 1, IsActive: true},
+		"deactivated-key": {ID: 2, IsActive: false},
+	}}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(requireAPIKey(auth))
+	router.GET("/instances", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user_id": CurrentUser(c).ID})
+	})
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected int
+		body     string
+	}{
+		{"missing key", map[string]string{}, 401, "API key required"},
+		{"unknown key", map[string]string{"X-API-Key": "unknown-key"}, 401, "invalid or inactive API key"},
+		{"deactivated key", map[string]string{"Authorization": "Bearer deactivated-key"}, 401, "invalid or inactive API key"},
+		{"active key", map[string]string{"Authorization": "Bearer active-key"}, 200, `"user_id":1`},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/instances", nil)
+		for key, value := range tt.headers {
+			req.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.expected || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s: expected status %d with %q, got %d %s", tt.name, tt.expected, tt.body, w.Code, w.Body.String())
+		}
+		if tt.expected == 401 && !strings.Contains(w.Body.String(), `"code":"unauthenticated"`) {
+			t.Errorf("%s: expected the unauthenticated code, got %s", tt.name, w.Body.String())
+		}
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	if CurrentUser(c) != nil {
+		t.Error("expected no user before authentication")
+	}
+
+	user := &models.User{ID: 7, Email: "test@example.com"}
+	c.Set(userContextKey, user)
+
+	if got := CurrentUser(c); got == nil || got.ID != 7 {
+		t.Errorf("expected user 7, got %v", got)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		expected int
+	}{
+		{"admin", &models.User{ID: 1, IsAdmin: true}, 200},
+		{"regular user", &models.User{ID: 2}, 403},
+		{"no user", nil, 403},
+	}
+
+	for _, tt := range tests {
+		router := gin.New()
+		router.Use(ErrorHandler())
+		router.Use(func(c *gin.Context) {
+			if tt.user != nil {
+				c.Set(userContextKey, tt.user)
+			}
+		})
+		router.GET("/budgets", RequireAdmin(), func(c *gin.Context) { c.Status(200) })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/budgets", nil))
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, w.Code)
+		}
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var seen string
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/instances", func(c *gin.Context) {
+		seen = logging.RequestID(c.Request.Context())
+		c.Status(200)
+	})
+
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{"propagated", "req-123", "req-123"},
+		{"missing", "", ""},
+		{"malformed", "bad id\n", ""},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/instances", nil)
+		if tt.header != "" {
+			req.Header.Set(RequestIDHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		got := w.Header().Get(RequestIDHeader)
+		if got == "" || got != seen {
+			t.Errorf("%s: expected the response header %q to match the context's request ID %q", tt.name, got, seen)
+		}
+		if tt.expected != "" && got != tt.expected {
+			t.Errorf("%s: expected request ID %q, got %q", tt.name, tt.expected, got)
+		}
+		if tt.expected == "" && len(got) != 32 {
+			t.Errorf("%s: expected a generated request ID, got %q", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"gpu-cloud-manager/pkg/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitSweepInterval is how often buckets that have refilled are dropped
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket holds the tokens left for one client and kind of request
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimitResult is the outcome of taking a token from a bucket
+type rateLimitResult struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	reset      time.Time     // when the bucket is full again
+	retryAfter time.Duration // until the next token, when not allowed
+}
+
+// RateLimiter keeps a token bucket per client for read requests and another for
+// mutating ones. Each bucket holds a minute's worth of requests and refills
+// continuously, so clients can burst up to their per-minute limit.
+type RateLimiter struct {
+	readRPM  int
+	writeRPM int
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewRateLimiter creates a limiter allowing readRPM read and writeRPM mutating
+// requests per minute per client. A limit of 0 leaves that kind of request unlimited.
+func NewRateLimiter(readRPM, writeRPM int) *RateLimiter {
+	return &RateLimiter{
+		readRPM:  readRPM,
+		writeRPM: writeRPM,
+		buckets:  make(map[string]*tokenBucket),
+		now:      time.Now,
+	}
+}
+
+// take spends a token from the bucket for key, which refills at limit tokens per minute
+func (l *RateLimiter) take(key string, limit int) rateLimitResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	capacity := float64(limit)
+	perSecond := capacity / 60
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*perSecond)
+	bucket.updated = now
+
+	result := rateLimitResult{limit: limit}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.allowed = true
+	} else {
+		result.retryAfter = time.Duration((1 - bucket.tokens) / perSecond * float64(time.Second))
+	}
+	result.remaining = int(bucket.tokens)
+	result.reset = now.Add(time.Duration((capacity - bucket.tokens) / perSecond * float64(time.Second)))
+	return result
+}
+
+// refund gives back a token taken from the bucket for key
+func (l *RateLimiter) refund(key string, limit int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if bucket, ok := l.buckets[key]; ok {
+		bucket.tokens = math.Min(float64(limit), bucket.tokens+1)
+	}
+}
+
+// sweep drops buckets untouched for long enough to have refilled, which are
+// indistinguishable from new ones. Must be called with l.mu held.
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.updated) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit rejects requests over the user's limit with 429 and reports the limit in
+// X-RateLimit-* headers. Must run after RequireAPIKey.
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		kind, limit := "read", limiter.readRPM
+		if isMutating(c.Request.Method) {
+			kind, limit = "write", limiter.writeRPM
+		}
+		user := CurrentUser(c)
+		if limit <= 0 || user == nil {
+			c.Next()
+			return
+		}
+
+		result := limiter.take(fmt.Sprintf("%s:user:%d", kind, user.ID), limit)
+		setRateLimitHeaders(c, result)
+		if !result.allowed {
+			abortRateLimited(c, kind, result)
+			return
+		}
+		c.Next()
+	}
+}
+
+// RateLimitUnauthenticated limits requests that fail authentication to the read limit
+// per minute per IP address, so API keys can't be guessed at any rate. Every request
+// takes a token before its key is checked, so guesses in flight at once count too, and
+// gets it back once it authenticates. Once the limit is spent the IP's requests are
+// rejected with 429. Must run before RequireAPIKey.
+func RateLimitUnauthenticated(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := limiter.readRPM
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		key := "unauthenticated:ip:" + c.ClientIP()
+		if result := limiter.take(key, limit); !result.allowed {
+			setRateLimitHeaders(c, result)
+			abortRateLimited(c, "unauthenticated", result)
+			return
+		}
+
+		c.Next()
+
+		if CurrentUser(c) != nil {
+			limiter.refund(key, limit)
+		}
+	}
+}
+
+// setRateLimitHeaders reports the state of the client's bucket in X-RateLimit-* headers
+func setRateLimitHeaders(c *gin.Context, result rateLimitResult) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(result.reset.Unix(), 10))
+}
+
+// abortRateLimited rejects a request over the limit for kind with 429
+func abortRateLimited(c *gin.Context, kind string, result rateLimitResult) {
+	retryAfter := int(math.Ceil(result.retryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.Error(apperr.Errorf(apperr.RateLimited, "Rate limit of %d %s requests per minute exceeded, retry in %d seconds", result.limit, kind, retryAfter))
+	c.Abort()
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitedRouter serves GET and POST /offers behind limiter, authenticating
+// requests that carry an X-User header as user 1
+func rateLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(RateLimitUnauthenticated(limiter))
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("X-User") != "" {
+			c.Set(userContextKey, &models.User{ID: 1})
+		}
+	})
+	router.Use(RateLimit(limiter))
+	router.GET("/offers", func(c *gin.Context) { c.Status(200) })
+	router.POST("/offers", func(c *gin.Context) { c.Status(201) })
+	return router
+}
+
+func send(router *gin.Engine, method string, user bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/offers", nil)
+	if user {
+		req.Header.Set("X-User", "1")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, 1)
+	limiter.now = func() time.Time { return now }
+	router := rateLimitedRouter(limiter)
+
+	for i := 0; i < 2; i++ {
+		if w := send(router, "GET", true); w.Code != 200 {
+			t.Fatalf("read %d: expected status 200, got %d", i, w.Code)
+		}
+	}
+
+	w := send(router, "GET", true)
+	if w.Code != 429 {
+		t.Fatalf("expected status 429 once the read budget is spent, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("unexpected rate limit headers %v", w.Header())
+	}
+
+	// Writes and other clients have budgets of their own
+	if w := send(router, "POST", true); w.Code != 201 {
+		t.Errorf("expected a write to use its own budget, got status %d", w.Code)
+	}
+	if w := send(router, "POST", true); w.Code != 429 {
+		t.Errorf("expected status 429 once the write budget is spent, got %d", w.Code)
+	}
+	if w := send(router, "GET", false); w.Code != 200 {
+		t.Errorf("expected an unauthenticated request to be limited by IP separately, got status %d", w.Code)
+	}
+
+	// Half a minute refills one read token
+	now = now.Add(30 * time.Second)
+	if w := send(router, "GET", true); w.Code != 200 {
+		t.Errorf("expected a read to be allowed after the bucket refilled, got status %d", w.Code)
+	}
+	if w := send(router, "GET", true); w.Code != 429 {
+		t.Errorf("expected only one token to have refilled, got status %d", w.Code)
+	}
+}
+
+func TestRateLimitUnauthenticated(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(2, 10)
+	limiter.now = func() time.Time { return now }
+	router := rateLimitedRouter(limiter)
+
+	// Authenticated requests don't use up the IP's limit
+	for i := 0; i < 2; i++ {
+		if w := send(router, "POST", true); w.Code != 201 {
+			t.Fatalf("write %d: expected status 201, got %d", i, w.Code)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send(router, "POST", false); w.Code != 201 {
+			t.Fatalf("unauthenticated request %d: expected status 201, got %d", i, w.Code)
+		}
+	}
+	w := send(router, "POST", false)
+	if w.Code != 429 || w.Header().Get("Retry-After") != "30" {
+		t.Errorf("expected status 429 once the IP's limit is spent, got %d %v", w.Code, w.Header())
+	}
+
+	now = now.Add(30 * time.Second)
+	if w := send(router, "POST", false); w.Code != 201 {
+		t.Errorf("expected a request to be allowed after the bucket refilled, got status %d", w.Code)
+	}
+}
+
+func TestRateLimitUnauthenticatedInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(2, 10)
+	entered, release := make(chan struct{}), make(chan struct{})
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.Use(RateLimitUnauthenticated(limiter))
+	router.GET("/offers", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(401)
+	})
+
+	// Requests still waiting on their key check count against the limit
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- send(router, "GET", false).Code }()
+		<-entered
+	}
+	if w := send(router, "GET", false); w.Code != 429 {
+		t.Errorf("expected status 429 while the IP's limit is held by requests in flight, got %d", w.Code)
+	}
+
+	close(release)
+	for i := 0; i < 2; i++ {
+		if code := <-done; code != 401 {
+			t.Errorf("expected the requests in flight to finish with status 401, got %d", code)
+		}
+	}
+}
+
+func TestRateLimitUnlimited(t *testing.T) {
+	router := rateLimitedRouter(NewRateLimiter(1, 0))
+
+	for i := 0; i < 5; i++ {
+		w := send(router, "POST", true)
+		if w.Code != 201 || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("write %d: expected writes to be unlimited, got status %d", i, w.Code)
+		}
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(10, 10)
+	limiter.now = func() time.Time { return now }
+
+	limiter.take("read:user:1", 10)
+	now = now.Add(30 * time.Second)
+	limiter.take("read:user:2", 10)
+
+	now = now.Add(45 * time.Second)
+	limiter.take("read:user:2", 10)
+	if _, ok := limiter.buckets["read:user:1"]; ok {
+		t.Error("expected the refilled bucket to be dropped")
+	}
+	if _, ok := limiter.buckets["read:user:2"]; !ok {
+		t.Error("expected the active bucket to be kept")
+	}
+}
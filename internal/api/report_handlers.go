@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gpu-cloud-manager/internal/services"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler handles spend report HTTP requests
+type ReportHandler struct {
+	reportService *services.ReportService
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(reportService *services.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+	}
+}
+
+// GetSpendReport returns what was spent on instances over a period
+// @Summary Get spend report
+// @Description Get the cost of running instances over a period, grouped by user, provider, GPU model or label. Admins see all users' spend, other users their own.
+// @Tags Reports
+// @Produce json
+// @Produce text/csv
+// @Param group_by query string false "user, provider, gpu_model or label (default user)"
+// @Param from query string false "Start of the period, RFC 3339 or YYYY-MM-DD (default start of the month)"
+// @Param to query string false "End of the period, RFC 3339 or YYYY-MM-DD (default now)"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} types.APIResponse{data=types.SpendReport}
+// @Failure 400 {object} types.APIResponse
+// @Failure 500 {object} types.APIResponse
+// @Router /api/v1/reports/spend [get]
+func (h *ReportHandler) GetSpendReport(c *gin.Context) {
+	query := services.SpendQuery{
+		GroupBy: c.Query("group_by"),
+	}
+
+	format := c.DefaultQuery("format", "json")
+	var err error
+	if format != "json" && format != "csv" {
+		err = errors.New("Invalid format: expected json or csv")
+	}
+	if err == nil {
+		query.From, err = parseTimeParam(c, "from")
+	}
+	if err == nil {
+		query.To, err = parseTimeParam(c, "to")
+	}
+	if err != nil {
+		c.Error(apperr.Wrap(apperr.InvalidArgument, err))
+		return
+	}
+
+	report, err := h.reportService.Spend(c.Request.Context(), CurrentUser(c), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if format == "csv" {
+		filename := "spend_" + report.GroupBy + "_" + report.From.Format("20060102") + "_" + report.To.Format("20060102") + ".csv"
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := writeSpendCSV(c.Writer, report); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, types.APIResponse{
+		Success: true,
+		Message: "Spend report generated successfully",
+		Data:    report,
+	})
+}
+
+// writeSpendCSV writes one row per group of a spend report, under a header naming
+// the grouping
+func writeSpendCSV(w io.Writer, report *types.SpendReport) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{report.GroupBy, "cost", "running_hours", "stopped_hours", "instances"})
+	for _, group := range report.Groups {
+		writer.Write([]string{
+			group.Key,
+			strconv.FormatFloat(group.Cost, 'f', 2, 64),
+			strconv.FormatFloat(group.RunningHours, 'f', 2, 64),
+			strconv.FormatFloat(group.StoppedHours, 'f', 2, 64),
+			strconv.Itoa(group.Instances),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestWriteSpendCSV(t *testing.T) {
+	report := &types.SpendReport{
+		GroupBy: "label",
+		Groups: []types.SpendGroup{
+			{Key: "training, large", Cost: 9, RunningHours: 4, StoppedHours: 8.5, Instances: 2},
+			{Key: "", Cost: 0.5, RunningHours: 0.25, Instances: 1},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSpendCSV(&buf, report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "label,cost,running_hours,stopped_hours,instances\n" +
+		"\"training, large\",9.00,4.00,8.50,2\n" +
+		",0.50,0.25,0.00,1\n"
+	if buf.String() != expected {
+		t.Errorf("expected CSV %q, got %q", expected, buf.String())
+	}
+}
@@ -7,12 +7,27 @@ import (
 )
 
 // SetupRoutes configures all API routes
-func SetupRoutes(router *gin.Engine, gpuService *services.GPUService) {
+func SetupRoutes(router *gin.Engine, gpuService *services.GPUService, authService *services.AuthService, credentialService *services.CredentialService, priceHistoryService *services.PriceHistoryService, alertService *services.AlertService, idempotencyService *services.IdempotencyService, idlePolicyService *services.IdlePolicyService, reportService *services.ReportService, budgetService *services.BudgetService, rateLimiter *RateLimiter) {
 	// Create handlers
 	gpuHandler := NewGPUHandler(gpuService)
+	credentialHandler := NewCredentialHandler(credentialService)
+	marketHandler := NewMarketHandler(priceHistoryService)
+	alertHandler := NewAlertHandler(alertService)
+	idlePolicyHandler := NewIdlePolicyHandler(idlePolicyService)
+	reportHandler := NewReportHandler(reportService)
+	budgetHandler := NewBudgetHandler(budgetService)
 	
 	// API version 1
 	v1 := router.Group("/api/v1")
+	v1.Use(ErrorHandler())
+	if rateLimiter != nil {
+		v1.Use(RateLimitUnauthenticated(rateLimiter))
+	}
+	v1.Use(RequireAPIKey(authService))
+	if rateLimiter != nil {
+		v1.Use(RateLimit(rateLimiter))
+	}
+	v1.Use(Idempotency(idempotencyService))
 	{
 		// GPU Offers routes
 		offers := v1.Group("/offers")
@@ -26,10 +41,65 @@ func SetupRoutes(router *gin.Engine, gpuService *services.GPUService) {
 		{
 			instances.GET("", gpuHandler.GetInstances)
 			instances.POST("", gpuHandler.CreateInstance)
+			instances.POST("/provision", gpuHandler.ProvisionInstance)
 			instances.GET("/:id", gpuHandler.GetInstance)
 			instances.DELETE("/:id", gpuHandler.DestroyInstance)
 			instances.POST("/:id/start", gpuHandler.StartInstance)
 			instances.POST("/:id/stop", gpuHandler.StopInstance)
+			instances.PATCH("/:id/expiry", gpuHandler.UpdateInstanceExpiry)
+			instances.GET("/:id/events", gpuHandler.ListInstanceEvents)
+			instances.GET("/:id/idle-policy", idlePolicyHandler.GetIdlePolicy)
+			instances.PUT("/:id/idle-policy", idlePolicyHandler.SetIdlePolicy)
+			instances.DELETE("/:id/idle-policy", idlePolicyHandler.DeleteIdlePolicy)
+		}
+		
+		// Provider credentials routes
+		credentials := v1.Group("/credentials")
+		{
+			credentials.GET("", credentialHandler.ListCredentials)
+			credentials.POST("", credentialHandler.CreateCredential)
+			credentials.PUT("/:id", credentialHandler.UpdateCredential)
+			credentials.DELETE("/:id", credentialHandler.DeleteCredential)
+			credentials.POST("/:id/validate", credentialHandler.ValidateCredential)
+		}
+		
+		// Price alert routes
+		alerts := v1.Group("/alerts")
+		{
+			alerts.GET("", alertHandler.ListAlertRules)
+			alerts.POST("", alertHandler.CreateAlertRule)
+			alerts.GET("/:id", alertHandler.GetAlertRule)
+			alerts.PUT("/:id", alertHandler.UpdateAlertRule)
+			alerts.DELETE("/:id", alertHandler.DeleteAlertRule)
+			alerts.GET("/:id/deliveries", alertHandler.ListAlertDeliveries)
+		}
+		
+		// Automated actions taken on the user's instances
+		v1.GET("/audit", gpuHandler.GetAuditLog)
+		
+		// Report routes
+		reports := v1.Group("/reports")
+		{
+			reports.GET("/spend", reportHandler.GetSpendReport)
+		}
+		
+		// Budget routes; budgets and teams are managed by admins
+		v1.GET("/budget", budgetHandler.GetMyBudgets)
+		budgets := v1.Group("/budgets", RequireAdmin())
+		{
+			budgets.GET("", budgetHandler.ListBudgets)
+			budgets.POST("", budgetHandler.CreateBudget)
+			budgets.GET("/:id", budgetHandler.GetBudget)
+			budgets.PUT("/:id", budgetHandler.UpdateBudget)
+			budgets.DELETE("/:id", budgetHandler.DeleteBudget)
+			budgets.GET("/:id/notifications", budgetHandler.ListBudgetNotifications)
+		}
+		teams := v1.Group("/teams", RequireAdmin())
+		{
+			teams.GET("", budgetHandler.ListTeams)
+			teams.POST("", budgetHandler.CreateTeam)
+			teams.PUT("/:id/members/:user_id", budgetHandler.AddTeamMember)
+			teams.DELETE("/:id/members/:user_id", budgetHandler.RemoveTeamMember)
 		}
 		
 		// Providers and Models routes
@@ -40,14 +110,15 @@ func SetupRoutes(router *gin.Engine, gpuService *services.GPUService) {
 		marketplace := v1.Group("/marketplace")
 		{
 			marketplace.GET("/stats", gpuHandler.GetMarketplaceStats)
+			marketplace.GET("/history", marketHandler.GetPriceHistory)
 		}
 	}
 	
 	// CORS middleware (if enabled)
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key, Idempotency-Key, X-Request-ID")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
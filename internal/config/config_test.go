@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadDefaultConfig(t *testing.T) {
@@ -34,6 +35,38 @@ func TestLoadDefaultConfig(t *testing.T) {
 	if cfg.RateLimitRPM != 100 {
 		t.Errorf("Expected default rate limit RPM to be 100, got %d", cfg.RateLimitRPM)
 	}
+
+	if cfg.RateLimitWriteRPM != 20 {
+		t.Errorf("Expected default write rate limit RPM to be 20, got %d", cfg.RateLimitWriteRPM)
+	}
+
+	if cfg.ReconcileInterval != time.Minute {
+		t.Errorf("Expected default reconcile interval to be 1m, got %s", cfg.ReconcileInterval)
+	}
+
+	if cfg.ShutdownTimeout != 30*time.Second {
+		t.Errorf("Expected default shutdown timeout to be 30s, got %s", cfg.ShutdownTimeout)
+	}
+
+	if cfg.UpstreamTimeout != 30*time.Second {
+		t.Errorf("Expected default upstream timeout to be 30s, got %s", cfg.UpstreamTimeout)
+	}
+
+	if cfg.UpstreamMaxRetries != 2 {
+		t.Errorf("Expected default upstream max retries to be 2, got %d", cfg.UpstreamMaxRetries)
+	}
+
+	if cfg.UpstreamRetryBaseDelay != 500*time.Millisecond {
+		t.Errorf("Expected default upstream retry base delay to be 500ms, got %s", cfg.UpstreamRetryBaseDelay)
+	}
+
+	if cfg.PriceSnapshotInterval != 15*time.Minute {
+		t.Errorf("Expected default price snapshot interval to be 15m, got %s", cfg.PriceSnapshotInterval)
+	}
+
+	if cfg.OfferCacheTTL != time.Minute || cfg.OfferCacheStale != 5*time.Minute {
+		t.Errorf("Expected default offer cache TTL 1m and stale window 5m, got %s and %s", cfg.OfferCacheTTL, cfg.OfferCacheStale)
+	}
 }
 
 func TestLoadConfigFromEnv(t *testing.T) {
@@ -46,6 +79,7 @@ func TestLoadConfigFromEnv(t *testing.T) {
 	os.Setenv("ENABLE_CORS", "false")
 	os.Setenv("RATE_LIMIT_ENABLED", "false")
 	os.Setenv("RATE_LIMIT_RPM", "200")
+	os.Setenv("RATE_LIMIT_WRITE_RPM", "50")
 	os.Setenv("LOG_LEVEL", "debug")
 
 	cfg := Load()
@@ -82,6 +116,10 @@ func TestLoadConfigFromEnv(t *testing.T) {
 		t.Errorf("Expected rate limit RPM to be 200, got %d", cfg.RateLimitRPM)
 	}
 
+	if cfg.RateLimitWriteRPM != 50 {
+		t.Errorf("Expected write rate limit RPM to be 50, got %d", cfg.RateLimitWriteRPM)
+	}
+
 	if cfg.LogLevel != "debug" {
 		t.Errorf("Expected log level to be 'debug', got %s", cfg.LogLevel)
 	}
@@ -170,3 +208,80 @@ func TestGetIntEnv(t *testing.T) {
 
 	os.Unsetenv("TEST_INT_VAR")
 }
+
+func TestGetDurationEnv(t *testing.T) {
+	// Test with valid duration
+	os.Setenv("TEST_DURATION_VAR", "90s")
+	result := getDurationEnv("TEST_DURATION_VAR", time.Minute)
+	if result != 90*time.Second {
+		t.Errorf("Expected 90s, got %s", result)
+	}
+
+	// Test with invalid and non-positive durations (should return default)
+	for _, value := range []string{"invalid", "0s", "-5m"} {
+		os.Setenv("TEST_DURATION_VAR", value)
+		result = getDurationEnv("TEST_DURATION_VAR", time.Minute)
+		if result != time.Minute {
+			t.Errorf("Expected default value 1m for %q, got %s", value, result)
+		}
+	}
+
+	// Test with non-existent environment variable
+	result = getDurationEnv("NON_EXISTENT_DURATION_VAR", 5*time.Minute)
+	if result != 5*time.Minute {
+		t.Errorf("Expected default value 5m for non-existent var, got %s", result)
+	}
+
+	os.Unsetenv("TEST_DURATION_VAR")
+}
+
+func TestGetOptionalDurationEnv(t *testing.T) {
+	tests := map[string]time.Duration{
+		"0":       0,
+		"0s":      0,
+		"90s":     90 * time.Second,
+		"-5m":     time.Minute,
+		"invalid": time.Minute,
+	}
+	for value, expected := range tests {
+		os.Setenv("TEST_DURATION_VAR", value)
+		if result := getOptionalDurationEnv("TEST_DURATION_VAR", time.Minute); result != expected {
+			t.Errorf("For %q, expected %s, got %s", value, expected, result)
+		}
+	}
+	os.Unsetenv("TEST_DURATION_VAR")
+
+	if result := getOptionalDurationEnv("NON_EXISTENT_DURATION_VAR", time.Minute); result != time.Minute {
+		t.Errorf("Expected default value 1m for non-existent var, got %s", result)
+	}
+}
+
+func TestLoadDisablesOfferCache(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("OFFER_CACHE_TTL", "0")
+	defer os.Clearenv()
+
+	if cfg := Load(); cfg.OfferCacheTTL != 0 {
+		t.Errorf("Expected OFFER_CACHE_TTL=0 to disable the offer cache, got %s", cfg.OfferCacheTTL)
+	}
+}
+
+func TestLoadDisablesPriceSnapshots(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("PRICE_SNAPSHOT_INTERVAL", "0")
+	defer os.Clearenv()
+
+	if cfg := Load(); cfg.PriceSnapshotInterval != 0 {
+		t.Errorf("Expected PRICE_SNAPSHOT_INTERVAL=0 to disable price snapshots, got %s", cfg.PriceSnapshotInterval)
+	}
+}
+
+func TestLoadDisablesAlertRefresh(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("ALERT_REFRESH_INTERVAL", "0")
+	defer os.Clearenv()
+
+	if cfg := Load(); cfg.AlertRefreshInterval != 0 {
+		t.Errorf("Expected ALERT_REFRESH_INTERVAL=0 to disable background alert refresh, got %s", cfg.AlertRefreshInterval)
+	}
+}
@@ -13,6 +13,22 @@ import (
 
 // Initialize sets up the database connection and runs migrations
 func Initialize(cfg *config.Config) (*gorm.DB, error) {
+	db, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Run auto migrations
+	if err := runMigrations(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %v", err)
+	}
+	
+	return db, nil
+}
+
+// Open sets up the database connection without running migrations, for tools that
+// must leave the schema and its data as they are
+func Open(cfg *config.Config) (*gorm.DB, error) {
 	// Configure GORM logger based on environment
 	var logLevel logger.LogLevel
 	switch cfg.Environment {
@@ -25,8 +41,10 @@ func Initialize(cfg *config.Config) (*gorm.DB, error) {
 	}
 	
 	// Open database connection
+	// Translated errors let services recognise unique constraint violations as gorm.ErrDuplicatedKey
 	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{
-		Logger: logger.Default.LogLevel(logLevel),
+		Logger:         logger.Default.LogMode(logLevel),
+		TranslateError: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %v", err)
@@ -42,21 +60,54 @@ func Initialize(cfg *config.Config) (*gorm.DB, error) {
 	sqlDB.SetMaxIdleConns(10)
 	sqlDB.SetMaxOpenConns(100)
 	
-	// Run auto migrations
-	if err := runMigrations(db); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %v", err)
-	}
-	
 	return db, nil
 }
 
+// Close closes the database's connection pool
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying sql.DB: %v", err)
+	}
+	return sqlDB.Close()
+}
+
 // runMigrations runs all database migrations
 func runMigrations(db *gorm.DB) error {
+	// Replace plaintext API keys before the users table gains its not-null hash column
+	if err := migrateAPIKeyHashes(db); err != nil {
+		return fmt.Errorf("failed to hash API keys: %v", err)
+	}
+	
+	// Duplicate credentials would keep their unique index from being created
+	if err := dedupeUserProviders(db); err != nil {
+		return fmt.Errorf("failed to remove duplicate provider credentials: %v", err)
+	}
+	
+	// An instance ID is only unique within its provider, which the old index didn't allow for
+	if err := migrateProviderInstanceIndex(db); err != nil {
+		return fmt.Errorf("failed to migrate the provider instance index: %v", err)
+	}
+	
 	// List of models to migrate
 	modelsToMigrate := []interface{}{
 		&models.User{},
 		&models.UserProvider{},
 		&models.Instance{},
+		&models.StatusTransition{},
+		&models.OrphanInstance{},
+		&models.PriceSnapshot{},
+		&models.AlertRule{},
+		&models.AlertDelivery{},
+		&models.IdempotencyRecord{},
+		&models.IdlePolicy{},
+		&models.UtilizationSample{},
+		&models.InstanceEvent{},
+		&models.AuditEntry{},
+		&models.CostSegment{},
+		&models.Team{},
+		&models.Budget{},
+		&models.BudgetNotification{},
 	}
 	
 	for _, model := range modelsToMigrate {
@@ -65,6 +116,11 @@ func runMigrations(db *gorm.DB) error {
 		}
 	}
 	
+	// Snapshots taken before the normalized model column existed need it filled in
+	if err := migratePriceSnapshotModelKeys(db); err != nil {
+		return fmt.Errorf("failed to normalize price snapshot models: %v", err)
+	}
+	
 	// Create indexes
 	if err := createIndexes(db); err != nil {
 		return fmt.Errorf("failed to create indexes: %v", err)
@@ -73,6 +129,72 @@ func runMigrations(db *gorm.DB) error {
 	return nil
 }
 
+// migrateAPIKeyHashes converts the legacy plaintext users.api_key column into api_key_hash
+func migrateAPIKeyHashes(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable("users") || !migrator.HasColumn("users", "api_key") {
+		return nil
+	}
+	
+	statements := []string{
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS api_key_hash text",
+		"UPDATE users SET api_key_hash = encode(sha256(api_key::bytea), 'hex') WHERE api_key_hash IS NULL",
+		"ALTER TABLE users DROP COLUMN api_key",
+	}
+	
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateProviderInstanceIndex drops the legacy idx_provider_instance, which covered
+// provider_id alone, so AutoMigrate recreates it on (provider, provider_id)
+func migrateProviderInstanceIndex(db *gorm.DB) error {
+	if !db.Migrator().HasTable("instances") {
+		return nil
+	}
+	
+	var legacy int64
+	err := db.Raw(`SELECT COUNT(*) FROM pg_indexes
+		WHERE tablename = 'instances' AND indexname = 'idx_provider_instance' AND indexdef LIKE '%(provider_id)'`).Scan(&legacy).Error
+	if err != nil || legacy == 0 {
+		return err
+	}
+	return db.Exec("DROP INDEX idx_provider_instance").Error
+}
+
+// dedupeUserProviders keeps only the newest credentials of each user for each provider,
+// which provider resolution used to pick between unpredictably
+func dedupeUserProviders(db *gorm.DB) error {
+	if !db.Migrator().HasTable("user_providers") {
+		return nil
+	}
+	
+	return db.Exec(`DELETE FROM user_providers older USING user_providers newer
+		WHERE older.user_id = newer.user_id AND older.provider = newer.provider AND older.id < newer.id`).Error
+}
+
+// migratePriceSnapshotModelKeys fills gpu_model_key for snapshots recorded before it existed
+// and drops the index on the raw model, which history queries no longer use
+func migratePriceSnapshotModelKeys(db *gorm.DB) error {
+	statements := []string{
+		"UPDATE price_snapshots SET gpu_model_key = LOWER(TRIM(gpu_model)) WHERE gpu_model_key = ''",
+		"DROP INDEX IF EXISTS idx_price_snapshot_model_time",
+	}
+	
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // createIndexes creates additional database indexes for performance
 func createIndexes(db *gorm.DB) error {
 	// Create compound indexes for better query performance
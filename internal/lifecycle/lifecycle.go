@@ -0,0 +1,114 @@
+// Package lifecycle starts the service's background workers and stops them on shutdown.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Worker is a background job that runs until its context is cancelled
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+// WorkerFunc adapts a function to the Worker interface
+type WorkerFunc func(ctx context.Context)
+
+// Run calls f(ctx)
+func (f WorkerFunc) Run(ctx context.Context) {
+	f(ctx)
+}
+
+// worker is a registered worker and, once started, the means to stop it
+type worker struct {
+	name   string
+	run    Worker
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager runs workers in the order they were added and stops them in reverse order,
+// so workers added later may rely on those added before them
+type Manager struct {
+	mu      sync.Mutex
+	workers []*worker
+	started bool
+}
+
+// NewManager creates a manager with no workers
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Add registers a worker to run under name. It must be called before Start.
+func (m *Manager) Add(name string, w Worker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.workers = append(m.workers, &worker{name: name, run: w})
+}
+
+// Start runs every worker in its own goroutine with a context derived from ctx. A
+// manager is only started once.
+func (m *Manager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.started {
+		return
+	}
+	m.started = true
+	for _, w := range m.workers {
+		m.start(ctx, w)
+	}
+}
+
+// start runs w. Must be called with m.mu held.
+func (m *Manager) start(ctx context.Context, w *worker) {
+	workerCtx, cancel := context.WithCancel(ctx)
+	w.cancel = cancel
+	w.done = make(chan struct{})
+
+	slog.InfoContext(ctx, "Lifecycle: starting worker", "worker", w.name)
+	go func() {
+		defer close(w.done)
+		w.run.Run(workerCtx)
+	}()
+}
+
+// Stop cancels the workers in reverse order, waiting for each to return before
+// stopping the next. Once ctx is done the remaining workers are cancelled without
+// waiting and an error naming those still running is returned.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var running []string
+	for i := len(m.workers) - 1; i >= 0; i-- {
+		w := m.workers[i]
+		if w.cancel == nil {
+			continue
+		}
+		w.cancel()
+		w.cancel = nil
+
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+		}
+		select {
+		case <-w.done:
+			slog.InfoContext(ctx, "Lifecycle: stopped worker", "worker", w.name)
+		default:
+			running = append(running, w.name)
+		}
+	}
+
+	if len(running) > 0 {
+		return fmt.Errorf("workers still running after %v: %s", ctx.Err(), strings.Join(running, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package lifecycle
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	var mu sync.Mutex
+	var stopped []string
+	started := make(chan string, 3)
+
+	m := NewManager()
+	for _, name := range []string{"reconciler", "alerts", "budgets"} {
+		name := name
+		m.Add(name, WorkerFunc(func(ctx context.Context) {
+			started <- name
+			<-ctx.Done()
+			mu.Lock()
+			stopped = append(stopped, name)
+			mu.Unlock()
+		}))
+	}
+
+	m.Start(context.Background())
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.Stop(ctx); err != nil {
+		t.Fatalf("unexpected error stopping workers: %v", err)
+	}
+
+	if strings.Join(stopped, ",") != "budgets,alerts,reconciler" {
+		t.Errorf("expected workers to stop in reverse order, got %v", stopped)
+	}
+}
+
+func TestManagerStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	m := NewManager()
+	m.Add("stuck", WorkerFunc(func(ctx context.Context) { <-release }))
+	m.Add("quick", WorkerFunc(func(ctx context.Context) { <-ctx.Done() }))
+	m.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := m.Stop(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stuck") || strings.Contains(err.Error(), "quick") {
+		t.Errorf("expected only the stuck worker to be reported, got %v", err)
+	}
+}
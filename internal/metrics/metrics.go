@@ -0,0 +1,195 @@
+// Package metrics exports the service's Prometheus metrics. Every method is safe to
+// call on a nil *Metrics and then records nothing, so metrics are disabled by not
+// creating one.
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gpu_cloud"
+
+// Cache lookup results
+const (
+	CacheHit   = "hit"
+	CacheStale = "stale"
+	CacheMiss  = "miss"
+)
+
+// instanceStatsTimeout bounds the database query run on every scrape
+const instanceStatsTimeout = 5 * time.Second
+
+// InstanceStats describes the instances of one provider billed as running
+type InstanceStats struct {
+	Running     int
+	HourlySpend float64
+}
+
+// InstanceStatsFunc loads the instance stats of every provider, keyed by provider name
+type InstanceStatsFunc func(ctx context.Context) (map[string]InstanceStats, error)
+
+// Metrics holds the collectors exported on /metrics
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	upstreamRequests *prometheus.CounterVec
+	upstreamDuration *prometheus.HistogramVec
+	cacheRequests    *prometheus.CounterVec
+	jobDuration      *prometheus.HistogramVec
+}
+
+// New creates the collectors and registers them, along with the Go runtime and
+// process collectors, on a registry of their own
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by method, route and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "provider_requests_total",
+			Help:      "Calls made to GPU providers, by provider, operation and outcome (success or error).",
+		}, []string{"provider", "operation", "outcome"}),
+		upstreamDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "provider_request_duration_seconds",
+			Help:      "Time taken by calls to GPU providers, by provider and operation.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"provider", "operation"}),
+		cacheRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_requests_total",
+			Help:      "Cache lookups, by cache and result (hit, stale or miss).",
+		}, []string{"cache", "result"}),
+		jobDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "job_duration_seconds",
+			Help:      "Time taken by runs of background jobs, by job.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+		}, []string{"job"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.upstreamRequests,
+		m.upstreamDuration,
+		m.cacheRequests,
+		m.jobDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// ObserveRequest records a handled HTTP request. route is the route's pattern, so
+// requests for different instances share one series.
+func (m *Metrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveUpstream records a call to a GPU provider
+func (m *Metrics) ObserveUpstream(provider, operation string, err error, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	m.upstreamRequests.WithLabelValues(provider, operation, outcome).Inc()
+	m.upstreamDuration.WithLabelValues(provider, operation).Observe(duration.Seconds())
+}
+
+// CacheLookup records a lookup in one of the service's caches
+func (m *Metrics) CacheLookup(cache, result string) {
+	if m == nil {
+		return
+	}
+	m.cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// ObserveJob records one run of a background job
+func (m *Metrics) ObserveJob(job string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.jobDuration.WithLabelValues(job).Observe(duration.Seconds())
+}
+
+// WatchInstances exports the number of running instances and their hourly spend per
+// provider, loading them with load on every scrape
+func (m *Metrics) WatchInstances(load InstanceStatsFunc) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(&instanceCollector{
+		load: load,
+		running: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "running_instances"),
+			"Instances billed as running, by provider.", []string{"provider"}, nil),
+		hourlySpend: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "hourly_burn_rate_dollars"),
+			"Combined hourly price of the instances billed as running, by provider.", []string{"provider"}, nil),
+	})
+}
+
+// instanceCollector reports instance stats loaded when the metrics are scraped
+type instanceCollector struct {
+	load        InstanceStatsFunc
+	running     *prometheus.Desc
+	hourlySpend *prometheus.Desc
+}
+
+// Describe implements prometheus.Collector
+func (c *instanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.running
+	ch <- c.hourlySpend
+}
+
+// Collect implements prometheus.Collector
+func (c *instanceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), instanceStatsTimeout)
+	defer cancel()
+
+	stats, err := c.load(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Metrics: error loading instance stats", "error", err)
+		ch <- prometheus.NewInvalidMetric(c.running, err)
+		return
+	}
+
+	for provider, s := range stats {
+		ch <- prometheus.MustNewConstMetric(c.running, prometheus.GaugeValue, float64(s.Running), provider)
+		ch <- prometheus.MustNewConstMetric(c.hourlySpend, prometheus.GaugeValue, s.HourlySpend, provider)
+	}
+}
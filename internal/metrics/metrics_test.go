@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrape returns the metrics exposition served by m
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatalf("error reading metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.ObserveRequest("GET", "/api/v1/instances", 200, time.Second)
+	m.ObserveUpstream("vast_ai", "search_offers", nil, time.Second)
+	m.CacheLookup("offers", CacheHit)
+	m.ObserveJob("reconcile", time.Second)
+	m.WatchInstances(func(ctx context.Context) (map[string]InstanceStats, error) { return nil, nil })
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 404 {
+		t.Errorf("expected disabled metrics not to be served, got status %d", w.Code)
+	}
+}
+
+func TestMetricsExposition(t *testing.T) {
+	m := New()
+	m.ObserveRequest("GET", "/api/v1/instances/:id", 404, 20*time.Millisecond)
+	m.ObserveUpstream("runpod", "create_instance", errors.New("refused"), time.Second)
+	m.CacheLookup("offers", CacheHit)
+	m.CacheLookup("offers", CacheHit)
+	m.ObserveJob("reconcile", 2*time.Second)
+	m.WatchInstances(func(ctx context.Context) (map[string]InstanceStats, error) {
+		return map[string]InstanceStats{"vast_ai": {Running: 3, HourlySpend: 4.5}}, nil
+	})
+
+	body := scrape(t, m)
+	expected := []string{
+		`gpu_cloud_http_requests_total{method="GET",route="/api/v1/instances/:id",status="404"} 1`,
+		`gpu_cloud_provider_requests_total{operation="create_instance",outcome="error",provider="runpod"} 1`,
+		`gpu_cloud_cache_requests_total{cache="offers",result="hit"} 2`,
+		`gpu_cloud_job_duration_seconds_count{job="reconcile"} 1`,
+		`gpu_cloud_running_instances{provider="vast_ai"} 3`,
+		`gpu_cloud_hourly_burn_rate_dollars{provider="vast_ai"} 4.5`,
+		`go_goroutines`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line) {
+			t.Errorf("expected %s in metrics output", line)
+		}
+	}
+}
+
+func TestInstanceStatsError(t *testing.T) {
+	m := New()
+	m.ObserveJob("reconcile", time.Second)
+	m.WatchInstances(func(ctx context.Context) (map[string]InstanceStats, error) {
+		return nil, errors.New("database down")
+	})
+
+	// A failed stats query leaves out the instance gauges but not the other metrics
+	body := scrape(t, m)
+	if strings.Contains(body, "gpu_cloud_running_instances{") || !strings.Contains(body, `gpu_cloud_job_duration_seconds_count{job="reconcile"} 1`) {
+		t.Errorf("unexpected metrics output after a failed stats query:\n%s", body)
+	}
+}
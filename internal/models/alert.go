@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+// AlertRule notifies a webhook when an offer matching Filter costs at most PriceThreshold
+type AlertRule struct {
+	ID              uint        `gorm:"primaryKey" json:"id"`
+	UserID          uint        `gorm:"not null;index" json:"user_id"`
+	Name            string      `gorm:"not null" json:"name"`
+	Filter          AlertFilter `gorm:"type:jsonb;not null" json:"filter"`
+	PriceThreshold  float64     `gorm:"not null" json:"price_threshold"`
+	WebhookURL      string      `gorm:"not null" json:"webhook_url"`
+	WebhookSecret   string      `gorm:"not null;serializer:encrypted" json:"-"` // Signs deliveries; only shown when the rule is created
+	IsEnabled       bool        `gorm:"default:true;index" json:"is_enabled"`
+	LastTriggeredAt *time.Time  `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time   `json:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at"`
+
+	// Foreign key relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName overrides the table name for the AlertRule model
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+// AlertFilter stores an alert rule's search filter as JSON
+type AlertFilter types.AdvancedSearchFilter
+
+// Value implements driver.Valuer so AlertFilter can be stored in a jsonb column
+func (f AlertFilter) Value() (driver.Value, error) {
+	return jsonValue(f, false)
+}
+
+// Scan implements sql.Scanner for AlertFilter
+func (f *AlertFilter) Scan(value interface{}) error {
+	return jsonScan(value, f)
+}
+
+// AlertDeliveryStatus is the state of a webhook notification
+type AlertDeliveryStatus string
+
+const (
+	DeliveryPending   AlertDeliveryStatus = "pending"
+	DeliveryDelivered AlertDeliveryStatus = "delivered"
+	DeliveryFailed    AlertDeliveryStatus = "failed"
+)
+
+// AlertDelivery logs a notification sent for an alert rule about one offer
+type AlertDelivery struct {
+	ID           uint                `gorm:"primaryKey" json:"id"`
+	RuleID       uint                `gorm:"not null;index:idx_alert_delivery_rule_offer,priority:1" json:"rule_id"`
+	OfferID      string              `gorm:"not null;index:idx_alert_delivery_rule_offer,priority:2" json:"offer_id"`
+	PricePerHour float64             `gorm:"not null" json:"price_per_hour"`
+	Status       AlertDeliveryStatus `gorm:"not null" json:"status"`
+	Attempts     int                 `json:"attempts"`
+	ResponseCode int                 `json:"response_code,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	CreatedAt    time.Time           `gorm:"index" json:"created_at"`
+	DeliveredAt  *time.Time          `json:"delivered_at,omitempty"`
+}
+
+// TableName overrides the table name for the AlertDelivery model
+func (AlertDelivery) TableName() string {
+	return "alert_deliveries"
+}
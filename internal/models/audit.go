@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// Instance event types
+const (
+	EventIdleStopWarning    = "idle_stop_warning"
+	EventIdleStop           = "idle_stop"
+	EventIdleDestroyWarning = "idle_destroy_warning"
+	EventIdleDestroy        = "idle_destroy"
+	EventExpiryStop         = "expiry_stop"
+	EventExpiryDestroy      = "expiry_destroy"
+	EventBudgetStop         = "budget_stop"
+)
+
+// InstanceEvent is a notice about an instance shown to its owner, such as a warning
+// that it is about to be stopped
+type InstanceEvent struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	InstanceID uint      `gorm:"not null;index:idx_instance_event_type_time,priority:1" json:"-"`
+	Type       string    `gorm:"not null;index:idx_instance_event_type_time,priority:2" json:"type"`
+	Message    string    `gorm:"not null" json:"message"`
+	CreatedAt  time.Time `gorm:"index:idx_instance_event_type_time,priority:3" json:"created_at"`
+}
+
+// TableName overrides the table name for the InstanceEvent model
+func (InstanceEvent) TableName() string {
+	return "instance_events"
+}
+
+// Audit outcomes
+const (
+	AuditSucceeded = "succeeded"
+	AuditFailed    = "failed"
+)
+
+// AuditEntry records an action taken on a user's resources by the system rather
+// than by the user
+type AuditEntry struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	UserID     uint      `gorm:"not null;index" json:"user_id"`
+	InstanceID *uint     `gorm:"index" json:"-"`
+	ResourceID string    `gorm:"not null" json:"resource_id"` // API ID of the affected instance
+	Actor      string    `gorm:"not null" json:"actor"`       // idle_policy, expiry, ...
+	Action     string    `gorm:"not null" json:"action"`      // instance.stop, instance.destroy, ...
+	Reason     string    `gorm:"not null" json:"reason"`
+	Outcome    string    `gorm:"not null" json:"outcome"` // succeeded or failed
+	Error      string    `json:"error,omitempty"`
+	Details    JSONMap   `gorm:"type:jsonb" json:"details,omitempty"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName overrides the table name for the AuditEntry model
+func (AuditEntry) TableName() string {
+	return "audit_entries"
+}
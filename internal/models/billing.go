@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+// Billing states of cost segments
+const (
+	BillingRunning = "running"
+	BillingStopped = "stopped"
+)
+
+// CostSegment is a stretch of time an instance spent in one billing state at one
+// price. The owner, provider, GPU model and label are copied from the instance so
+// spend can be reported after the instance is gone.
+type CostSegment struct {
+	ID           uint              `gorm:"primaryKey" json:"id"`
+	InstanceID   uint              `gorm:"not null;index" json:"-"`
+	UserID       uint              `gorm:"not null;index" json:"user_id"`
+	Provider     types.GPUProvider `gorm:"not null" json:"provider"`
+	GPUModel     string            `gorm:"not null" json:"gpu_model"`
+	Label        string            `json:"label"`
+	State        string            `gorm:"not null" json:"state"`
+	PricePerHour float64           `gorm:"not null" json:"price_per_hour"` // 0 while stopped
+	StartedAt    time.Time         `gorm:"not null;index" json:"started_at"`
+	EndedAt      *time.Time        `gorm:"index" json:"ended_at,omitempty"` // Unset while the segment is open
+}
+
+// TableName overrides the table name for the CostSegment model
+func (CostSegment) TableName() string {
+	return "cost_segments"
+}
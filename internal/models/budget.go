@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+// Team groups users whose instances share a budget
+type Team struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"uniqueIndex;not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName overrides the table name for the Team model
+func (Team) TableName() string {
+	return "teams"
+}
+
+// Budget caps the spend of one user or of every member of one team. Limits left at 0
+// don't apply.
+type Budget struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	UserID         *uint     `gorm:"uniqueIndex" json:"user_id,omitempty"` // Set for user budgets
+	TeamID         *uint     `gorm:"uniqueIndex" json:"team_id,omitempty"` // Set for team budgets
+	MonthlyLimit   float64   `gorm:"not null" json:"monthly_limit"`
+	MaxGPUs        int       `gorm:"not null" json:"max_gpus"`
+	MaxHourlySpend float64   `gorm:"not null" json:"max_hourly_spend"`
+	HardCap        bool      `gorm:"not null" json:"hard_cap"` // Stop running instances once MonthlyLimit is reached
+	WebhookURL     string    `json:"webhook_url,omitempty"`
+	WebhookSecret  string    `gorm:"not null;serializer:encrypted" json:"-"` // Signs notifications; only shown when the budget is created
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	Usage *types.BudgetUsage `gorm:"-" json:"usage,omitempty"`
+}
+
+// TableName overrides the table name for the Budget model
+func (Budget) TableName() string {
+	return "budgets"
+}
+
+// BudgetNotification records that spend crossed a share of a budget's monthly limit,
+// so each threshold is notified once a month
+type BudgetNotification struct {
+	ID               uint                `gorm:"primaryKey" json:"id"`
+	BudgetID         uint                `gorm:"not null;uniqueIndex:idx_budget_notification,priority:1" json:"budget_id"`
+	Period           string              `gorm:"not null;uniqueIndex:idx_budget_notification,priority:2" json:"period"` // Month, as YYYY-MM (UTC)
+	ThresholdPercent int                 `gorm:"not null;uniqueIndex:idx_budget_notification,priority:3" json:"threshold_percent"`
+	Spend            float64             `gorm:"not null" json:"spend"`
+	MonthlyLimit     float64             `gorm:"not null" json:"monthly_limit"`
+	Status           AlertDeliveryStatus `json:"status,omitempty"` // Unset when the budget has no webhook
+	Attempts         int                 `json:"attempts,omitempty"`
+	ResponseCode     int                 `json:"response_code,omitempty"`
+	Error            string              `json:"error,omitempty"`
+	CreatedAt        time.Time           `json:"created_at"`
+	DeliveredAt      *time.Time          `json:"delivered_at,omitempty"`
+}
+
+// TableName overrides the table name for the BudgetNotification model
+func (BudgetNotification) TableName() string {
+	return "budget_notifications"
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gpu-cloud-manager/internal/secrets"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrNoKeyring is returned when an encrypted field is written before a keyring is configured
+var ErrNoKeyring = errors.New("no encryption keyring configured")
+
+var (
+	keyringMu sync.RWMutex
+	keyring   *secrets.Keyring
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// SetKeyring sets the keyring used by fields tagged serializer:encrypted
+func SetKeyring(k *secrets.Keyring) {
+	keyringMu.Lock()
+	defer keyringMu.Unlock()
+	keyring = k
+}
+
+func currentKeyring() *secrets.Keyring {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	return keyring
+}
+
+// EncryptedSerializer encrypts string fields on write and decrypts them on read.
+// Values stored before encryption was introduced are read back as plaintext.
+type EncryptedSerializer struct{}
+
+// Scan implements schema.SerializerInterface
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+		return nil
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("unsupported value type %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	value := stored
+	if secrets.IsEncrypted(stored) {
+		k := currentKeyring()
+		if k == nil {
+			return fmt.Errorf("cannot decrypt %s: %w", field.Name, ErrNoKeyring)
+		}
+
+		var err error
+		if value, err = k.Decrypt(stored); err != nil {
+			return fmt.Errorf("cannot decrypt %s: %v", field.Name, err)
+		}
+	}
+
+	return field.Set(ctx, dst, value)
+}
+
+// Value implements schema.SerializerValuerInterface
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("encrypted field %s must be a string, got %T", field.Name, fieldValue)
+	}
+
+	k := currentKeyring()
+	if k == nil {
+		return nil, fmt.Errorf("cannot encrypt %s: %w", field.Name, ErrNoKeyring)
+	}
+
+	return k.Encrypt(value)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"gpu-cloud-manager/internal/secrets"
+
+	"gorm.io/gorm/schema"
+)
+
+func apiKeyField(t *testing.T) *schema.Field {
+	t.Helper()
+	s, err := schema.Parse(&UserProvider{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	field := s.LookUpField("APIKey")
+	if field == nil || field.Serializer == nil {
+		t.Fatalf("expected APIKey to use a serializer")
+	}
+	return field
+}
+
+func TestEncryptedSerializer(t *testing.T) {
+	k, err := secrets.NewKeyring(map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, "k1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetKeyring(k)
+	defer SetKeyring(nil)
+
+	field := apiKeyField(t)
+	ctx := context.Background()
+
+	stored, err := EncryptedSerializer{}.Value(ctx, field, reflect.Value{}, "runpod-secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(stored.(string), "enc:v1:k1:") {
+		t.Errorf("expected encrypted value, got %v", stored)
+	}
+
+	var up UserProvider
+	if err := (EncryptedSerializer{}).Scan(ctx, field, reflect.ValueOf(&up).Elem(), stored); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if up.APIKey != "runpod-secret" {
+		t.Errorf("expected runpod-secret, got %s", up.APIKey)
+	}
+
+	// Rows written before encryption was enabled are read back unchanged
+	var legacy UserProvider
+	if err := (EncryptedSerializer{}).Scan(ctx, field, reflect.ValueOf(&legacy).Elem(), []byte("plain-key")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if legacy.APIKey != "plain-key" {
+		t.Errorf("expected plain-key, got %s", legacy.APIKey)
+	}
+}
+
+func TestEncryptedSerializerWithoutKeyring(t *testing.T) {
+	SetKeyring(nil)
+	field := apiKeyField(t)
+
+	_, err := EncryptedSerializer{}.Value(context.Background(), field, reflect.Value{}, "secret")
+	if !errors.Is(err, ErrNoKeyring) {
+		t.Errorf("expected ErrNoKeyring, got %v", err)
+	}
+}
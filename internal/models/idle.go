@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// IdlePolicy stops an instance after its GPUs have been idle for a while, and
+// destroys it after it has been stopped for a while
+type IdlePolicy struct {
+	ID                       uint      `gorm:"primaryKey" json:"id"`
+	InstanceID               uint      `gorm:"not null;uniqueIndex" json:"-"`
+	UtilizationThreshold     float64   `gorm:"not null" json:"utilization_threshold_percent"` // GPU utilization below which the instance counts as idle
+	IdleMinutes              int       `gorm:"not null" json:"idle_minutes"`                  // 0 never stops the instance
+	DestroyAfterStoppedHours int       `gorm:"not null" json:"destroy_after_stopped_hours"`   // 0 never destroys the instance
+	GraceMinutes             int       `gorm:"not null" json:"grace_minutes"`                 // Lead time of the warning recorded before acting
+	IsEnabled                bool      `gorm:"not null;index" json:"is_enabled"`
+	CreatedAt                time.Time `json:"created_at"`
+	UpdatedAt                time.Time `json:"updated_at"`
+
+	// Foreign key relationships
+	Instance Instance `gorm:"foreignKey:InstanceID" json:"-"`
+}
+
+// TableName overrides the table name for the IdlePolicy model
+func (IdlePolicy) TableName() string {
+	return "idle_policies"
+}
+
+// UtilizationSample records the GPU load of a running instance at one point in time
+type UtilizationSample struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	InstanceID    uint      `gorm:"not null;index:idx_utilization_instance_time,priority:1" json:"-"`
+	GPUPercent    float64   `gorm:"not null" json:"gpu_percent"`
+	MemoryPercent float64   `gorm:"not null" json:"memory_percent"`
+	SampledAt     time.Time `gorm:"not null;index:idx_utilization_instance_time,priority:2;index" json:"sampled_at"`
+}
+
+// TableName overrides the table name for the UtilizationSample model
+func (UtilizationSample) TableName() string {
+	return "utilization_samples"
+}
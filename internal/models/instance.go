@@ -11,8 +11,8 @@ import (
 type Instance struct {
 	ID           uint                     `gorm:"primaryKey" json:"id"`
 	UserID       uint                     `gorm:"not null;index" json:"user_id"`
-	Provider     types.GPUProvider        `gorm:"not null;index" json:"provider"`
-	ProviderID   string                   `gorm:"not null;uniqueIndex:idx_provider_instance" json:"provider_id"`
+	Provider     types.GPUProvider        `gorm:"not null;index;uniqueIndex:idx_provider_instance,priority:1" json:"provider"`
+	ProviderID   string                   `gorm:"not null;uniqueIndex:idx_provider_instance,priority:2" json:"provider_id"` // Unique per provider, since providers' ID spaces can overlap
 	Name         string                   `gorm:"not null" json:"name"`
 	Status       types.InstanceStatus     `gorm:"not null;index" json:"status"`
 	GPUModel     string                   `gorm:"not null" json:"gpu_model"`
@@ -22,6 +22,16 @@ type Instance struct {
 	Storage      int                      `gorm:"not null" json:"storage_gb"`
 	PricePerHour float64                  `gorm:"not null" json:"price_per_hour"`
 	Region       string                   `gorm:"not null" json:"region"`
+	OfferID      string                   `json:"offer_id"`
+	Image        string                   `json:"image"`
+	Label        string                   `json:"label"`
+	LaunchConfig LaunchConfig             `gorm:"type:jsonb" json:"launch_config"`
+	SSHHost      string                   `json:"ssh_host,omitempty"`
+	SSHPort      int                      `json:"ssh_port,omitempty"`
+	LastSyncedAt *time.Time               `json:"last_synced_at,omitempty"`
+	MissingSince *time.Time               `json:"missing_since,omitempty"` // Set when the provider no longer reports the instance
+	ExpiresAt    *time.Time               `gorm:"index" json:"expires_at,omitempty"` // When the expiry scheduler stops or destroys the instance
+	ExpiryAction types.ExpiryAction       `json:"expiry_action,omitempty"`
 	CreatedAt    time.Time                `json:"created_at"`
 	UpdatedAt    time.Time                `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt           `gorm:"index" json:"-"`
@@ -59,17 +69,23 @@ func (i *Instance) ToGPUInstance() types.GPUInstance {
 		CreatedAt:    i.CreatedAt,
 		UpdatedAt:    i.UpdatedAt,
 		ProviderData: map[string]interface{}(i.ProviderData),
+		SSH:          i.sshEndpoint(),
+		ExpiresAt:    i.ExpiresAt,
+		ExpiryAction: i.ExpiryAction,
 	}
 }
 
+// sshEndpoint returns the recorded SSH endpoint, if any
+func (i *Instance) sshEndpoint() *types.SSHEndpoint {
+	if i.SSHHost == "" {
+		return nil
+	}
+	return &types.SSHEndpoint{Host: i.SSHHost, Port: i.SSHPort}
+}
+
 // generateAPIID creates the external API ID format
 func (i *Instance) generateAPIID() string {
-	switch i.Provider {
-	case types.VastAI:
-		return "vast_" + i.ProviderID
-	default:
-		return string(i.Provider) + "_" + i.ProviderID
-	}
+	return i.Provider.InstanceIDPrefix() + i.ProviderID
 }
 
 // FromGPUInstance populates database model from API type
@@ -87,4 +103,41 @@ func (i *Instance) FromGPUInstance(gpu types.GPUInstance, userID uint) {
 	i.PricePerHour = gpu.PricePerHour
 	i.Region = gpu.Region
 	i.ProviderData = ProviderData(gpu.ProviderData)
+	if gpu.SSH != nil {
+		i.SSHHost = gpu.SSH.Host
+		i.SSHPort = gpu.SSH.Port
+	}
+}
+
+// StatusTransition records an instance moving from one status to another
+type StatusTransition struct {
+	ID         uint                 `gorm:"primaryKey" json:"id"`
+	InstanceID uint                 `gorm:"not null;index" json:"instance_id"`
+	FromStatus types.InstanceStatus `json:"from_status"`
+	ToStatus   types.InstanceStatus `gorm:"not null" json:"to_status"`
+	Source     string               `gorm:"not null" json:"source"` // api, reconciler, ...
+	OccurredAt time.Time            `gorm:"not null;index" json:"occurred_at"`
+}
+
+// TableName overrides the table name for the StatusTransition model
+func (StatusTransition) TableName() string {
+	return "instance_status_transitions"
+}
+
+// OrphanInstance is an instance that exists at a provider but has no matching record
+type OrphanInstance struct {
+	ID           uint                 `gorm:"primaryKey" json:"id"`
+	Provider     types.GPUProvider    `gorm:"not null;uniqueIndex:idx_orphan_provider_instance" json:"provider"`
+	ProviderID   string               `gorm:"not null;uniqueIndex:idx_orphan_provider_instance" json:"provider_id"`
+	Name         string               `json:"name"`
+	Status       types.InstanceStatus `json:"status"`
+	GPUModel     string               `json:"gpu_model"`
+	PricePerHour float64              `json:"price_per_hour"`
+	FirstSeenAt  time.Time            `gorm:"not null" json:"first_seen_at"`
+	LastSeenAt   time.Time            `gorm:"not null" json:"last_seen_at"`
+}
+
+// TableName overrides the table name for the OrphanInstance model
+func (OrphanInstance) TableName() string {
+	return "orphan_instances"
 }
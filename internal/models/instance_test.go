@@ -23,8 +23,8 @@ func TestGenerateAPIID(t *testing.T) {
 		expected   string
 	}{
 		{types.VastAI, "12345", "vast_12345"},
-		{types.GCP, "abc", "GCP_abc"},
-		{types.AWS, "xyz", "AWS_xyz"},
+		{types.GCP, "abc", "gcp_abc"},
+		{types.AWS, "xyz", "aws_xyz"},
 	}
 
 	for _, tt := range tests {
@@ -47,7 +47,7 @@ func TestToGPUInstance(t *testing.T) {
 		Provider:     types.AWS,
 		ProviderID:   "i-123456",
 		Name:         "test-instance",
-		Status:       types.Running,
+		Status:       types.StatusRunning,
 		GPUModel:     "NVIDIA A100",
 		GPUCount:     2,
 		CPUCount:     16,
@@ -62,8 +62,8 @@ func TestToGPUInstance(t *testing.T) {
 
 	got := instance.ToGPUInstance()
 
-	if got.ID != "AWS_i-123456" {
-		t.Errorf("expected ID %s, got %s", "AWS_i-123456", got.ID)
+	if got.ID != "aws_i-123456" {
+		t.Errorf("expected ID %s, got %s", "aws_i-123456", got.ID)
 	}
 	if got.Provider != types.AWS {
 		t.Errorf("expected provider AWS, got %v", got.Provider)
@@ -80,7 +80,7 @@ func TestFromGPUInstance(t *testing.T) {
 		Provider:     types.AWS,
 		ProviderID:   "i-654321",
 		Name:         "new-instance",
-		Status:       types.Stopped,
+		Status:       types.StatusOffline,
 		GPUModel:     "NVIDIA V100",
 		GPUCount:     4,
 		CPUCount:     32,
@@ -106,3 +106,46 @@ func TestFromGPUInstance(t *testing.T) {
 		t.Errorf("expected ProviderData map[custom:data], got %v", i.ProviderData)
 	}
 }
+
+func TestProviderDataValueAndScan(t *testing.T) {
+	data := ProviderData{"ssh_host": "ssh5.vast.ai", "ssh_port": float64(22)}
+
+	value, err := data.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var scanned ProviderData
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(scanned, data) {
+		t.Errorf("expected %v, got %v", data, scanned)
+	}
+
+	var empty ProviderData
+	if value, _ := empty.Value(); value != nil {
+		t.Errorf("expected nil value for nil ProviderData, got %v", value)
+	}
+}
+
+func TestLaunchConfigValueAndScan(t *testing.T) {
+	config := LaunchConfig{
+		OnStartScript: "#!/bin/bash",
+		Environment:   map[string]string{"WANDB_PROJECT": "test"},
+		Ports:         []types.PortMapping{{ContainerPort: 8888, Protocol: "tcp"}},
+	}
+
+	value, err := config.Value()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var scanned LaunchConfig
+	if err := scanned.Scan([]byte(value.(string))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(scanned, config) {
+		t.Errorf("expected %+v, got %+v", config, scanned)
+	}
+}
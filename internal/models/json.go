@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+// LaunchConfig records the parameters an instance was launched with
+type LaunchConfig struct {
+	OnStartScript string                  `json:"onstart_script,omitempty"`
+	SSHKey        string                  `json:"ssh_key,omitempty"`
+	Environment   map[string]string       `json:"environment,omitempty"`
+	Ports         []types.PortMapping     `json:"ports,omitempty"`
+	Resources     *types.ResourceRequests `json:"resources,omitempty"`
+}
+
+// Value implements driver.Valuer so JSONMap can be stored in a jsonb column
+func (m JSONMap) Value() (driver.Value, error) {
+	return jsonValue(m, m == nil)
+}
+
+// Scan implements sql.Scanner for JSONMap
+func (m *JSONMap) Scan(value interface{}) error {
+	return jsonScan(value, m)
+}
+
+// Value implements driver.Valuer so ProviderData can be stored in a jsonb column
+func (d ProviderData) Value() (driver.Value, error) {
+	return jsonValue(d, d == nil)
+}
+
+// Scan implements sql.Scanner for ProviderData
+func (d *ProviderData) Scan(value interface{}) error {
+	return jsonScan(value, d)
+}
+
+// Value implements driver.Valuer so LaunchConfig can be stored in a jsonb column
+func (l LaunchConfig) Value() (driver.Value, error) {
+	return jsonValue(l, false)
+}
+
+// Scan implements sql.Scanner for LaunchConfig
+func (l *LaunchConfig) Scan(value interface{}) error {
+	return jsonScan(value, l)
+}
+
+// jsonValue marshals v for storage, mapping empty values to NULL
+func jsonValue(v interface{}, isNil bool) (driver.Value, error) {
+	if isNil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// jsonScan unmarshals a JSON column value into dest
+func jsonScan(value interface{}, dest interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, dest)
+	}
+	return json.Unmarshal(data, dest)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+// PriceSnapshot records the price of one marketplace offer at a point in time
+type PriceSnapshot struct {
+	ID           uint              `gorm:"primaryKey" json:"id"`
+	Provider     types.GPUProvider `gorm:"not null;index:idx_price_snapshot_provider" json:"provider"`
+	GPUModel     string            `gorm:"not null" json:"gpu_model"`
+	GPUModelKey  string            `gorm:"not null;default:'';index:idx_price_snapshot_key_time,priority:1" json:"-"` // GPUModel normalized by GPUModelKey, for lookups
+	GPUCount     int               `gorm:"not null" json:"gpu_count"`
+	Region       string            `json:"region"`
+	PricePerHour float64           `gorm:"not null" json:"price_per_hour"`
+	Reliability  float64           `json:"reliability"`
+	CapturedAt   time.Time         `gorm:"not null;index;index:idx_price_snapshot_key_time,priority:2" json:"captured_at"`
+}
+
+// TableName overrides the table name for the PriceSnapshot model
+func (PriceSnapshot) TableName() string {
+	return "price_snapshots"
+}
+
+// GPUModelKey normalizes a GPU model name so lookups ignore case and surrounding space
+func GPUModelKey(model string) string {
+	return strings.ToLower(strings.TrimSpace(model))
+}
+
+// NewPriceSnapshot records an offer as seen at capturedAt
+func NewPriceSnapshot(offer types.GPUInstance, capturedAt time.Time) PriceSnapshot {
+	return PriceSnapshot{
+		Provider:     offer.Provider,
+		GPUModel:     offer.GPUModel,
+		GPUModelKey:  GPUModelKey(offer.GPUModel),
+		GPUCount:     offer.GPUCount,
+		Region:       offer.Region,
+		PricePerHour: offer.PricePerHour,
+		Reliability:  offer.Reliability,
+		CapturedAt:   capturedAt,
+	}
+}
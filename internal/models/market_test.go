@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestNewPriceSnapshot(t *testing.T) {
+	now := time.Now()
+	snapshot := NewPriceSnapshot(types.GPUInstance{
+		Provider:     types.RunPod,
+		GPUModel:     " H100 ",
+		GPUCount:     8,
+		PricePerHour: 23.92,
+	}, now)
+
+	if snapshot.GPUModelKey != "h100" {
+		t.Errorf("expected model key h100, got %q", snapshot.GPUModelKey)
+	}
+	if snapshot.GPUModel != " H100 " || snapshot.GPUCount != 8 || !snapshot.CapturedAt.Equal(now) {
+		t.Errorf("expected the offer to be recorded as seen, got %+v", snapshot)
+	}
+	if GPUModelKey("h100") != GPUModelKey("H100") {
+		t.Errorf("expected model keys to ignore case")
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,18 +10,20 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	Name      string         `gorm:"not null" json:"name"`
-	APIKey    string         `gorm:"uniqueIndex;not null" json:"-"` // Hidden from JSON
-	IsActive  bool           `gorm:"default:true" json:"is_active"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	Email      string         `gorm:"uniqueIndex;not null" json:"email"`
+	Name       string         `gorm:"not null" json:"name"`
+	APIKeyHash string         `gorm:"uniqueIndex;not null" json:"-"` // SHA-256 of the API key, hidden from JSON
+	IsActive   bool           `gorm:"default:true" json:"is_active"`
+	IsAdmin    bool           `gorm:"not null;default:false" json:"is_admin"` // Admins see reports across all users
+	TeamID     *uint          `gorm:"index" json:"team_id,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
+
 	// Relationships
 	Instances []Instance `gorm:"foreignKey:UserID" json:"-"`
-	
+
 	// Provider-specific configurations
 	Providers []UserProvider `gorm:"foreignKey:UserID" json:"-"`
 }
@@ -29,21 +33,36 @@ func (User) TableName() string {
 	return "users"
 }
 
+// HashAPIKey returns the hex-encoded SHA-256 digest stored in place of a plaintext API key
+func HashAPIKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
 // UserProvider stores user's configuration for each GPU provider
 type UserProvider struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
-	UserID     uint      `gorm:"not null;index" json:"user_id"`
-	Provider   string    `gorm:"not null" json:"provider"`
-	APIKey     string    `gorm:"not null" json:"-"` // Hidden from JSON
+	UserID     uint      `gorm:"not null;index;uniqueIndex:idx_user_providers_user_provider,priority:1" json:"user_id"`
+	Provider   string    `gorm:"not null;uniqueIndex:idx_user_providers_user_provider,priority:2" json:"provider"` // One set of credentials per provider and user
+	APIKey     string    `gorm:"not null;serializer:encrypted" json:"-"` // Encrypted at rest, hidden from JSON
+	APIKeyHint string    `json:"api_key_hint,omitempty"`                 // Last characters of the key, for telling keys apart
 	IsEnabled  bool      `gorm:"default:true" json:"is_enabled"`
 	Config     JSONMap   `gorm:"type:jsonb" json:"config,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
-	
+
 	// Foreign key relationships
 	User User `gorm:"foreignKey:UserID" json:"-"`
 }
 
+// KeyHint returns a masked form of an API key that is safe to display
+func KeyHint(apiKey string) string {
+	if len(apiKey) <= 8 {
+		return "****"
+	}
+	return "****" + apiKey[len(apiKey)-4:]
+}
+
 // JSONMap is a custom type for storing JSON data
 type JSONMap map[string]interface{}
 
@@ -51,3 +70,20 @@ type JSONMap map[string]interface{}
 func (UserProvider) TableName() string {
 	return "user_providers"
 }
+
+// IdempotencyRecord remembers the response to a mutating request made with an Idempotency-Key
+type IdempotencyRecord struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_idempotency_user_key,priority:1" json:"user_id"`
+	Key          string    `gorm:"not null;size:255;uniqueIndex:idx_idempotency_user_key,priority:2" json:"key"`
+	RequestHash  string    `gorm:"not null" json:"request_hash"`   // SHA-256 of the method, URI and body
+	StatusCode   int       `json:"status_code"`                    // 0 while the original request is in progress
+	ResponseBody string    `gorm:"type:text" json:"response_body"` // Replayed for repeats of the request
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// TableName overrides the table name for the IdempotencyRecord model
+func (IdempotencyRecord) TableName() string {
+	return "idempotency_records"
+}
@@ -23,24 +23,50 @@ func TestUserProviderTableName(t *testing.T) {
 
 func TestUserFields(t *testing.T) {
 	user := User{
-		ID:       1,
-		Email:    "test@example.com",
-		Name:     "Test User",
-		APIKey:   "secret-key",
-		IsActive: true,
+		ID:         1,
+		Email:      "test@example.com",
+		Name:       "Test User",
+		APIKeyHash: HashAPIKey("secret-key"),
+		IsActive:   true,
 	}
 
 	if user.Email != "test@example.com" {
 		t.Errorf("expected email=test@example.com, got %s", user.Email)
 	}
-	if user.APIKey != "secret-key" {
-		t.Errorf("expected APIKey=secret-key, got %s", user.APIKey)
+	if user.APIKeyHash != HashAPIKey("secret-key") {
+		t.Errorf("expected APIKeyHash to be the hash of secret-key, got %s", user.APIKeyHash)
 	}
 	if !user.IsActive {
 		t.Errorf("expected IsActive=true, got %v", user.IsActive)
 	}
 }
 
+func TestHashAPIKey(t *testing.T) {
+	hash := HashAPIKey("secret-key")
+
+	if len(hash) != 64 {
+		t.Errorf("expected 64 hex characters, got %d", len(hash))
+	}
+	if hash == "secret-key" {
+		t.Error("expected hash to differ from plaintext key")
+	}
+	if hash != HashAPIKey("secret-key") {
+		t.Error("expected hashing to be deterministic")
+	}
+	if hash == HashAPIKey("other-key") {
+		t.Error("expected different keys to produce different hashes")
+	}
+}
+
+func TestKeyHint(t *testing.T) {
+	if got := KeyHint("vast-1234567890abcd"); got != "****abcd" {
+		t.Errorf("expected ****abcd, got %s", got)
+	}
+	if got := KeyHint("short"); got != "****" {
+		t.Errorf("expected short keys to be fully masked, got %s", got)
+	}
+}
+
 func TestUserProviderFields(t *testing.T) {
 	config := JSONMap{"region": "us-east-1", "quota": 5}
 
@@ -0,0 +1,238 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenDestination is returned for webhooks addressed to loopback, private,
+// link-local (including cloud metadata endpoints) or other internal addresses
+var ErrForbiddenDestination = errors.New("webhooks can't be sent to loopback, private or link-local addresses")
+
+// Headers set on every webhook request. The signature is "sha256=" followed by the
+// hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+const (
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Sender delivers signed webhooks, retrying failed attempts with exponential backoff.
+// Webhooks are only sent to public addresses, checked as each connection is made so
+// a host can't resolve to an internal address after its URL was accepted.
+type Sender struct {
+	client       *http.Client
+	maxAttempts  int
+	backoff      time.Duration // Delay before the first retry, doubled for each retry after it
+	allowPrivate bool          // Lets tests deliver to local servers
+
+	mu        sync.Mutex
+	inFlight  sync.WaitGroup
+	closed    bool
+	ctx       context.Context // Cancelled by Close to cut short deliveries still running
+	cancelAll context.CancelFunc
+}
+
+// Result describes how a webhook delivery went
+type Result struct {
+	Attempts   int
+	StatusCode int // Status of the last response, 0 when none was received
+}
+
+// NewSender creates a webhook sender
+func NewSender() *Sender {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sender{
+		maxAttempts: 5,
+		backoff:     2 * time.Second,
+		ctx:         ctx,
+		cancelAll:   cancel,
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); !s.allowPrivate && (ip == nil || forbiddenIP(ip)) {
+				return ErrForbiddenDestination
+			}
+			return nil
+		},
+	}
+	s.client = &http.Client{
+		Timeout: 10 * time.Second,
+		// No proxy, so the dialer checks the address actually connected to
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+	return s
+}
+
+// CheckURL rejects webhook URLs that aren't absolute http or https URLs, or whose host
+// resolves to an address webhooks can't be sent to
+func (s *Sender) CheckURL(ctx context.Context, rawURL string) error {
+	webhook, err := url.Parse(rawURL)
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+		return errors.New("webhook_url must be an absolute http or https URL")
+	}
+	if s.allowPrivate {
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, webhook.Hostname())
+	if err != nil {
+		return fmt.Errorf("error resolving webhook host %s: %v", webhook.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if forbiddenIP(addr.IP) {
+			return ErrForbiddenDestination
+		}
+	}
+	return nil
+}
+
+// forbiddenIP reports whether ip is an address webhooks can't be sent to
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, internal to many cloud networks
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Go runs deliver in its own goroutine, tracked so Close can wait for it. deliver
+// should send through ctx, which Close cancels when shutdown runs out of time.
+// Deliveries started after Close don't run.
+func (s *Sender) Go(deliver func(ctx context.Context)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.inFlight.Add(1)
+	go func() {
+		defer s.inFlight.Done()
+		deliver(s.ctx)
+	}()
+}
+
+// Close stops new deliveries and waits for those running to finish. Once ctx is done
+// the deliveries still running are cancelled, and Close waits for them to record that
+// before returning ctx's error.
+func (s *Sender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancelAll()
+		return nil
+	case <-ctx.Done():
+	}
+	s.cancelAll()
+	<-done
+	return fmt.Errorf("webhook deliveries cut short: %w", ctx.Err())
+}
+
+// Send posts payload as JSON to url, signed with secret. Network errors, 429 and 5xx
+// responses are retried; any other non-2xx response fails immediately.
+func (s *Sender) Send(ctx context.Context, url, secret, event string, payload interface{}) (Result, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Result{}, fmt.Errorf("error encoding payload: %v", err)
+	}
+
+	var result Result
+	delay := s.backoff
+	for {
+		result.Attempts++
+
+		var retry bool
+		result.StatusCode, retry, err = s.attempt(ctx, url, secret, event, body)
+		if err == nil || !retry || result.Attempts >= s.maxAttempts {
+			return result, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// attempt makes one delivery attempt and reports whether a failure is worth retrying
+func (s *Sender) attempt(ctx context.Context, url, secret, event string, body []byte) (int, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("error creating request: %v", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gpu-cloud-manager-webhooks")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrForbiddenDestination) {
+			return 0, false, ErrForbiddenDestination
+		}
+		return 0, ctx.Err() == nil, fmt.Errorf("error sending webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return resp.StatusCode, retry, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
+
+// Sign computes the signature header value for a webhook body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a webhook signature, for receivers validating deliveries
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
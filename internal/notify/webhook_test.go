@@ -0,0 +1,184 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestSender returns a sender that retries without waiting long
+func newTestSender() *Sender {
+	sender := NewSender()
+	sender.backoff = time.Millisecond
+	sender.allowPrivate = true
+	return sender
+}
+
+func TestSendSignsPayload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(EventHeader) != "price_alert" {
+			t.Errorf("unexpected event %q", r.Header.Get(EventHeader))
+		}
+		if !Verify("secret", r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("signature did not verify")
+		}
+		if Verify("other", r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("signature verified with the wrong secret")
+		}
+		if string(body) != `{"price":1.5}` {
+			t.Errorf("unexpected body %s", body)
+		}
+	}))
+	defer server.Close()
+
+	result, err := newTestSender().Send(context.Background(), server.URL, "secret", "price_alert", map[string]float64{"price": 1.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Attempts != 1 || result.StatusCode != http.StatusOK {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestSendRetriesServerErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	result, err := newTestSender().Send(context.Background(), server.URL, "secret", "price_alert", struct{}{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Attempts != 3 || result.StatusCode != http.StatusNoContent {
+		t.Errorf("expected success on the third attempt, got %+v", result)
+	}
+}
+
+func TestSendGivesUp(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sender := newTestSender()
+
+	result, err := sender.Send(context.Background(), server.URL+"/gone", "secret", "price_alert", struct{}{})
+	if err == nil || result.Attempts != 1 || result.StatusCode != http.StatusGone {
+		t.Errorf("expected client errors not to be retried, got %+v, %v", result, err)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	result, err = sender.Send(context.Background(), server.URL, "secret", "price_alert", struct{}{})
+	if err == nil || result.Attempts != sender.maxAttempts || atomic.LoadInt32(&calls) != int32(sender.maxAttempts) {
+		t.Errorf("expected %d attempts, got %+v, %v", sender.maxAttempts, result, err)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	sender := NewSender()
+
+	forbidden := []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"http://[fd00:ec2::254]/hook",
+		"http://0.0.0.0/hook",
+		"http://100.64.0.1/hook",
+	}
+	for _, u := range forbidden {
+		if err := sender.CheckURL(context.Background(), u); !errors.Is(err, ErrForbiddenDestination) {
+			t.Errorf("%s: expected ErrForbiddenDestination, got %v", u, err)
+		}
+	}
+
+	for _, u := range []string{"ftp://example.com/hook", "/hook", "https://"} {
+		if err := sender.CheckURL(context.Background(), u); err == nil {
+			t.Errorf("%s: expected an invalid URL to be rejected", u)
+		}
+	}
+
+	if err := sender.CheckURL(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("expected a public address to be accepted, got %v", err)
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	// The URL passed validation elsewhere but the host now resolves to loopback
+	sender := NewSender()
+	sender.backoff = time.Millisecond
+	result, err := sender.Send(context.Background(), server.URL, "secret", "price_alert", struct{}{})
+	if !errors.Is(err, ErrForbiddenDestination) || result.Attempts != 1 || atomic.LoadInt32(&calls) != 0 {
+		t.Errorf("expected a single refused attempt, got %+v, %v after %d calls", result, err, calls)
+	}
+}
+
+func TestCloseWaitsForDeliveries(t *testing.T) {
+	sender := newTestSender()
+
+	finished := make(chan struct{})
+	release := make(chan struct{})
+	sender.Go(func(ctx context.Context) {
+		<-release
+		close(finished)
+	})
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	if err := sender.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("expected Close to wait for the delivery")
+	}
+
+	sender.Go(func(ctx context.Context) { t.Error("expected deliveries after Close not to run") })
+}
+
+func TestCloseCancelsDeliveriesAtDeadline(t *testing.T) {
+	sender := newTestSender()
+
+	var cancelled int32
+	sender.Go(func(ctx context.Context) {
+		<-ctx.Done()
+		atomic.StoreInt32(&cancelled, 1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sender.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the deadline error, got %v", err)
+	}
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Error("expected the delivery to be cancelled and waited for")
+	}
+}
@@ -0,0 +1,41 @@
+package providers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// fingerprintSecret keys the HMAC fingerprinting API keys. It is random per process,
+// as fingerprints only tell accounts apart within it.
+var fingerprintSecret = func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("providers: error generating fingerprint secret: " + err.Error())
+	}
+	return secret
+}()
+
+// account tags a provider with a fingerprint of the API key it was created with
+type account struct {
+	Provider
+	fingerprint string
+}
+
+// AccountFingerprint identifies the provider account p calls without revealing its
+// API key. Providers sharing an API key share a fingerprint; providers not created
+// through a Descriptor have none and get "".
+func AccountFingerprint(p Provider) string {
+	if a, ok := p.(*account); ok {
+		return a.fingerprint
+	}
+	return ""
+}
+
+// fingerprintKey returns a truncated HMAC of an API key
+func fingerprintKey(apiKey string) string {
+	mac := hmac.New(sha256.New, fingerprintSecret)
+	mac.Write([]byte(apiKey))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
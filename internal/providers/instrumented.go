@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// instrumented records the count, latency and errors of every call to a provider
+type instrumented struct {
+	Provider
+	metrics *metrics.Metrics
+}
+
+// Instrument wraps a provider so its calls are recorded in m. The provider is
+// returned unchanged when m is nil.
+func Instrument(p Provider, m *metrics.Metrics) Provider {
+	if m == nil {
+		return p
+	}
+	return &instrumented{Provider: p, metrics: m}
+}
+
+// NewInstrumented creates the descriptor's provider for apiKey with its calls recorded
+// in m. The provider is tagged with the fingerprint of apiKey returned by AccountFingerprint.
+func (d Descriptor) NewInstrumented(apiKey string, m *metrics.Metrics) Provider {
+	return &account{Provider: Instrument(d.New(apiKey), m), fingerprint: fingerprintKey(apiKey)}
+}
+
+// observe records one call made at start
+func (p *instrumented) observe(operation string, start time.Time, err error) {
+	p.metrics.ObserveUpstream(string(p.Name()), operation, err, time.Since(start))
+}
+
+func (p *instrumented) SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
+	start := time.Now()
+	offers, err := p.Provider.SearchOffers(ctx, filter)
+	p.observe("search_offers", start, err)
+	return offers, err
+}
+
+func (p *instrumented) GetOffer(ctx context.Context, offerID string) (*types.GPUInstance, error) {
+	start := time.Now()
+	offer, err := p.Provider.GetOffer(ctx, offerID)
+	p.observe("get_offer", start, err)
+	return offer, err
+}
+
+func (p *instrumented) ListInstances(ctx context.Context) ([]types.GPUInstance, error) {
+	start := time.Now()
+	instances, err := p.Provider.ListInstances(ctx)
+	p.observe("list_instances", start, err)
+	return instances, err
+}
+
+func (p *instrumented) GetInstance(ctx context.Context, providerID string) (*types.GPUInstance, error) {
+	start := time.Now()
+	instance, err := p.Provider.GetInstance(ctx, providerID)
+	p.observe("get_instance", start, err)
+	return instance, err
+}
+
+func (p *instrumented) CreateInstance(ctx context.Context, req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
+	start := time.Now()
+	instance, err := p.Provider.CreateInstance(ctx, req)
+	p.observe("create_instance", start, err)
+	return instance, err
+}
+
+func (p *instrumented) StartInstance(ctx context.Context, providerID string) error {
+	start := time.Now()
+	err := p.Provider.StartInstance(ctx, providerID)
+	p.observe("start_instance", start, err)
+	return err
+}
+
+func (p *instrumented) StopInstance(ctx context.Context, providerID string) error {
+	start := time.Now()
+	err := p.Provider.StopInstance(ctx, providerID)
+	p.observe("stop_instance", start, err)
+	return err
+}
+
+func (p *instrumented) DestroyInstance(ctx context.Context, providerID string) error {
+	start := time.Now()
+	err := p.Provider.DestroyInstance(ctx, providerID)
+	p.observe("destroy_instance", start, err)
+	return err
+}
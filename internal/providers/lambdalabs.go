@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/lambdalabs"
+	"gpu-cloud-manager/pkg/types"
+)
+
+var lambdaLabsCapabilities = Capabilities{
+	DisplayName:       "Lambda Labs",
+	Website:           "https://lambdalabs.com",
+	Regions:           []string{"US-West", "US-East", "US-South", "Europe", "Asia"},
+	Features:          []string{"SSH Access", "Jupyter Notebooks", "Persistent Filesystems", "On-Demand Datacenter GPUs"},
+	SupportsStartStop: false,
+}
+
+// lambdaLabsProvider adapts the Lambda Cloud client to the Provider interface
+type lambdaLabsProvider struct {
+	client *lambdalabs.Client
+}
+
+// NewLambdaLabs creates a Lambda Labs provider from an API key
+func NewLambdaLabs(apiKey string) Provider {
+	return &lambdaLabsProvider{client: lambdalabs.NewClient(apiKey)}
+}
+
+func (p *lambdaLabsProvider) Name() types.GPUProvider {
+	return types.LambdaLabs
+}
+
+func (p *lambdaLabsProvider) Capabilities() Capabilities {
+	return lambdaLabsCapabilities
+}
+
+func (p *lambdaLabsProvider) SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
+	instanceTypes, err := p.client.GetInstanceTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []types.GPUInstance
+	for _, availability := range instanceTypes {
+		for _, offer := range lambdalabs.ConvertInstanceTypeToGPUInstances(availability) {
+			if filter.Available && offer.Status == types.StatusUnavailable {
+				continue
+			}
+			if filter.GPUModel != "" && !strings.Contains(strings.ToLower(offer.GPUModel), strings.ToLower(filter.GPUModel)) {
+				continue
+			}
+			if filter.MinGPUCount > 0 && offer.GPUCount < filter.MinGPUCount {
+				continue
+			}
+			if filter.MaxPrice > 0 && offer.PricePerHour > filter.MaxPrice {
+				continue
+			}
+			if filter.MinRAM > 0 && offer.RAM < filter.MinRAM {
+				continue
+			}
+			if filter.Region != "" && !strings.Contains(strings.ToLower(offer.Region), strings.ToLower(filter.Region)) {
+				continue
+			}
+			instances = append(instances, withGPUInfo(offer))
+		}
+	}
+
+	return instances, nil
+}
+
+func (p *lambdaLabsProvider) GetOffer(ctx context.Context, offerID string) (*types.GPUInstance, error) {
+	if _, _, err := lambdalabs.ParseOfferID(offerID); err != nil {
+		return nil, err
+	}
+
+	// Lambda lists every instance type, so this finds any offer with capacity
+	offers, err := p.SearchOffers(ctx, &types.AdvancedSearchFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return findOffer(offers, offerID)
+}
+
+func (p *lambdaLabsProvider) ListInstances(ctx context.Context) ([]types.GPUInstance, error) {
+	instances, err := p.client.GetInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.GPUInstance
+	for _, instance := range instances {
+		result = append(result, withGPUInfo(lambdalabs.ConvertInstanceToGPUInstance(instance)))
+	}
+
+	return result, nil
+}
+
+func (p *lambdaLabsProvider) GetInstance(ctx context.Context, providerID string) (*types.GPUInstance, error) {
+	instance, err := p.client.GetInstance(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withGPUInfo(lambdalabs.ConvertInstanceToGPUInstance(*instance))
+	return &result, nil
+}
+
+func (p *lambdaLabsProvider) CreateInstance(ctx context.Context, req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
+	instanceType, region, err := lambdalabs.ParseOfferID(req.OfferID)
+	if err != nil {
+		return nil, err
+	}
+
+	keyName, err := p.sshKeyName(ctx, req.SSHKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := p.client.LaunchInstances(ctx, &lambdalabs.LaunchRequest{
+		RegionName:       region,
+		InstanceTypeName: instanceType,
+		SSHKeyNames:      []string{keyName},
+		Quantity:         1,
+		Name:             req.Label,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Lambda only returns the new ID, so describe the instance from the offer until it boots
+	instance := lambdalabs.ConvertInstanceToGPUInstance(lambdalabs.LambdaInstance{
+		ID:           ids[0],
+		Name:         req.Label,
+		Status:       "booting",
+		SSHKeyNames:  []string{keyName},
+		Region:       lambdalabs.Region{Name: region},
+		InstanceType: lambdalabs.InstanceType{Name: instanceType},
+	})
+	if launched, err := p.client.GetInstance(ctx, ids[0]); err == nil {
+		instance = lambdalabs.ConvertInstanceToGPUInstance(*launched)
+	}
+
+	result := withGPUInfo(instance)
+	return &result, nil
+}
+
+// StartInstance is not supported; Lambda instances can only be launched and terminated
+func (p *lambdaLabsProvider) StartInstance(ctx context.Context, providerID string) error {
+	return fmt.Errorf("%w: %s instances cannot be started", ErrNotSupported, lambdaLabsCapabilities.DisplayName)
+}
+
+// StopInstance is not supported; Lambda instances can only be launched and terminated
+func (p *lambdaLabsProvider) StopInstance(ctx context.Context, providerID string) error {
+	return fmt.Errorf("%w: %s instances cannot be stopped", ErrNotSupported, lambdaLabsCapabilities.DisplayName)
+}
+
+func (p *lambdaLabsProvider) DestroyInstance(ctx context.Context, providerID string) error {
+	return p.client.TerminateInstances(ctx, []string{providerID})
+}
+
+// sshKeyName resolves the SSH key to launch with. A public key is registered with
+// Lambda if it isn't already, a bare name refers to an existing key, and without
+// either the account's first key is used since Lambda requires one.
+func (p *lambdaLabsProvider) sshKeyName(ctx context.Context, sshKey string) (string, error) {
+	sshKey = strings.TrimSpace(sshKey)
+
+	keys, err := p.client.ListSSHKeys(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error listing SSH keys: %w", err)
+	}
+
+	if sshKey == "" {
+		if len(keys) == 0 {
+			return "", apperr.New(apperr.InvalidArgument, "Lambda Labs requires an SSH key: set ssh_key or add one to the account")
+		}
+		return keys[0].Name, nil
+	}
+
+	if !isPublicKey(sshKey) {
+		for _, key := range keys {
+			if key.Name == sshKey {
+				return key.Name, nil
+			}
+		}
+		return "", apperr.Errorf(apperr.InvalidArgument, "SSH key %q not found on Lambda Labs account", sshKey)
+	}
+
+	for _, key := range keys {
+		if sameKey(key.PublicKey, sshKey) {
+			return key.Name, nil
+		}
+	}
+
+	// Name registered keys after their content so the same key maps to the same name
+	sum := sha256.Sum256([]byte(sshKey))
+	added, err := p.client.AddSSHKey(ctx, "gpu-cloud-manager-"+hex.EncodeToString(sum[:6]), sshKey)
+	if err != nil {
+		return "", fmt.Errorf("error adding SSH key: %w", err)
+	}
+	return added.Name, nil
+}
+
+// isPublicKey reports whether s looks like an OpenSSH public key rather than a key name
+func isPublicKey(s string) bool {
+	return strings.HasPrefix(s, "ssh-") || strings.HasPrefix(s, "ecdsa-") || strings.HasPrefix(s, "sk-")
+}
+
+// sameKey compares public keys by type and key material, ignoring the comment
+func sameKey(a, b string) bool {
+	fa, fb := strings.Fields(a), strings.Fields(b)
+	return len(fa) >= 2 && len(fb) >= 2 && fa[0] == fb[0] && fa[1] == fb[1]
+}
@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/paperspace"
+	"gpu-cloud-manager/pkg/types"
+)
+
+var paperspaceCapabilities = Capabilities{
+	DisplayName:       "Paperspace",
+	Website:           "https://www.paperspace.com",
+	Regions:           paperspace.Regions,
+	Features:          []string{"SSH Access", "Custom Templates", "Public IPs", "Start/Stop Billing"},
+	SupportsStartStop: true,
+}
+
+// paperspaceAvailabilityChecks bounds concurrent availability requests during a search
+const paperspaceAvailabilityChecks = 4
+
+// paperspaceDefaultDiskGB is the storage size used when a request doesn't ask for one
+const paperspaceDefaultDiskGB = 50
+
+// paperspaceProvider adapts the Paperspace client to the Provider interface
+type paperspaceProvider struct {
+	client *paperspace.Client
+}
+
+// NewPaperspace creates a Paperspace provider from an API key
+func NewPaperspace(apiKey string) Provider {
+	return &paperspaceProvider{client: paperspace.NewClient(apiKey)}
+}
+
+func (p *paperspaceProvider) Name() types.GPUProvider {
+	return types.Paperspace
+}
+
+func (p *paperspaceProvider) Capabilities() Capabilities {
+	return paperspaceCapabilities
+}
+
+func (p *paperspaceProvider) SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
+	type candidate struct {
+		machineType paperspace.MachineType
+		region      string
+	}
+
+	// Narrow down by the static machine type data before asking about availability
+	var candidates []candidate
+	for _, machineType := range paperspace.SortedMachineTypes() {
+		if filter.GPUModel != "" && !strings.Contains(strings.ToLower(machineType.GPUModel), strings.ToLower(filter.GPUModel)) {
+			continue
+		}
+		if filter.MinGPUCount > 0 && machineType.GPUCount < filter.MinGPUCount {
+			continue
+		}
+		if filter.MaxPrice > 0 && machineType.PricePerHour > filter.MaxPrice {
+			continue
+		}
+		if filter.MinRAM > 0 && machineType.RAMGB < filter.MinRAM {
+			continue
+		}
+		for _, region := range paperspace.Regions {
+			if filter.Region != "" && !strings.Contains(strings.ToLower(region), strings.ToLower(filter.Region)) {
+				continue
+			}
+			candidates = append(candidates, candidate{machineType: machineType, region: region})
+		}
+	}
+
+	available := make([]bool, len(candidates))
+	errs := make([]error, len(candidates))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, paperspaceAvailabilityChecks)
+	for i, c := range candidates {
+		wg.Add(1)
+		go func(i int, c candidate) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			available[i], errs[i] = p.client.GetAvailability(ctx, c.region, c.machineType.Name)
+		}(i, c)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var instances []types.GPUInstance
+	failures := 0
+	for i, c := range candidates {
+		if errs[i] != nil {
+			failures++
+		}
+		if filter.Available && !available[i] {
+			continue
+		}
+		offer := paperspace.ConvertMachineTypeToGPUInstance(c.machineType, c.region, available[i])
+		if errs[i] != nil {
+			offer.ProviderData["availability_error"] = errs[i].Error()
+		}
+		instances = append(instances, withGPUInfo(offer))
+	}
+
+	// One failed check just marks that offer unavailable; all of them failing means the API is down
+	if len(candidates) > 0 && failures == len(candidates) {
+		return nil, fmt.Errorf("error checking availability: %w", errs[0])
+	}
+
+	return instances, nil
+}
+
+func (p *paperspaceProvider) GetOffer(ctx context.Context, offerID string) (*types.GPUInstance, error) {
+	machineType, region, err := paperspace.ParseOfferID(offerID)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := paperspace.MachineTypes[machineType]
+	if !ok {
+		return nil, apperr.Errorf(apperr.NotFound, "unknown Paperspace machine type: %s", machineType)
+	}
+
+	available, err := p.client.GetAvailability(ctx, region, machineType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withGPUInfo(paperspace.ConvertMachineTypeToGPUInstance(spec, region, available))
+	return &result, nil
+}
+
+func (p *paperspaceProvider) ListInstances(ctx context.Context) ([]types.GPUInstance, error) {
+	machines, err := p.client.GetMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.GPUInstance
+	for _, machine := range machines {
+		result = append(result, withGPUInfo(paperspace.ConvertMachineToGPUInstance(machine)))
+	}
+
+	return result, nil
+}
+
+func (p *paperspaceProvider) GetInstance(ctx context.Context, providerID string) (*types.GPUInstance, error) {
+	machine, err := p.client.GetMachine(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withGPUInfo(paperspace.ConvertMachineToGPUInstance(*machine))
+	return &result, nil
+}
+
+func (p *paperspaceProvider) CreateInstance(ctx context.Context, req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
+	machineType, region, err := paperspace.ParseOfferID(req.OfferID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := paperspace.MachineTypes[machineType]; !ok {
+		return nil, apperr.Errorf(apperr.InvalidArgument, "unknown Paperspace machine type: %s", machineType)
+	}
+
+	// Paperspace machines boot from an OS template rather than a container image
+	if req.Image == "" {
+		return nil, apperr.New(apperr.InvalidArgument, "Paperspace requires a template ID in image")
+	}
+
+	createReq := &paperspace.CreateMachineRequest{
+		Region:         region,
+		MachineType:    machineType,
+		Size:           paperspaceDefaultDiskGB,
+		BillingType:    "hourly",
+		MachineName:    req.Label,
+		TemplateID:     req.Image,
+		AssignPublicIP: true,
+	}
+	if createReq.MachineName == "" {
+		createReq.MachineName = "gpu-cloud-manager-" + strings.ToLower(machineType)
+	}
+
+	// Override with resource requirements
+	if req.Resources != nil && req.Resources.MinStorage > 0 {
+		createReq.Size = req.Resources.MinStorage
+	}
+
+	machine, err := p.client.CreateMachine(ctx, createReq)
+	if err != nil {
+		return nil, err
+	}
+	if machine.MachineType == "" {
+		machine.MachineType = machineType
+	}
+
+	result := withGPUInfo(paperspace.ConvertMachineToGPUInstance(*machine))
+	return &result, nil
+}
+
+func (p *paperspaceProvider) StartInstance(ctx context.Context, providerID string) error {
+	return p.client.StartMachine(ctx, providerID)
+}
+
+func (p *paperspaceProvider) StopInstance(ctx context.Context, providerID string) error {
+	return p.client.StopMachine(ctx, providerID)
+}
+
+func (p *paperspaceProvider) DestroyInstance(ctx context.Context, providerID string) error {
+	return p.client.DestroyMachine(ctx, providerID)
+}
@@ -0,0 +1,157 @@
+package providers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/retry"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// ErrNotSupported is returned for operations a provider doesn't offer
+var ErrNotSupported = apperr.New(apperr.InvalidArgument, "operation not supported by provider")
+
+// Provider is the common interface implemented by every GPU cloud provider adapter
+type Provider interface {
+	// Name returns the provider identifier
+	Name() types.GPUProvider
+
+	// Capabilities describes what the provider supports
+	Capabilities() Capabilities
+
+	// SearchOffers returns the offers matching the provider-side parts of the filter
+	SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error)
+
+	// GetOffer returns a single offer by its provider-specific ID
+	GetOffer(ctx context.Context, offerID string) (*types.GPUInstance, error)
+
+	// ListInstances returns every instance rented on the provider account
+	ListInstances(ctx context.Context) ([]types.GPUInstance, error)
+
+	// GetInstance returns a single instance by its provider-specific ID
+	GetInstance(ctx context.Context, providerID string) (*types.GPUInstance, error)
+
+	// CreateInstance rents a new instance from an offer
+	CreateInstance(ctx context.Context, req *types.CreateInstanceRequest) (*types.GPUInstance, error)
+
+	// StartInstance starts a stopped instance
+	StartInstance(ctx context.Context, providerID string) error
+
+	// StopInstance stops a running instance
+	StopInstance(ctx context.Context, providerID string) error
+
+	// DestroyInstance permanently terminates an instance
+	DestroyInstance(ctx context.Context, providerID string) error
+}
+
+// Capabilities describes a provider and the operations it supports
+type Capabilities struct {
+	DisplayName        string
+	Website            string
+	Regions            []string
+	Features           []string
+	SupportsStartStop  bool
+	ReportsUtilization bool // Instances report GPU utilization, which idle detection needs
+}
+
+// ProviderInfo converts capabilities into the API representation
+func (c Capabilities) ProviderInfo(name types.GPUProvider, configured bool) types.ProviderInfo {
+	return types.ProviderInfo{
+		Name:         name,
+		DisplayName:  c.DisplayName,
+		Website:      c.Website,
+		Regions:      c.Regions,
+		Features:     c.Features,
+		IsConfigured: configured,
+	}
+}
+
+// Descriptor describes a provider implementation that can be registered
+type Descriptor struct {
+	Name         types.GPUProvider
+	Capabilities Capabilities
+	New          func(apiKey string) Provider
+}
+
+// Catalog lists every provider implementation known to the service.
+// Adding a provider means adding its descriptor here.
+var Catalog = []Descriptor{
+	{Name: types.VastAI, Capabilities: vastAICapabilities, New: NewVastAI},
+	{Name: types.RunPod, Capabilities: runPodCapabilities, New: NewRunPod},
+	{Name: types.LambdaLabs, Capabilities: lambdaLabsCapabilities, New: NewLambdaLabs},
+	{Name: types.Paperspace, Capabilities: paperspaceCapabilities, New: NewPaperspace},
+}
+
+// Lookup returns the catalog descriptor for a provider
+func Lookup(name types.GPUProvider) (Descriptor, bool) {
+	for _, d := range Catalog {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Descriptor{}, false
+}
+
+// ParseInstanceID splits an external instance ID (e.g. vast_123) into provider and provider ID
+func ParseInstanceID(instanceID string) (types.GPUProvider, string, error) {
+	if len(instanceID) < 5 {
+		return "", "", apperr.New(apperr.InvalidArgument, "invalid instance ID format")
+	}
+
+	// Check longer prefixes first so one provider's prefix can't shadow another's
+	descriptors := make([]Descriptor, len(Catalog))
+	copy(descriptors, Catalog)
+	sort.Slice(descriptors, func(i, j int) bool {
+		return len(descriptors[i].Name.InstanceIDPrefix()) > len(descriptors[j].Name.InstanceIDPrefix())
+	})
+
+	for _, d := range descriptors {
+		prefix := d.Name.InstanceIDPrefix()
+		if strings.HasPrefix(instanceID, prefix) && len(instanceID) > len(prefix) {
+			return d.Name, instanceID[len(prefix):], nil
+		}
+	}
+
+	return "", "", apperr.Errorf(apperr.InvalidArgument, "unknown provider in instance ID: %s", instanceID)
+}
+
+// withGPUInfo enhances an instance with the static GPU model information
+func withGPUInfo(instance types.GPUInstance) types.GPUInstance {
+	if gpuInfo, exists := types.GPUModels[instance.GPUModel]; exists {
+		instance.GPUInfo = &gpuInfo
+		instance.Performance = gpuInfo.Performance
+	}
+	return instance
+}
+
+// findOffer picks the offer with the provider-specific ID offerID out of offers
+func findOffer(offers []types.GPUInstance, offerID string) (*types.GPUInstance, error) {
+	for i := range offers {
+		if offers[i].ProviderID == offerID {
+			return &offers[i], nil
+		}
+	}
+	return nil, apperr.Errorf(apperr.NotFound, "offer not found: %s", offerID)
+}
+
+var (
+	retryPolicyMu sync.RWMutex
+	retryPolicy   = retry.DefaultPolicy()
+)
+
+// SetRetryPolicy sets how Vast.ai and RunPod providers created afterwards retry calls
+// that are safe to repeat. Creating instances is never retried.
+func SetRetryPolicy(p retry.Policy) {
+	retryPolicyMu.Lock()
+	defer retryPolicyMu.Unlock()
+	retryPolicy = p
+}
+
+func currentRetryPolicy() retry.Policy {
+	retryPolicyMu.RLock()
+	defer retryPolicyMu.RUnlock()
+	return retryPolicy
+}
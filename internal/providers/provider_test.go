@@ -0,0 +1,145 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestParseInstanceID(t *testing.T) {
+	tests := []struct {
+		instanceID string
+		provider   types.GPUProvider
+		providerID string
+		wantErr    bool
+	}{
+		{"vast_12345", types.VastAI, "12345", false},
+		{"runpod_abc123", types.RunPod, "abc123", false},
+		{"lambda_labs_0920582c7ff0", types.LambdaLabs, "0920582c7ff0", false},
+		{"aws_i-123", "", "", true},
+		{"vast", "", "", true},
+		{"vast_", "", "", true},
+	}
+
+	for _, tt := range tests {
+		provider, providerID, err := ParseInstanceID(tt.instanceID)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("expected error for %s", tt.instanceID)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", tt.instanceID, err)
+			continue
+		}
+		if provider != tt.provider || providerID != tt.providerID {
+			t.Errorf("expected %s/%s, got %s/%s", tt.provider, tt.providerID, provider, providerID)
+		}
+	}
+}
+
+func TestNewRegistryFromKeys(t *testing.T) {
+	registry := NewRegistryFromKeys(map[types.GPUProvider]string{
+		types.VastAI: "vast_key",
+		types.RunPod: "",
+	}, nil)
+
+	if _, ok := registry.Get(types.VastAI); !ok {
+		t.Error("expected Vast.ai to be registered")
+	}
+	if _, ok := registry.Get(types.RunPod); ok {
+		t.Error("expected RunPod without API key not to be registered")
+	}
+
+	all := registry.All()
+	if len(all) != 1 || all[0].Name() != types.VastAI {
+		t.Errorf("expected only Vast.ai in registry, got %d providers", len(all))
+	}
+}
+
+func TestCatalogDescriptorsMatchProviders(t *testing.T) {
+	for _, d := range Catalog {
+		p := d.New("test_key")
+		if p.Name() != d.Name {
+			t.Errorf("descriptor %s builds provider named %s", d.Name, p.Name())
+		}
+		if p.Capabilities().DisplayName != d.Capabilities.DisplayName {
+			t.Errorf("descriptor %s capabilities do not match provider", d.Name)
+		}
+	}
+}
+
+func TestSameKey(t *testing.T) {
+	if !sameKey("ssh-ed25519 AAAAC3Nza laptop", "ssh-ed25519 AAAAC3Nza ci@runner") {
+		t.Errorf("expected keys differing only in comment to match")
+	}
+	if sameKey("ssh-ed25519 AAAAC3Nza", "ssh-rsa AAAAC3Nza") {
+		t.Errorf("expected keys of different types not to match")
+	}
+	if isPublicKey("laptop") || !isPublicKey("ssh-ed25519 AAAAC3Nza") {
+		t.Errorf("expected key names and public keys to be told apart")
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	p := NewLambdaLabs("test_key")
+	if Instrument(p, nil) != p {
+		t.Errorf("expected the provider to be returned unchanged without metrics")
+	}
+
+	m := metrics.New()
+	instrumented := Instrument(p, m)
+	if instrumented.Name() != types.LambdaLabs {
+		t.Errorf("expected the instrumented provider to keep its name, got %s", instrumented.Name())
+	}
+	if err := instrumented.StopInstance(context.Background(), "abc"); err == nil {
+		t.Fatal("expected stopping a Lambda Labs instance to fail")
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	expected := `gpu_cloud_provider_requests_total{operation="stop_instance",outcome="error",provider="lambda_labs"} 1`
+	if !strings.Contains(string(body), expected) {
+		t.Errorf("expected %s in metrics output", expected)
+	}
+}
+
+func TestNewInstrumented(t *testing.T) {
+	d, _ := Lookup(types.LambdaLabs)
+	m := metrics.New()
+	if err := d.NewInstrumented("test_key", m).StopInstance(context.Background(), "abc"); err == nil {
+		t.Fatal("expected stopping a Lambda Labs instance to fail")
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	if !strings.Contains(string(body), `operation="stop_instance",outcome="error",provider="lambda_labs"`) {
+		t.Error("expected the call to be recorded")
+	}
+}
+
+func TestAccountFingerprint(t *testing.T) {
+	d, _ := Lookup(types.LambdaLabs)
+	first, again, other := d.NewInstrumented("key_one", nil), d.NewInstrumented("key_one", nil), d.NewInstrumented("key_two", nil)
+
+	if AccountFingerprint(first) == "" || AccountFingerprint(first) != AccountFingerprint(again) {
+		t.Errorf("expected providers with the same key to share a fingerprint")
+	}
+	if AccountFingerprint(first) == AccountFingerprint(other) {
+		t.Errorf("expected providers with different keys to have different fingerprints")
+	}
+	if strings.Contains(AccountFingerprint(first), "key_one") {
+		t.Errorf("expected the fingerprint not to reveal the key")
+	}
+	if AccountFingerprint(NewLambdaLabs("key_one")) != "" {
+		t.Errorf("expected no fingerprint for a provider not created from a descriptor")
+	}
+}
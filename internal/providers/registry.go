@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"sort"
+
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// Registry holds configured provider implementations keyed by provider name
+type Registry struct {
+	providers map[types.GPUProvider]Provider
+}
+
+// NewRegistry creates an empty provider registry
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[types.GPUProvider]Provider),
+	}
+}
+
+// NewRegistryFromKeys builds a registry with every catalog provider that has an API
+// key, instrumented with m
+func NewRegistryFromKeys(apiKeys map[types.GPUProvider]string, m *metrics.Metrics) *Registry {
+	registry := NewRegistry()
+	for _, d := range Catalog {
+		if key := apiKeys[d.Name]; key != "" {
+			registry.Register(d.NewInstrumented(key, m))
+		}
+	}
+	return registry
+}
+
+// Register adds a provider, replacing any existing provider with the same name
+func (r *Registry) Register(p Provider) {
+	r.providers[p.Name()] = p
+}
+
+// Get returns the provider registered under name
+func (r *Registry) Get(name types.GPUProvider) (Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// All returns every registered provider ordered by name
+func (r *Registry) All() []Provider {
+	all := make([]Provider, 0, len(r.providers))
+	for _, p := range r.providers {
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name() < all[j].Name()
+	})
+	return all
+}
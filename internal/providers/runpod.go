@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/runpod"
+	"gpu-cloud-manager/pkg/types"
+)
+
+var runPodCapabilities = Capabilities{
+	DisplayName:        "RunPod",
+	Website:            "https://runpod.io",
+	Regions:            []string{"Global", "US", "Europe", "Asia"},
+	Features:           []string{"GraphQL API", "Jupyter Support", "SSH Access", "Community & Secure Cloud"},
+	SupportsStartStop:  true,
+	ReportsUtilization: true,
+}
+
+// runPodProvider adapts the RunPod client to the Provider interface
+type runPodProvider struct {
+	client *runpod.Client
+}
+
+// NewRunPod creates a RunPod provider from an API key
+func NewRunPod(apiKey string) Provider {
+	client := runpod.NewClient(apiKey)
+	client.SetRetryPolicy(currentRetryPolicy())
+	return &runPodProvider{client: client}
+}
+
+func (p *runPodProvider) Name() types.GPUProvider {
+	return types.RunPod
+}
+
+func (p *runPodProvider) Capabilities() Capabilities {
+	return runPodCapabilities
+}
+
+func (p *runPodProvider) SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
+	gpuTypes, err := p.client.GetGPUTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []types.GPUInstance
+	for _, gpuType := range gpuTypes {
+		instances = append(instances, withGPUInfo(runpod.ConvertGPUTypeToGPUInstance(gpuType)))
+	}
+
+	return instances, nil
+}
+
+func (p *runPodProvider) GetOffer(ctx context.Context, offerID string) (*types.GPUInstance, error) {
+	offers, err := p.SearchOffers(ctx, &types.AdvancedSearchFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return findOffer(offers, offerID)
+}
+
+func (p *runPodProvider) ListInstances(ctx context.Context) ([]types.GPUInstance, error) {
+	pods, err := p.client.SearchPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.GPUInstance
+	for _, pod := range pods {
+		result = append(result, withGPUInfo(runpod.ConvertPodToGPUInstance(pod)))
+	}
+
+	return result, nil
+}
+
+func (p *runPodProvider) GetInstance(ctx context.Context, providerID string) (*types.GPUInstance, error) {
+	// RunPod doesn't have a direct get instance by ID, so we search through all pods
+	pods, err := p.client.SearchPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range pods {
+		if pod.ID == providerID {
+			result := withGPUInfo(runpod.ConvertPodToGPUInstance(pod))
+			return &result, nil
+		}
+	}
+
+	return nil, apperr.Errorf(apperr.NotFound, "RunPod instance not found: %s", providerID)
+}
+
+func (p *runPodProvider) CreateInstance(ctx context.Context, req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
+	runpodReq := &runpod.CreatePodRequest{
+		Name:            req.Label,
+		ImageName:       req.Image,
+		GPUTypeID:       req.OfferID,
+		CloudType:       "COMMUNITY", // Default to community cloud
+		SupportPublicIp: true,
+		StartJupyter:    false,
+		StartSsh:        true,
+		ContainerDisk:   10, // Default container disk
+		VolumeInGb:      0,  // No additional volume by default
+		VolumeMountPath: "/workspace",
+		Ports:           "22/tcp,8888/tcp", // SSH and Jupyter
+	}
+
+	if runpodReq.ImageName == "" {
+		runpodReq.ImageName = "pytorch/pytorch:latest"
+	}
+
+	// Convert environment variables
+	for key, value := range req.Environment {
+		runpodReq.Env = append(runpodReq.Env, runpod.EnvVar{
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	// Override with resource requirements
+	if req.Resources != nil {
+		if req.Resources.MinStorage > 0 {
+			runpodReq.VolumeInGb = req.Resources.MinStorage
+		}
+	}
+
+	// Handle ports
+	if len(req.Ports) > 0 {
+		var ports []string
+		for _, port := range req.Ports {
+			protocol := "tcp"
+			if port.Protocol != "" {
+				protocol = port.Protocol
+			}
+			ports = append(ports, fmt.Sprintf("%d/%s", port.ContainerPort, protocol))
+		}
+		runpodReq.Ports = strings.Join(ports, ",")
+	}
+
+	pod, err := p.client.CreatePod(ctx, runpodReq)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withGPUInfo(runpod.ConvertPodToGPUInstance(*pod))
+	return &result, nil
+}
+
+func (p *runPodProvider) StartInstance(ctx context.Context, providerID string) error {
+	return p.client.ResumePod(ctx, providerID)
+}
+
+func (p *runPodProvider) StopInstance(ctx context.Context, providerID string) error {
+	return p.client.StopPod(ctx, providerID)
+}
+
+func (p *runPodProvider) DestroyInstance(ctx context.Context, providerID string) error {
+	return p.client.TerminatePod(ctx, providerID)
+}
@@ -0,0 +1,184 @@
+package providers
+
+import (
+	"context"
+	"strconv"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+	"gpu-cloud-manager/pkg/vastai"
+)
+
+var vastAICapabilities = Capabilities{
+	DisplayName:       "Vast.ai",
+	Website:           "https://vast.ai",
+	Regions:           []string{"US-East", "US-West", "Europe", "Asia"},
+	Features:          []string{"SSH Access", "Docker Support", "Jupyter Notebooks", "Custom Images"},
+	SupportsStartStop: true,
+}
+
+// vastAIProvider adapts the Vast.ai client to the Provider interface
+type vastAIProvider struct {
+	client *vastai.Client
+}
+
+// NewVastAI creates a Vast.ai provider from an API key
+func NewVastAI(apiKey string) Provider {
+	client := vastai.NewClient(apiKey)
+	client.SetRetryPolicy(currentRetryPolicy())
+	return &vastAIProvider{client: client}
+}
+
+func (p *vastAIProvider) Name() types.GPUProvider {
+	return types.VastAI
+}
+
+func (p *vastAIProvider) Capabilities() Capabilities {
+	return vastAICapabilities
+}
+
+func (p *vastAIProvider) SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
+	vastFilter := &vastai.SearchOffersRequest{
+		AvailableOnly: filter.Available,
+	}
+
+	if filter.GPUModel != "" {
+		vastFilter.GPUName = filter.GPUModel
+	}
+	if filter.MinGPUCount > 0 {
+		vastFilter.MinGPUCount = filter.MinGPUCount
+	}
+	if filter.MaxPrice > 0 {
+		vastFilter.MaxPrice = filter.MaxPrice
+	}
+	if filter.MinRAM > 0 {
+		vastFilter.MinRAM = filter.MinRAM
+	}
+	if filter.Region != "" {
+		vastFilter.Datacenter = filter.Region
+	}
+
+	offers, err := p.client.SearchOffers(ctx, vastFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []types.GPUInstance
+	for _, offer := range offers {
+		instances = append(instances, withGPUInfo(vastai.ConvertOfferToGPUInstance(offer)))
+	}
+
+	return instances, nil
+}
+
+func (p *vastAIProvider) GetOffer(ctx context.Context, offerID string) (*types.GPUInstance, error) {
+	id, err := parseVastID(offerID)
+	if err != nil {
+		return nil, err
+	}
+
+	offer, err := p.client.GetOffer(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withGPUInfo(vastai.ConvertOfferToGPUInstance(*offer))
+	return &result, nil
+}
+
+func (p *vastAIProvider) ListInstances(ctx context.Context) ([]types.GPUInstance, error) {
+	instances, err := p.client.GetInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.GPUInstance
+	for _, instance := range instances {
+		result = append(result, withGPUInfo(vastai.ConvertInstanceToGPUInstance(instance)))
+	}
+
+	return result, nil
+}
+
+func (p *vastAIProvider) GetInstance(ctx context.Context, providerID string) (*types.GPUInstance, error) {
+	id, err := parseVastID(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := p.client.GetInstance(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withGPUInfo(vastai.ConvertInstanceToGPUInstance(*instance))
+	return &result, nil
+}
+
+func (p *vastAIProvider) CreateInstance(ctx context.Context, req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
+	offerID, err := strconv.Atoi(req.OfferID)
+	if err != nil {
+		return nil, apperr.Errorf(apperr.InvalidArgument, "invalid offer ID: %v", err)
+	}
+
+	vastReq := &vastai.CreateInstanceRequest{
+		OfferID:       offerID,
+		Price:         0,  // Use default price from offer
+		DiskSizeGB:    10, // Default disk size
+		Image:         req.Image,
+		Label:         req.Label,
+		OnStartScript: req.OnStartScript,
+	}
+
+	if vastReq.Image == "" {
+		vastReq.Image = "pytorch/pytorch:latest" // Default image
+	}
+
+	// Override with resource requirements
+	if req.Resources != nil {
+		if req.Resources.MinStorage > 0 {
+			vastReq.DiskSizeGB = req.Resources.MinStorage
+		}
+	}
+
+	instance, err := p.client.CreateInstance(ctx, vastReq)
+	if err != nil {
+		return nil, err
+	}
+
+	result := withGPUInfo(vastai.ConvertInstanceToGPUInstance(*instance))
+	return &result, nil
+}
+
+func (p *vastAIProvider) StartInstance(ctx context.Context, providerID string) error {
+	id, err := parseVastID(providerID)
+	if err != nil {
+		return err
+	}
+	return p.client.StartInstance(ctx, id)
+}
+
+func (p *vastAIProvider) StopInstance(ctx context.Context, providerID string) error {
+	id, err := parseVastID(providerID)
+	if err != nil {
+		return err
+	}
+	return p.client.StopInstance(ctx, id)
+}
+
+func (p *vastAIProvider) DestroyInstance(ctx context.Context, providerID string) error {
+	id, err := parseVastID(providerID)
+	if err != nil {
+		return err
+	}
+	return p.client.DestroyInstance(ctx, id)
+}
+
+// parseVastID converts a provider ID into the numeric ID used by Vast.ai
+func parseVastID(providerID string) (int, error) {
+	id, err := strconv.Atoi(providerID)
+	if err != nil {
+		return 0, apperr.Errorf(apperr.InvalidArgument, "invalid provider ID: %v", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, activeID string, ids ...string) *Keyring {
+	t.Helper()
+	keys := make(map[string][]byte)
+	for i, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(i + 1)}, 32)
+	}
+	k, err := NewKeyring(keys, activeID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return k
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+
+	encrypted, err := k.Encrypt("vast-secret-key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !IsEncrypted(encrypted) || strings.Contains(encrypted, "vast-secret-key") {
+		t.Errorf("expected an encrypted value, got %s", encrypted)
+	}
+	if id, _ := KeyID(encrypted); id != "k1" {
+		t.Errorf("expected key ID k1, got %s", id)
+	}
+
+	again, _ := k.Encrypt("vast-secret-key")
+	if again == encrypted {
+		t.Errorf("expected a fresh data key and nonce per encryption")
+	}
+
+	decrypted, err := k.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decrypted != "vast-secret-key" {
+		t.Errorf("expected vast-secret-key, got %s", decrypted)
+	}
+}
+
+func TestDecryptRejectsTamperingAndUnknownKeys(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+	encrypted, _ := k.Encrypt("secret")
+
+	tampered := encrypted[:len(encrypted)-4] + "AAA="
+	if _, err := k.Decrypt(tampered); err == nil {
+		t.Errorf("expected an error for a tampered value")
+	}
+
+	// Relabelling the wrapped key with another ID must fail authentication
+	other := testKeyring(t, "k2", "k2", "k1")
+	relabelled := strings.Replace(encrypted, "enc:v1:k1:", "enc:v1:k2:", 1)
+	if _, err := other.Decrypt(relabelled); err == nil {
+		t.Errorf("expected an error for a relabelled value")
+	}
+
+	unknown := testKeyring(t, "k9", "k9")
+	if _, err := unknown.Decrypt(encrypted); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	old := testKeyring(t, "old", "old")
+	encrypted, _ := old.Encrypt("secret")
+
+	rotated := testKeyring(t, "new", "old", "new")
+
+	value, changed, err := rotated.Reencrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !changed {
+		t.Errorf("expected value to be re-encrypted")
+	}
+	if id, _ := KeyID(value); id != "new" {
+		t.Errorf("expected key ID new, got %s", id)
+	}
+	if plaintext, _ := rotated.Decrypt(value); plaintext != "secret" {
+		t.Errorf("expected secret, got %s", plaintext)
+	}
+
+	if _, changed, _ := rotated.Reencrypt(value); changed {
+		t.Errorf("expected values under the active key to be left alone")
+	}
+
+	value, changed, err = rotated.Reencrypt("legacy-plaintext")
+	if err != nil || !changed {
+		t.Fatalf("expected plaintext to be encrypted, got changed=%v err=%v", changed, err)
+	}
+	if plaintext, _ := rotated.Decrypt(value); plaintext != "legacy-plaintext" {
+		t.Errorf("expected legacy-plaintext, got %s", plaintext)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+
+	k, err := ParseKeyring("2024:"+key+", 2025:"+key, "2025")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k.ActiveKeyID() != "2025" {
+		t.Errorf("expected active key 2025, got %s", k.ActiveKeyID())
+	}
+	if ids := k.KeyIDs(); len(ids) != 2 || ids[0] != "2024" {
+		t.Errorf("expected keys [2024 2025], got %v", ids)
+	}
+
+	single, err := ParseKeyring("only:"+key, "")
+	if err != nil || single.ActiveKeyID() != "only" {
+		t.Errorf("expected the only key to be active, got %v, %v", single, err)
+	}
+
+	if k, err := ParseKeyring("", ""); k != nil || err != nil {
+		t.Errorf("expected nil keyring for empty spec, got %v, %v", k, err)
+	}
+
+	invalid := []struct {
+		spec     string
+		activeID string
+	}{
+		{"nokey", ""},
+		{"k1:not-base64!", ""},
+		{"k1:" + base64.StdEncoding.EncodeToString([]byte("short")), ""},
+		{"k1:" + key + ",k2:" + key, ""},
+		{"k1:" + key, "k2"},
+	}
+	for _, tt := range invalid {
+		if _, err := ParseKeyring(tt.spec, tt.activeID); err == nil {
+			t.Errorf("expected error for spec %q active %q", tt.spec, tt.activeID)
+		}
+	}
+}
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/notify"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAlertRuleNotFound is returned when an alert rule doesn't exist or belongs to another user
+	ErrAlertRuleNotFound = apperr.New(apperr.NotFound, "alert rule not found")
+
+	// ErrInvalidAlertRule is returned for alert rules that could never be delivered
+	ErrInvalidAlertRule = apperr.New(apperr.InvalidArgument, "invalid alert rule")
+)
+
+// maxAlertsPerRefresh bounds how many offers one rule notifies about per offer refresh
+const maxAlertsPerRefresh = 5
+
+// priceAlertEvent names the webhook event sent for matching offers
+const priceAlertEvent = "price_alert"
+
+// AlertService manages price alert rules and notifies their webhooks about matching offers
+type AlertService struct {
+	db       *gorm.DB
+	config   *config.Config
+	cache    *OfferCache
+	sender   *notify.Sender
+	registry *providers.Registry // Global provider keys, used to keep watched offers fresh
+	metrics  *metrics.Metrics
+
+	evaluating sync.Mutex // Serializes evaluations so concurrent refreshes can't both alert on an offer
+}
+
+// NewAlertService creates an alert service that evaluates rules on every refresh of cache
+func NewAlertService(db *gorm.DB, cfg *config.Config, cache *OfferCache, sender *notify.Sender, m *metrics.Metrics) *AlertService {
+	s := &AlertService{
+		db:       db,
+		config:   cfg,
+		cache:    cache,
+		sender:   sender,
+		registry: providers.NewRegistryFromKeys(globalProviderKeys(cfg), m),
+		metrics:  m,
+	}
+	cache.OnRefresh(s.evaluate)
+	return s
+}
+
+// List returns the user's alert rules
+func (s *AlertService) List(ctx context.Context, userID uint) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("error loading alert rules: %v", err)
+	}
+	return rules, nil
+}
+
+// Get returns one of the user's alert rules
+func (s *AlertService) Get(ctx context.Context, userID, id uint) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&rule).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAlertRuleNotFound
+		}
+		return nil, fmt.Errorf("error loading alert rule: %v", err)
+	}
+	return &rule, nil
+}
+
+// Create stores a new alert rule with a freshly generated webhook secret
+func (s *AlertService) Create(ctx context.Context, userID uint, req *types.AlertRuleRequest) (*models.AlertRule, error) {
+	if err := s.validate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	rule := models.AlertRule{
+		UserID:        userID,
+		WebhookSecret: secret,
+		IsEnabled:     true,
+	}
+	applyAlertRuleRequest(&rule, req)
+
+	// Select all fields so an explicit is_enabled=false isn't replaced by the column default
+	if err := s.db.WithContext(ctx).Select("*").Create(&rule).Error; err != nil {
+		return nil, fmt.Errorf("error saving alert rule: %v", err)
+	}
+
+	return &rule, nil
+}
+
+// Update replaces an alert rule's settings, keeping its webhook secret
+func (s *AlertService) Update(ctx context.Context, userID, id uint, req *types.AlertRuleRequest) (*models.AlertRule, error) {
+	if err := s.validate(ctx, req); err != nil {
+		return nil, err
+	}
+
+	rule, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	applyAlertRuleRequest(rule, req)
+
+	if err := s.db.WithContext(ctx).Save(rule).Error; err != nil {
+		return nil, fmt.Errorf("error updating alert rule: %v", err)
+	}
+
+	return rule, nil
+}
+
+// Delete removes an alert rule and its delivery log
+func (s *AlertService) Delete(ctx context.Context, userID, id uint) error {
+	rule, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&models.AlertDelivery{}).Error; err != nil {
+			return fmt.Errorf("error deleting alert deliveries: %v", err)
+		}
+		if err := tx.Delete(rule).Error; err != nil {
+			return fmt.Errorf("error deleting alert rule: %v", err)
+		}
+		return nil
+	})
+}
+
+// Deliveries returns the most recent notifications sent for one of the user's rules
+func (s *AlertService) Deliveries(ctx context.Context, userID, id uint, limit int) ([]models.AlertDelivery, error) {
+	rule, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []models.AlertDelivery
+	err = s.db.WithContext(ctx).Where("rule_id = ?", rule.ID).Order("created_at DESC").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading alert deliveries: %v", err)
+	}
+	return deliveries, nil
+}
+
+// Run refreshes the offers watched by enabled rules on every interval until ctx is
+// cancelled, so alerts fire even when nobody is searching. Refreshes go through the
+// offer cache, which evaluates the rules.
+func (s *AlertService) Run(ctx context.Context) {
+	if len(s.registry.All()) == 0 {
+		slog.WarnContext(ctx, "Alerts: no provider API keys configured, offers are only refreshed by searches")
+		return
+	}
+
+	ticker := time.NewTicker(s.config.AlertRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		s.RefreshOnce(ctx)
+		s.metrics.ObserveJob("alert_refresh", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshOnce searches once for the offers of every distinct enabled rule filter
+func (s *AlertService) RefreshOnce(ctx context.Context) {
+	rules, err := s.enabledRules(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Alerts: error loading rules", "error", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if ctx.Err() != nil {
+			return
+		}
+
+		filter := types.AdvancedSearchFilter(rule.Filter)
+		key := offerCacheKey(filter.Provider, "", &filter)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		var targets []providers.Provider
+		for _, p := range s.registry.All() {
+			if filter.Provider == "" || filter.Provider == p.Name() {
+				targets = append(targets, p)
+			}
+		}
+		fanOutSearch(ctx, s.cache, targets, &filter, s.config.UpstreamTimeout)
+	}
+}
+
+// evaluate notifies every enabled rule about refreshed offers it matches. Offers a rule
+// was notified about within the dedup window are skipped unless their price dropped.
+func (s *AlertService) evaluate(provider types.GPUProvider, offers []types.GPUInstance) {
+	s.evaluating.Lock()
+	defer s.evaluating.Unlock()
+
+	ctx := context.Background()
+	rules, err := s.enabledRules(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Alerts: error loading rules", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range rules {
+		rule := &rules[i]
+		filter := types.AdvancedSearchFilter(rule.Filter)
+		if filter.Provider != "" && filter.Provider != provider {
+			continue
+		}
+
+		notified := 0
+		for _, offer := range matchingOffers(offers, &filter, rule.PriceThreshold) {
+			if notified == maxAlertsPerRefresh {
+				break
+			}
+
+			duplicate, err := s.alreadyNotified(ctx, rule.ID, offer, now)
+			if err != nil {
+				slog.ErrorContext(ctx, "Alerts: error checking previous deliveries", "rule_id", rule.ID, "error", err)
+				break
+			}
+			if duplicate {
+				continue
+			}
+
+			delivery := models.AlertDelivery{
+				RuleID:       rule.ID,
+				OfferID:      offer.ID,
+				PricePerHour: offer.PricePerHour,
+				Status:       models.DeliveryPending,
+			}
+			if err := s.db.WithContext(ctx).Create(&delivery).Error; err != nil {
+				slog.ErrorContext(ctx, "Alerts: error logging delivery", "rule_id", rule.ID, "error", err)
+				break
+			}
+			notified++
+
+			r, d, o := *rule, delivery, offer
+			s.sender.Go(func(ctx context.Context) { s.deliver(ctx, r, d, o) })
+		}
+
+		if notified > 0 {
+			if err := s.db.WithContext(ctx).Model(rule).Update("last_triggered_at", now).Error; err != nil {
+				slog.ErrorContext(ctx, "Alerts: error recording trigger", "rule_id", rule.ID, "error", err)
+			}
+		}
+	}
+}
+
+// alreadyNotified reports whether the rule recently notified about the offer at the same or a lower price
+func (s *AlertService) alreadyNotified(ctx context.Context, ruleID uint, offer types.GPUInstance, now time.Time) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.AlertDelivery{}).
+		Where("rule_id = ? AND offer_id = ? AND created_at > ? AND price_per_hour <= ?", ruleID, offer.ID, now.Add(-s.config.AlertDedupWindow), offer.PricePerHour).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("error checking previous deliveries: %v", err)
+	}
+	return count > 0, nil
+}
+
+// deliver sends one notification and records the outcome in the delivery log
+func (s *AlertService) deliver(ctx context.Context, rule models.AlertRule, delivery models.AlertDelivery, offer types.GPUInstance) {
+	event := types.PriceAlertEvent{
+		Event:          priceAlertEvent,
+		RuleID:         rule.ID,
+		RuleName:       rule.Name,
+		PriceThreshold: rule.PriceThreshold,
+		Offer:          offer,
+		TriggeredAt:    delivery.CreatedAt,
+	}
+
+	result, err := s.sender.Send(ctx, rule.WebhookURL, rule.WebhookSecret, priceAlertEvent, event)
+
+	updates := map[string]interface{}{
+		"status":        models.DeliveryDelivered,
+		"attempts":      result.Attempts,
+		"response_code": result.StatusCode,
+		"delivered_at":  time.Now(),
+	}
+	if err != nil {
+		updates["status"] = models.DeliveryFailed
+		updates["error"] = err.Error()
+		updates["delivered_at"] = nil
+	}
+
+	if err := s.db.Model(&delivery).Updates(updates).Error; err != nil {
+		slog.Error("Alerts: error updating delivery", "rule_id", rule.ID, "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// enabledRules loads every enabled alert rule
+func (s *AlertService) enabledRules(ctx context.Context) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := s.db.WithContext(ctx).Where("is_enabled = ?", true).Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("error loading alert rules: %v", err)
+	}
+	return rules, nil
+}
+
+// matchingOffers returns the offers matching filter priced at or below threshold, cheapest first
+func matchingOffers(offers []types.GPUInstance, filter *types.AdvancedSearchFilter, threshold float64) []types.GPUInstance {
+	var matches []types.GPUInstance
+	for _, offer := range offers {
+		if offer.PricePerHour > threshold || !matchesProviderFilter(offer, filter) || !matchesAdvancedFilter(offer, filter) {
+			continue
+		}
+		matches = append(matches, offer)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].PricePerHour < matches[j].PricePerHour
+	})
+	return matches
+}
+
+// matchesProviderFilter checks an offer against the filters providers normally apply,
+// as refreshed offers may have been fetched for a broader search
+func matchesProviderFilter(offer types.GPUInstance, filter *types.AdvancedSearchFilter) bool {
+	if filter.GPUModel != "" && !strings.Contains(strings.ToLower(offer.GPUModel), strings.ToLower(filter.GPUModel)) {
+		return false
+	}
+	if filter.MinGPUCount > 0 && offer.GPUCount < filter.MinGPUCount {
+		return false
+	}
+	if filter.MaxPrice > 0 && offer.PricePerHour > filter.MaxPrice {
+		return false
+	}
+	if filter.MinRAM > 0 && offer.RAM < filter.MinRAM {
+		return false
+	}
+	if filter.Region != "" && !strings.Contains(strings.ToLower(offer.Region), strings.ToLower(filter.Region)) {
+		return false
+	}
+	if filter.Available && offer.Status == types.StatusUnavailable {
+		return false
+	}
+	return true
+}
+
+// validate rejects rules that can't work or whose webhook points at an internal address
+func (s *AlertService) validate(ctx context.Context, req *types.AlertRuleRequest) error {
+	if err := validateAlertRule(req); err != nil {
+		return err
+	}
+	if err := s.sender.CheckURL(ctx, req.WebhookURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAlertRule, err)
+	}
+	return nil
+}
+
+// validateAlertRule rejects rules whose filter or webhook can't work
+func validateAlertRule(req *types.AlertRuleRequest) error {
+	if req.PriceThreshold <= 0 {
+		return fmt.Errorf("%w: price_threshold must be positive", ErrInvalidAlertRule)
+	}
+	if req.Filter.Provider != "" {
+		if _, known := providers.Lookup(req.Filter.Provider); !known {
+			return fmt.Errorf("%w: unsupported provider: %s", ErrInvalidAlertRule, req.Filter.Provider)
+		}
+	}
+
+	webhook, err := url.Parse(req.WebhookURL)
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+		return fmt.Errorf("%w: webhook_url must be an absolute http or https URL", ErrInvalidAlertRule)
+	}
+	return nil
+}
+
+// applyAlertRuleRequest copies the request's settings onto a rule
+func applyAlertRuleRequest(rule *models.AlertRule, req *types.AlertRuleRequest) {
+	rule.Name = req.Name
+	rule.Filter = models.AlertFilter(req.Filter)
+	rule.PriceThreshold = req.PriceThreshold
+	rule.WebhookURL = req.WebhookURL
+	if req.IsEnabled != nil {
+		rule.IsEnabled = *req.IsEnabled
+	}
+}
+
+// generateWebhookSecret creates the key deliveries for a rule are signed with
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %v", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestMatchingOffers(t *testing.T) {
+	offers := []types.GPUInstance{
+		{ID: "vast_1", GPUModel: "A100 SXM4", GPUCount: 1, PricePerHour: 1.10, Region: "US-West"},
+		{ID: "vast_2", GPUModel: "A100 PCIE", GPUCount: 1, PricePerHour: 0.90, Region: "EU-North"},
+		{ID: "vast_3", GPUModel: "A100 SXM4", GPUCount: 1, PricePerHour: 1.60, Region: "US-East"},
+		{ID: "vast_4", GPUModel: "RTX 4090", GPUCount: 1, PricePerHour: 0.40, Region: "US-West"},
+		{ID: "vast_5", GPUModel: "A100 SXM4", GPUCount: 2, PricePerHour: 0.80, Region: "US-West", Status: types.StatusUnavailable},
+	}
+
+	filter := &types.AdvancedSearchFilter{GPUModel: "a100", Available: true}
+	matches := matchingOffers(offers, filter, 1.20)
+	if len(matches) != 2 || matches[0].ID != "vast_2" || matches[1].ID != "vast_1" {
+		t.Errorf("expected vast_2 then vast_1, got %v", matches)
+	}
+
+	filter = &types.AdvancedSearchFilter{GPUModel: "a100", Regions: []string{"us-"}}
+	matches = matchingOffers(offers, filter, 1.20)
+	if len(matches) != 2 || matches[0].ID != "vast_5" || matches[1].ID != "vast_1" {
+		t.Errorf("expected vast_5 then vast_1, got %v", matches)
+	}
+}
+
+func TestValidateAlertRule(t *testing.T) {
+	valid := &types.AlertRuleRequest{Name: "cheap a100", PriceThreshold: 1.2, WebhookURL: "https://example.com/hooks/gpu"}
+	if err := validateAlertRule(valid); err != nil {
+		t.Errorf("expected a valid rule, got %v", err)
+	}
+
+	invalid := []*types.AlertRuleRequest{
+		{Name: "no threshold", WebhookURL: "https://example.com/hooks/gpu"},
+		{Name: "relative url", PriceThreshold: 1, WebhookURL: "/hooks/gpu"},
+		{Name: "bad scheme", PriceThreshold: 1, WebhookURL: "ftp://example.com/hooks"},
+		{Name: "bad provider", PriceThreshold: 1, WebhookURL: "https://example.com", Filter: types.AdvancedSearchFilter{Provider: "nimbus"}},
+	}
+	for _, req := range invalid {
+		if err := validateAlertRule(req); !errors.Is(err, ErrInvalidAlertRule) {
+			t.Errorf("%s: expected an invalid rule error, got %v", req.Name, err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+
+	"gorm.io/gorm"
+)
+
+// Actions recorded on audit entries
+const (
+	auditActionStop    = "instance.stop"
+	auditActionDestroy = "instance.destroy"
+)
+
+// automatedRetryBackoff is how long a failed automated stop or destroy waits before it is retried
+const automatedRetryBackoff = 15 * time.Minute
+
+// automatedAction is a stop or destroy the system performs on a user's instance
+type automatedAction struct {
+	actor     string // Also recorded as the source of the status transition
+	action    string
+	eventType string
+	reason    string
+	details   models.JSONMap
+}
+
+// runAutomated stops or destroys an instance on the system's behalf. The attempt is
+// recorded as an audit entry whatever its outcome, and on success as an instance event.
+func (s *GPUService) runAutomated(ctx context.Context, row *models.Instance, a automatedAction) error {
+	var run func(context.Context, *models.Instance, providers.Provider, string) error
+	switch a.action {
+	case auditActionStop:
+		run = s.stopInstance
+	case auditActionDestroy:
+		run = s.destroyInstance
+	default:
+		return fmt.Errorf("unknown automated action %s", a.action)
+	}
+
+	p, err := s.provider(ctx, row.UserID, row.Provider)
+	if err == nil {
+		err = run(ctx, row, p, a.actor)
+	}
+
+	instanceID := row.ID
+	entry := models.AuditEntry{
+		UserID:     row.UserID,
+		InstanceID: &instanceID,
+		ResourceID: row.ToGPUInstance().ID,
+		Actor:      a.actor,
+		Action:     a.action,
+		Reason:     a.reason,
+		Outcome:    models.AuditSucceeded,
+		Details:    a.details,
+	}
+	if err != nil {
+		entry.Outcome = models.AuditFailed
+		entry.Error = err.Error()
+	}
+
+	recordErr := s.db.WithContext(context.WithoutCancel(ctx)).Transaction(func(tx *gorm.DB) error {
+		if err == nil {
+			event := models.InstanceEvent{InstanceID: row.ID, Type: a.eventType, Message: a.reason}
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&entry).Error
+	})
+
+	if err != nil {
+		return fmt.Errorf("error running %s: %v", a.action, err)
+	}
+	if recordErr != nil {
+		return fmt.Errorf("%s succeeded but could not be recorded: %v", a.action, recordErr)
+	}
+	return nil
+}
+
+// automatedRetryDue reports whether an automated action on an instance may run, which
+// it may unless its latest attempt failed less than automatedRetryBackoff ago
+func automatedRetryDue(db *gorm.DB, instanceID uint, action string, now time.Time) (bool, error) {
+	var entry models.AuditEntry
+	err := db.Where("instance_id = ? AND action = ?", instanceID, action).Order("created_at DESC").Limit(1).Find(&entry).Error
+	if err != nil {
+		return false, fmt.Errorf("error loading audit log: %v", err)
+	}
+	if entry.ID == 0 || entry.Outcome != models.AuditFailed {
+		return true, nil
+	}
+	return now.Sub(entry.CreatedAt) >= automatedRetryBackoff, nil
+}
+
+// AuditLog returns the most recent automated actions taken on the user's instances
+func (s *GPUService) AuditLog(ctx context.Context, userID uint, limit int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading audit log: %v", err)
+	}
+	return entries, nil
+}
+
+// InstanceEvents returns the most recent events of one of the user's instances
+func (s *GPUService) InstanceEvents(ctx context.Context, userID uint, instanceID string, limit int) ([]models.InstanceEvent, error) {
+	row, err := s.ownedRow(ctx, userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.InstanceEvent
+	err = s.db.WithContext(ctx).Where("instance_id = ?", row.ID).Order("created_at DESC").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading instance events: %v", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/apperr"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAPIKey is returned when an API key is unknown or belongs to an inactive user
+var ErrInvalidAPIKey = apperr.New(apperr.Unauthenticated, "invalid or inactive API key")
+
+// AuthService resolves API keys to users
+type AuthService struct {
+	db *gorm.DB
+}
+
+// NewAuthService creates a new authentication service
+func NewAuthService(db *gorm.DB) *AuthService {
+	return &AuthService{
+		db: db,
+	}
+}
+
+// Authenticate returns the active user owning the given API key
+func (s *AuthService) Authenticate(ctx context.Context, apiKey string) (*models.User, error) {
+	if apiKey == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var user models.User
+	err := s.db.WithContext(ctx).Where("api_key_hash = ?", models.HashAPIKey(apiKey)).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("error looking up API key: %v", err)
+	}
+
+	if !user.IsActive {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &user, nil
+}
+
+// EnsureAdminUser creates the admin user, or rotates its key, so that apiKey authenticates as admin
+func (s *AuthService) EnsureAdminUser(email, apiKey string) error {
+	var user models.User
+	err := s.db.Where("email = ?", email).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("error looking up admin user: %v", err)
+	}
+
+	user.Email = email
+	user.APIKeyHash = models.HashAPIKey(apiKey)
+	user.IsAdmin = true
+	if user.ID == 0 {
+		user.Name = "System Administrator"
+		user.IsActive = true
+	}
+
+	if err := s.db.Save(&user).Error; err != nil {
+		return fmt.Errorf("error saving admin user: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// runningBillingStatuses are the statuses in which an instance is billed as running
+var runningBillingStatuses = []types.InstanceStatus{types.StatusRunning, types.StatusLoading, types.StatusStarting, types.StatusStopping}
+
+// billingState returns how an instance in status is billed, or "" when it isn't
+func billingState(status types.InstanceStatus) string {
+	for _, running := range runningBillingStatuses {
+		if status == running {
+			return models.BillingRunning
+		}
+	}
+	if status == types.StatusOffline {
+		return models.BillingStopped
+	}
+	return ""
+}
+
+// syncCostSegment closes the instance's open cost segment when its billing state or
+// price no longer match the instance, and opens one for its current state. It is a
+// no-op when the open segment is still current.
+func syncCostSegment(tx *gorm.DB, row *models.Instance, at time.Time) error {
+	state := billingState(row.Status)
+	price := 0.0
+	if state == models.BillingRunning {
+		price = row.PricePerHour
+	}
+
+	var open models.CostSegment
+	if err := tx.Where("instance_id = ? AND ended_at IS NULL", row.ID).Limit(1).Find(&open).Error; err != nil {
+		return err
+	}
+	if open.ID != 0 {
+		if open.State == state && open.PricePerHour == price {
+			return nil
+		}
+		if err := tx.Model(&open).Update("ended_at", at).Error; err != nil {
+			return err
+		}
+	}
+
+	if state == "" {
+		return nil
+	}
+	return tx.Create(&models.CostSegment{
+		InstanceID:   row.ID,
+		UserID:       row.UserID,
+		Provider:     row.Provider,
+		GPUModel:     row.GPUModel,
+		Label:        row.Label,
+		State:        state,
+		PricePerHour: price,
+		StartedAt:    at,
+	}).Error
+}
+
+// accruedCosts returns the cost accrued so far by each of the instances
+func (s *GPUService) accruedCosts(ctx context.Context, instanceIDs []uint, now time.Time) (map[uint]float64, error) {
+	costs := make(map[uint]float64, len(instanceIDs))
+	if len(instanceIDs) == 0 {
+		return costs, nil
+	}
+
+	var segments []models.CostSegment
+	err := s.db.WithContext(ctx).Where("instance_id IN ? AND state = ?", instanceIDs, models.BillingRunning).Find(&segments).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading cost segments: %v", err)
+	}
+
+	for _, segment := range segments {
+		costs[segment.InstanceID] += segmentCost(segment, segment.StartedAt, now)
+	}
+	return costs, nil
+}
+
+// segmentCost is what a segment cost between from and to
+func segmentCost(segment models.CostSegment, from, to time.Time) float64 {
+	return segment.PricePerHour * segmentOverlap(segment, from, to).Hours()
+}
+
+// segmentOverlap is how much of the segment falls between from and to, counting an
+// open segment as lasting until to
+func segmentOverlap(segment models.CostSegment, from, to time.Time) time.Duration {
+	start := segment.StartedAt
+	if start.Before(from) {
+		start = from
+	}
+	end := to
+	if segment.EndedAt != nil && segment.EndedAt.Before(to) {
+		end = *segment.EndedAt
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// RunningInstanceStats counts the instances billed as running and their combined
+// hourly price, per provider
+func (s *GPUService) RunningInstanceStats(ctx context.Context) (map[string]metrics.InstanceStats, error) {
+	var rows []struct {
+		Provider    string
+		Running     int
+		HourlySpend float64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Instance{}).
+		Select("provider, COUNT(*) AS running, COALESCE(SUM(price_per_hour), 0) AS hourly_spend").
+		Where("status IN ?", runningBillingStatuses).
+		Group("provider").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("error counting running instances: %v", err)
+	}
+
+	stats := make(map[string]metrics.InstanceStats, len(rows))
+	for _, row := range rows {
+		stats[row.Provider] = metrics.InstanceStats{Running: row.Running, HourlySpend: row.HourlySpend}
+	}
+	return stats, nil
+}
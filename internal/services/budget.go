@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrBudgetExceeded is returned when running an instance would break a budget
+var ErrBudgetExceeded = apperr.New(apperr.PermissionDenied, "budget exceeded")
+
+// launchCost is what an instance about to run adds to the usage of a budget
+type launchCost struct {
+	gpus         int
+	pricePerHour float64
+	projected    float64 // Its cost until it expires or the month ends
+}
+
+// budgetState is a budget together with its usage
+type budgetState struct {
+	budget models.Budget
+	usage  types.BudgetUsage
+}
+
+// budgetPeriod returns the UTC month containing now as YYYY-MM, with its start and end
+func budgetPeriod(now time.Time) (string, time.Time, time.Time) {
+	year, month, _ := now.UTC().Date()
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01"), start, start.AddDate(0, 1, 0)
+}
+
+// projectedCost is what running at pricePerHour costs from now until the instance
+// expires or the month ends
+func projectedCost(pricePerHour float64, expiresAt *time.Time, now, monthEnd time.Time) float64 {
+	end := monthEnd
+	if expiresAt != nil && expiresAt.Before(end) {
+		end = *expiresAt
+	}
+	if !end.After(now) {
+		return 0
+	}
+	return pricePerHour * end.Sub(now).Hours()
+}
+
+// newLaunchCost prices an instance that is about to run
+func newLaunchCost(gpus int, pricePerHour float64, expiresAt *time.Time, now time.Time) launchCost {
+	_, _, monthEnd := budgetPeriod(now)
+	return launchCost{
+		gpus:         gpus,
+		pricePerHour: pricePerHour,
+		projected:    projectedCost(pricePerHour, expiresAt, now, monthEnd),
+	}
+}
+
+// budgetScope names what a budget applies to in messages
+func budgetScope(b models.Budget) string {
+	if b.TeamID != nil {
+		return "the team budget"
+	}
+	return "your budget"
+}
+
+// checkBudgetLaunch returns why running an instance costing cost would break a
+// budget with the given usage, or nil when it fits
+func checkBudgetLaunch(b models.Budget, usage types.BudgetUsage, cost launchCost) error {
+	scope := budgetScope(b)
+	if b.MaxGPUs > 0 && usage.GPUsInUse+cost.gpus > b.MaxGPUs {
+		return fmt.Errorf("%w: %s allows %d GPUs at once, %d are in use and the instance needs %d", ErrBudgetExceeded, scope, b.MaxGPUs, usage.GPUsInUse, cost.gpus)
+	}
+	if b.MaxHourlySpend > 0 && roundCents(usage.HourlySpend+cost.pricePerHour) > b.MaxHourlySpend {
+		return fmt.Errorf("%w: %s allows $%.2f/hr, running instances cost $%.2f/hr and the instance $%.2f/hr", ErrBudgetExceeded, scope, b.MaxHourlySpend, usage.HourlySpend, cost.pricePerHour)
+	}
+	if b.MonthlyLimit > 0 {
+		if usage.MonthToDate >= b.MonthlyLimit {
+			return fmt.Errorf("%w: %s of $%.2f for %s is spent", ErrBudgetExceeded, scope, b.MonthlyLimit, usage.Period)
+		}
+		if projected := usage.ProjectedSpend + cost.projected; roundCents(projected) > b.MonthlyLimit {
+			return fmt.Errorf("%w: spend for %s is projected to reach $%.2f, over %s of $%.2f; set an earlier expiry to launch it", ErrBudgetExceeded, usage.Period, projected, scope, b.MonthlyLimit)
+		}
+	}
+	return nil
+}
+
+// withinBudgets checks that running an instance costing cost breaks none of the budgets
+func withinBudgets(states []budgetState, cost launchCost) error {
+	for _, state := range states {
+		if err := checkBudgetLaunch(state.budget, state.usage, cost); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// affordableOffers drops offers whose instances would break a budget. When every offer
+// is dropped the reason the first one was is returned.
+func affordableOffers(offers []types.GPUInstance, states []budgetState, expiresAt *time.Time, now time.Time) ([]types.GPUInstance, error) {
+	kept := make([]types.GPUInstance, 0, len(offers))
+	var firstErr error
+	for _, offer := range offers {
+		err := withinBudgets(states, newLaunchCost(offer.GPUCount, offer.PricePerHour, expiresAt, now))
+		if err == nil {
+			kept = append(kept, offer)
+		} else if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if len(kept) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return kept, nil
+}
+
+// budgetLocks serializes launches against the same budgets within this process, so
+// concurrent launches queue here rather than each holding a transaction open while
+// they wait for the budget rows
+type budgetLocks struct {
+	mu    sync.Mutex
+	locks map[uint]*budgetLock
+}
+
+// budgetLock is held by whoever has put a token in held
+type budgetLock struct {
+	held chan struct{}
+	refs int // Launches holding or waiting for the lock
+}
+
+func newBudgetLocks() *budgetLocks {
+	return &budgetLocks{locks: make(map[uint]*budgetLock)}
+}
+
+// lock takes the locks of the budgets with the given IDs, and returns the func
+// releasing them. Locks are taken in ascending ID order so launches against
+// overlapping budgets can't deadlock. It gives up when ctx is done.
+func (l *budgetLocks) lock(ctx context.Context, ids []uint) (func(), error) {
+	sorted := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var held []*budgetLock
+	unlock := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			<-held[i].held
+			l.release(sorted[i], held[i])
+		}
+	}
+	for _, id := range sorted {
+		lock := l.acquire(id)
+		select {
+		case lock.held <- struct{}{}:
+			held = append(held, lock)
+		case <-ctx.Done():
+			l.release(id, lock)
+			unlock()
+			return nil, ctx.Err()
+		}
+	}
+	return unlock, nil
+}
+
+// acquire returns the lock of a budget, creating it for the first launch to want it
+func (l *budgetLocks) acquire(id uint) *budgetLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, ok := l.locks[id]
+	if !ok {
+		lock = &budgetLock{held: make(chan struct{}, 1)}
+		l.locks[id] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// release drops a launch's interest in a budget's lock, forgetting the lock once no
+// launch wants it
+func (l *budgetLocks) release(id uint, lock *budgetLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, id)
+	}
+}
+
+// launchWithinBudgets runs launch unless what it launches, priced by costOf at the
+// time of the check, would break one of the user's budgets. Launches against the same
+// budget run one at a time: within this process through s.budgetLocks, and across
+// processes by locking the budget rows in a transaction that launch gets as db, so
+// recording the instance through db makes the next launch see it. Without budgets
+// launch gets a plain session. Either way db outlives ctx, since the instance has to
+// be recorded once the provider has created it.
+func (s *GPUService) launchWithinBudgets(ctx context.Context, userID uint, costOf func(now time.Time) (launchCost, error), launch func(db *gorm.DB) error) error {
+	budgets, err := loadUserBudgets(s.db.WithContext(ctx), userID)
+	if err != nil {
+		return err
+	}
+	db := s.db.WithContext(context.WithoutCancel(ctx))
+	if len(budgets) == 0 {
+		return launch(db)
+	}
+
+	ids := make([]uint, 0, len(budgets))
+	for _, b := range budgets {
+		ids = append(ids, b.ID)
+	}
+	unlock, err := s.budgetLocks.lock(ctx, ids)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		budgets, err := loadUserBudgets(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+		if len(budgets) > 0 {
+			now := time.Now()
+			states, err := budgetStates(tx, budgets, now)
+			if err != nil {
+				return err
+			}
+			cost, err := costOf(now)
+			if err != nil {
+				return err
+			}
+			if err := withinBudgets(states, cost); err != nil {
+				return err
+			}
+		}
+		return launch(tx)
+	})
+}
+
+// userBudgets loads the budgets applying to the user, their own and their team's,
+// along with their usage
+func (s *GPUService) userBudgets(ctx context.Context, userID uint, now time.Time) ([]budgetState, error) {
+	db := s.db.WithContext(ctx)
+	budgets, err := loadUserBudgets(db, userID)
+	if err != nil {
+		return nil, err
+	}
+	return budgetStates(db, budgets, now)
+}
+
+// loadUserBudgets loads the budgets applying to the user in ID order
+func loadUserBudgets(db *gorm.DB, userID uint) ([]models.Budget, error) {
+	var user models.User
+	if err := db.Session(&gorm.Session{NewDB: true}).First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("error loading user: %v", err)
+	}
+
+	query := db.Where("user_id = ?", userID)
+	if user.TeamID != nil {
+		query = query.Or("team_id = ?", *user.TeamID)
+	}
+	var budgets []models.Budget
+	if err := query.Order("id").Find(&budgets).Error; err != nil {
+		return nil, fmt.Errorf("error loading budgets: %v", err)
+	}
+	return budgets, nil
+}
+
+// budgetStates measures the usage of each budget in the month containing now
+func budgetStates(db *gorm.DB, budgets []models.Budget, now time.Time) ([]budgetState, error) {
+	states := make([]budgetState, 0, len(budgets))
+	for _, b := range budgets {
+		usage, _, err := budgetUsage(db, b, now)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, budgetState{budget: b, usage: usage})
+	}
+	return states, nil
+}
+
+// budgetUsage measures a budget's usage in the month containing now, and returns the
+// running instances counting against it
+func budgetUsage(db *gorm.DB, b models.Budget, now time.Time) (types.BudgetUsage, []models.Instance, error) {
+	period, monthStart, monthEnd := budgetPeriod(now)
+	usage := types.BudgetUsage{Period: period}
+
+	members := []uint{}
+	if b.UserID != nil {
+		members = append(members, *b.UserID)
+	} else if b.TeamID != nil {
+		// Deleted members' instances still count against their team
+		if err := db.Unscoped().Model(&models.User{}).Where("team_id = ?", *b.TeamID).Pluck("id", &members).Error; err != nil {
+			return usage, nil, fmt.Errorf("error loading team members: %v", err)
+		}
+	}
+	if len(members) == 0 {
+		return usage, nil, nil
+	}
+
+	var segments []models.CostSegment
+	err := db.
+		Where("user_id IN ? AND state = ? AND started_at < ? AND (ended_at IS NULL OR ended_at > ?)", members, models.BillingRunning, now, monthStart).
+		Find(&segments).Error
+	if err != nil {
+		return usage, nil, fmt.Errorf("error loading cost segments: %v", err)
+	}
+	for _, segment := range segments {
+		usage.MonthToDate += segmentCost(segment, monthStart, now)
+	}
+
+	var running []models.Instance
+	err = db.Where("user_id IN ? AND status IN ?", members, runningBillingStatuses).Find(&running).Error
+	if err != nil {
+		return usage, nil, fmt.Errorf("error loading running instances: %v", err)
+	}
+
+	usage.ProjectedSpend = usage.MonthToDate
+	for _, row := range running {
+		usage.GPUsInUse += row.GPUCount
+		usage.HourlySpend += row.PricePerHour
+		usage.ProjectedSpend += projectedCost(row.PricePerHour, row.ExpiresAt, now, monthEnd)
+	}
+
+	usage.MonthToDate = roundCents(usage.MonthToDate)
+	usage.ProjectedSpend = roundCents(usage.ProjectedSpend)
+	usage.HourlySpend = roundCents(usage.HourlySpend)
+	return usage, running, nil
+}
+
+// offerLaunchCost prices an offer for budget checks, asking the provider for the offer
+// itself so the price is current. When the offer can't be priced the launch is refused
+// as unavailable, since whether it fits the budgets isn't known.
+func (s *GPUService) offerLaunchCost(ctx context.Context, p providers.Provider, offerID string, expiresAt *time.Time, now time.Time) (launchCost, error) {
+	upstreamCtx, cancel := s.upstreamContext(ctx)
+	defer cancel()
+
+	offer, err := p.GetOffer(upstreamCtx, offerID)
+	if err != nil {
+		if apperr.KindOf(err) == apperr.InvalidArgument {
+			return launchCost{}, err
+		}
+		return launchCost{}, apperr.Errorf(apperr.Unavailable, "can't price offer %s from %s to check it against your budget: %v", offerID, p.Capabilities().DisplayName, err)
+	}
+	return newLaunchCost(offer.GPUCount, offer.PricePerHour, expiresAt, now), nil
+}
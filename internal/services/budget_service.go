@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/notify"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBudgetNotFound is returned when a budget doesn't exist
+	ErrBudgetNotFound = apperr.New(apperr.NotFound, "budget not found")
+
+	// ErrTeamNotFound is returned when a team doesn't exist
+	ErrTeamNotFound = apperr.New(apperr.NotFound, "team not found")
+
+	// ErrUserNotFound is returned when a user doesn't exist
+	ErrUserNotFound = apperr.New(apperr.NotFound, "user not found")
+
+	// ErrInvalidBudget is returned for budgets that can't be applied
+	ErrInvalidBudget = apperr.New(apperr.InvalidArgument, "invalid budget")
+)
+
+// budgetThresholds are the shares of a monthly limit, in percent, notified when spend reaches them
+var budgetThresholds = []int{80, 100}
+
+// budgetThresholdEvent names the webhook event sent when spend reaches a threshold
+const budgetThresholdEvent = "budget_threshold"
+
+// BudgetService manages teams and budgets, notifies budget webhooks as monthly spend
+// reaches each threshold and stops instances over hard caps. Launches are checked
+// against budgets by GPUService.
+type BudgetService struct {
+	db       *gorm.DB
+	gpu      *GPUService
+	sender   *notify.Sender
+	interval time.Duration
+	metrics  *metrics.Metrics
+}
+
+// NewBudgetService creates a new budget service
+func NewBudgetService(db *gorm.DB, cfg *config.Config, gpu *GPUService, sender *notify.Sender, m *metrics.Metrics) *BudgetService {
+	return &BudgetService{
+		db:       db,
+		gpu:      gpu,
+		sender:   sender,
+		interval: cfg.BudgetCheckInterval,
+		metrics:  m,
+	}
+}
+
+// Teams returns every team
+func (s *BudgetService) Teams(ctx context.Context) ([]models.Team, error) {
+	var teams []models.Team
+	if err := s.db.WithContext(ctx).Order("name").Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("error loading teams: %v", err)
+	}
+	return teams, nil
+}
+
+// CreateTeam adds a team
+func (s *BudgetService) CreateTeam(ctx context.Context, req *types.TeamRequest) (*models.Team, error) {
+	team := models.Team{Name: req.Name}
+	if err := s.db.WithContext(ctx).Create(&team).Error; err != nil {
+		return nil, fmt.Errorf("error saving team: %v", err)
+	}
+	return &team, nil
+}
+
+// SetTeamMember moves a user into a team, out of any team they were in
+func (s *BudgetService) SetTeamMember(ctx context.Context, teamID, userID uint) error {
+	if _, err := s.team(ctx, teamID); err != nil {
+		return err
+	}
+
+	result := s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("team_id", teamID)
+	if result.Error != nil {
+		return fmt.Errorf("error updating user: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RemoveTeamMember takes a user out of a team
+func (s *BudgetService) RemoveTeamMember(ctx context.Context, teamID, userID uint) error {
+	result := s.db.WithContext(ctx).Model(&models.User{}).Where("id = ? AND team_id = ?", userID, teamID).Update("team_id", nil)
+	if result.Error != nil {
+		return fmt.Errorf("error updating user: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// team loads a team by ID
+func (s *BudgetService) team(ctx context.Context, id uint) (*models.Team, error) {
+	var team models.Team
+	if err := s.db.WithContext(ctx).First(&team, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("error loading team: %v", err)
+	}
+	return &team, nil
+}
+
+// List returns every budget with its usage this month
+func (s *BudgetService) List(ctx context.Context) ([]models.Budget, error) {
+	var budgets []models.Budget
+	if err := s.db.WithContext(ctx).Order("id").Find(&budgets).Error; err != nil {
+		return nil, fmt.Errorf("error loading budgets: %v", err)
+	}
+	return s.withUsage(ctx, budgets)
+}
+
+// ForUser returns the budgets applying to the user, their own and their team's, with
+// their usage this month
+func (s *BudgetService) ForUser(ctx context.Context, userID uint) ([]models.Budget, error) {
+	states, err := s.gpu.userBudgets(ctx, userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	budgets := make([]models.Budget, 0, len(states))
+	for _, state := range states {
+		usage := state.usage
+		state.budget.Usage = &usage
+		budgets = append(budgets, state.budget)
+	}
+	return budgets, nil
+}
+
+// Get returns a budget with its usage this month
+func (s *BudgetService) Get(ctx context.Context, id uint) (*models.Budget, error) {
+	var budget models.Budget
+	if err := s.db.WithContext(ctx).First(&budget, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBudgetNotFound
+		}
+		return nil, fmt.Errorf("error loading budget: %v", err)
+	}
+
+	budgets, err := s.withUsage(ctx, []models.Budget{budget})
+	if err != nil {
+		return nil, err
+	}
+	return &budgets[0], nil
+}
+
+// Create stores a budget for a user or a team with a freshly generated webhook secret
+func (s *BudgetService) Create(ctx context.Context, req *types.BudgetRequest) (*models.Budget, error) {
+	if err := s.validate(ctx, req); err != nil {
+		return nil, err
+	}
+	if req.TeamID != nil {
+		if _, err := s.team(ctx, *req.TeamID); err != nil {
+			return nil, err
+		}
+	} else {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", *req.UserID).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("error loading user: %v", err)
+		}
+		if count == 0 {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Budget{}).Where("user_id = ? OR team_id = ?", req.UserID, req.TeamID).Count(&count).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading budgets: %v", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("%w: a budget already exists for this user or team, update it instead", ErrInvalidBudget)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	budget := models.Budget{
+		UserID:        req.UserID,
+		TeamID:        req.TeamID,
+		WebhookSecret: secret,
+	}
+	applyBudgetRequest(&budget, req)
+
+	if err := s.db.WithContext(ctx).Create(&budget).Error; err != nil {
+		return nil, fmt.Errorf("error saving budget: %v", err)
+	}
+	return &budget, nil
+}
+
+// Update replaces a budget's limits and webhook, keeping who it applies to and its secret
+func (s *BudgetService) Update(ctx context.Context, id uint, req *types.BudgetRequest) (*models.Budget, error) {
+	budget, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.UserID == nil && req.TeamID == nil {
+		req.UserID, req.TeamID = budget.UserID, budget.TeamID
+	}
+	if err := s.validate(ctx, req); err != nil {
+		return nil, err
+	}
+	if !sameID(req.UserID, budget.UserID) || !sameID(req.TeamID, budget.TeamID) {
+		return nil, fmt.Errorf("%w: the user or team of a budget can't be changed", ErrInvalidBudget)
+	}
+	applyBudgetRequest(budget, req)
+
+	if err := s.db.WithContext(ctx).Save(budget).Error; err != nil {
+		return nil, fmt.Errorf("error updating budget: %v", err)
+	}
+	return budget, nil
+}
+
+// Delete removes a budget and its notification log
+func (s *BudgetService) Delete(ctx context.Context, id uint) error {
+	budget, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("budget_id = ?", budget.ID).Delete(&models.BudgetNotification{}).Error; err != nil {
+			return fmt.Errorf("error deleting budget notifications: %v", err)
+		}
+		if err := tx.Delete(budget).Error; err != nil {
+			return fmt.Errorf("error deleting budget: %v", err)
+		}
+		return nil
+	})
+}
+
+// Notifications returns the most recent threshold notifications of a budget
+func (s *BudgetService) Notifications(ctx context.Context, id uint, limit int) ([]models.BudgetNotification, error) {
+	budget, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []models.BudgetNotification
+	err = s.db.WithContext(ctx).Where("budget_id = ?", budget.ID).Order("created_at DESC").Limit(limit).Find(&notifications).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading budget notifications: %v", err)
+	}
+	return notifications, nil
+}
+
+// withUsage fills in the usage of each budget this month
+func (s *BudgetService) withUsage(ctx context.Context, budgets []models.Budget) ([]models.Budget, error) {
+	now := time.Now()
+	for i := range budgets {
+		usage, _, err := budgetUsage(s.db.WithContext(ctx), budgets[i], now)
+		if err != nil {
+			return nil, err
+		}
+		budgets[i].Usage = &usage
+	}
+	return budgets, nil
+}
+
+// Run checks budgets on every interval until ctx is cancelled
+func (s *BudgetService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		s.CheckOnce(ctx)
+		s.metrics.ObserveJob("budgets", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckOnce notifies every budget whose monthly spend reached a threshold it hasn't
+// been notified of this month, and stops the running instances of hard-capped budgets
+// that are used up
+func (s *BudgetService) CheckOnce(ctx context.Context) {
+	var budgets []models.Budget
+	if err := s.db.WithContext(ctx).Where("monthly_limit > 0").Find(&budgets).Error; err != nil {
+		slog.ErrorContext(ctx, "Budgets: error loading budgets", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range budgets {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.check(ctx, &budgets[i], now); err != nil {
+			slog.ErrorContext(ctx, "Budgets: error checking budget", "budget_id", budgets[i].ID, "error", err)
+		}
+	}
+}
+
+// check notifies a budget's newly reached thresholds and enforces its hard cap
+func (s *BudgetService) check(ctx context.Context, budget *models.Budget, now time.Time) error {
+	usage, running, err := budgetUsage(s.db.WithContext(ctx), *budget, now)
+	if err != nil {
+		return err
+	}
+
+	for _, threshold := range reachedThresholds(usage.MonthToDate, budget.MonthlyLimit) {
+		if err := s.notify(ctx, budget, usage, threshold); err != nil {
+			return err
+		}
+	}
+
+	if budget.HardCap && usage.MonthToDate >= budget.MonthlyLimit {
+		s.stopRunning(ctx, budget, usage, running, now)
+	}
+	return nil
+}
+
+// notify records that spend reached a threshold this month and sends the budget's
+// webhook, unless the threshold was already notified
+func (s *BudgetService) notify(ctx context.Context, budget *models.Budget, usage types.BudgetUsage, threshold int) error {
+	notification := models.BudgetNotification{
+		BudgetID:         budget.ID,
+		Period:           usage.Period,
+		ThresholdPercent: threshold,
+		Spend:            usage.MonthToDate,
+		MonthlyLimit:     budget.MonthlyLimit,
+	}
+	if budget.WebhookURL != "" {
+		notification.Status = models.DeliveryPending
+	}
+
+	// The unique index on budget, period and threshold keeps repeated checks from notifying twice
+	result := s.db.WithContext(ctx).
+		Where(models.BudgetNotification{BudgetID: budget.ID, Period: usage.Period, ThresholdPercent: threshold}).
+		FirstOrCreate(&notification)
+	if result.Error != nil {
+		return fmt.Errorf("error recording notification: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	slog.InfoContext(ctx, "Budgets: spend reached a threshold", "budget_id", budget.ID, "threshold_percent", threshold, "monthly_limit", budget.MonthlyLimit, "period", usage.Period)
+	if budget.WebhookURL != "" {
+		b, n := *budget, notification
+		s.sender.Go(func(ctx context.Context) { s.deliver(ctx, b, n) })
+	}
+	return nil
+}
+
+// deliver sends one threshold notification and records the outcome
+func (s *BudgetService) deliver(ctx context.Context, budget models.Budget, notification models.BudgetNotification) {
+	event := types.BudgetThresholdEvent{
+		Event:            budgetThresholdEvent,
+		BudgetID:         budget.ID,
+		UserID:           budget.UserID,
+		TeamID:           budget.TeamID,
+		Period:           notification.Period,
+		ThresholdPercent: notification.ThresholdPercent,
+		MonthlyLimit:     notification.MonthlyLimit,
+		Spend:            notification.Spend,
+		HardCap:          budget.HardCap,
+		TriggeredAt:      notification.CreatedAt,
+	}
+
+	result, err := s.sender.Send(ctx, budget.WebhookURL, budget.WebhookSecret, budgetThresholdEvent, event)
+
+	updates := map[string]interface{}{
+		"status":        models.DeliveryDelivered,
+		"attempts":      result.Attempts,
+		"response_code": result.StatusCode,
+		"delivered_at":  time.Now(),
+	}
+	if err != nil {
+		updates["status"] = models.DeliveryFailed
+		updates["error"] = err.Error()
+		updates["delivered_at"] = nil
+	}
+
+	if err := s.db.Model(&notification).Updates(updates).Error; err != nil {
+		slog.Error("Budgets: error updating notification", "budget_id", budget.ID, "notification_id", notification.ID, "error", err)
+	}
+}
+
+// stopRunning stops the running instances counting against a used-up budget. Instances
+// of providers that can only destroy them are left running, as destroying them would
+// lose their data.
+func (s *BudgetService) stopRunning(ctx context.Context, budget *models.Budget, usage types.BudgetUsage, running []models.Instance, now time.Time) {
+	for i := range running {
+		row := &running[i]
+		if ctx.Err() != nil {
+			return
+		}
+		if row.Status == types.StatusStopping {
+			continue
+		}
+		if descriptor, known := providers.Lookup(row.Provider); known && !descriptor.Capabilities.SupportsStartStop {
+			slog.WarnContext(ctx, "Budgets: instance can't be stopped, leaving it running", "budget_id", budget.ID, "instance_id", row.ToGPUInstance().ID)
+			continue
+		}
+
+		due, err := automatedRetryDue(s.db.WithContext(ctx), row.ID, auditActionStop, now)
+		if err != nil || !due {
+			if err != nil {
+				slog.ErrorContext(ctx, "Budgets: error checking for a recent failed stop", "budget_id", budget.ID, "instance_id", row.ToGPUInstance().ID, "error", err)
+			}
+			continue
+		}
+
+		a := automatedAction{
+			actor:     transitionSourceBudget,
+			action:    auditActionStop,
+			eventType: models.EventBudgetStop,
+			reason:    fmt.Sprintf("Stopped: the monthly budget of $%.2f for %s is used up", budget.MonthlyLimit, usage.Period),
+			details:   models.JSONMap{"budget_id": budget.ID, "spend": usage.MonthToDate, "monthly_limit": budget.MonthlyLimit},
+		}
+		slog.InfoContext(ctx, "Budgets: "+a.reason, "budget_id", budget.ID, "instance_id", row.ToGPUInstance().ID)
+		if err := s.gpu.runAutomated(ctx, row, a); err != nil {
+			slog.ErrorContext(ctx, "Budgets: error stopping instance", "budget_id", budget.ID, "instance_id", row.ToGPUInstance().ID, "error", err)
+		}
+	}
+}
+
+// reachedThresholds returns the thresholds spend has reached
+func reachedThresholds(spend, monthlyLimit float64) []int {
+	var reached []int
+	if monthlyLimit <= 0 {
+		return reached
+	}
+	for _, threshold := range budgetThresholds {
+		if spend >= monthlyLimit*float64(threshold)/100 {
+			reached = append(reached, threshold)
+		}
+	}
+	return reached
+}
+
+// validate rejects budgets that can't be applied or whose webhook points at an
+// internal address
+func (s *BudgetService) validate(ctx context.Context, req *types.BudgetRequest) error {
+	if err := validateBudget(req); err != nil {
+		return err
+	}
+	if req.WebhookURL != "" {
+		if err := s.sender.CheckURL(ctx, req.WebhookURL); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidBudget, err)
+		}
+	}
+	return nil
+}
+
+// validateBudget rejects budgets without exactly one owner or with an unusable webhook
+func validateBudget(req *types.BudgetRequest) error {
+	if (req.UserID == nil) == (req.TeamID == nil) {
+		return fmt.Errorf("%w: set exactly one of user_id and team_id", ErrInvalidBudget)
+	}
+	if req.HardCap && req.MonthlyLimit <= 0 {
+		return fmt.Errorf("%w: hard_cap needs a monthly_limit", ErrInvalidBudget)
+	}
+	if req.WebhookURL != "" {
+		webhook, err := url.Parse(req.WebhookURL)
+		if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			return fmt.Errorf("%w: webhook_url must be an absolute http or https URL", ErrInvalidBudget)
+		}
+	}
+	return nil
+}
+
+// applyBudgetRequest copies the request's limits and webhook onto a budget
+func applyBudgetRequest(budget *models.Budget, req *types.BudgetRequest) {
+	budget.MonthlyLimit = req.MonthlyLimit
+	budget.MaxGPUs = req.MaxGPUs
+	budget.MaxHourlySpend = req.MaxHourlySpend
+	budget.HardCap = req.HardCap
+	budget.WebhookURL = req.WebhookURL
+}
+
+// sameID reports whether two optional IDs are equal
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestBudgetPeriod(t *testing.T) {
+	period, start, end := budgetPeriod(time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC))
+	if period != "2024-12" || !start.Equal(time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected period %s from %v to %v", period, start, end)
+	}
+}
+
+func TestProjectedCost(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	monthEnd := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	if cost := projectedCost(2, nil, now, monthEnd); cost != 24 {
+		t.Errorf("expected 12 hours until the month ends at $2/hr, got %v", cost)
+	}
+
+	expiresAt := now.Add(3 * time.Hour)
+	if cost := projectedCost(2, &expiresAt, now, monthEnd); cost != 6 {
+		t.Errorf("expected the cost to stop at the expiry, got %v", cost)
+	}
+
+	expired := now.Add(-time.Hour)
+	if cost := projectedCost(2, &expired, now, monthEnd); cost != 0 {
+		t.Errorf("expected nothing for an expired instance, got %v", cost)
+	}
+}
+
+func TestCheckBudgetLaunch(t *testing.T) {
+	budget := models.Budget{MonthlyLimit: 1000, MaxGPUs: 8, MaxHourlySpend: 20}
+	usage := types.BudgetUsage{Period: "2024-03", MonthToDate: 400, ProjectedSpend: 700, GPUsInUse: 6, HourlySpend: 15}
+
+	if err := checkBudgetLaunch(budget, usage, launchCost{gpus: 2, pricePerHour: 5, projected: 300}); err != nil {
+		t.Errorf("expected a launch exactly at every limit to fit, got %v", err)
+	}
+
+	over := []launchCost{
+		{gpus: 3, pricePerHour: 1},
+		{gpus: 1, pricePerHour: 5.5},
+		{gpus: 1, pricePerHour: 1, projected: 301},
+	}
+	for _, cost := range over {
+		if err := checkBudgetLaunch(budget, usage, cost); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("expected %+v to be rejected, got %v", cost, err)
+		}
+	}
+
+	spent := usage
+	spent.MonthToDate = 1000
+	if err := checkBudgetLaunch(budget, spent, launchCost{}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected a used-up budget to reject any launch, got %v", err)
+	}
+
+	if err := checkBudgetLaunch(models.Budget{}, spent, launchCost{gpus: 100, pricePerHour: 100, projected: 1e6}); err != nil {
+		t.Errorf("expected limits left at 0 not to apply, got %v", err)
+	}
+}
+
+func TestAffordableOffers(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	states := []budgetState{{budget: models.Budget{MaxGPUs: 4}, usage: types.BudgetUsage{GPUsInUse: 2}}}
+	offers := []types.GPUInstance{
+		{ProviderID: "8x", GPUCount: 8},
+		{ProviderID: "2x", GPUCount: 2},
+	}
+
+	kept, err := affordableOffers(offers, states, nil, now)
+	if err != nil || len(kept) != 1 || kept[0].ProviderID != "2x" {
+		t.Errorf("expected only the 2 GPU offer to be kept, got %v %v", kept, err)
+	}
+
+	if _, err := affordableOffers(offers[:1], states, nil, now); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected the budget error when no offer fits, got %v", err)
+	}
+
+	if kept, err := affordableOffers(nil, states, nil, now); err != nil || len(kept) != 0 {
+		t.Errorf("expected no offers and no error, got %v %v", kept, err)
+	}
+}
+
+func TestBudgetLocksSerializeLaunches(t *testing.T) {
+	locks := newBudgetLocks()
+	budget := models.Budget{ID: 1, MaxGPUs: 1}
+
+	var mu sync.Mutex
+	var usage types.BudgetUsage
+	launch := func() error {
+		unlock, err := locks.lock(context.Background(), []uint{budget.ID})
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		mu.Lock()
+		current := usage
+		mu.Unlock()
+		if err := checkBudgetLaunch(budget, current, launchCost{gpus: 1}); err != nil {
+			return err
+		}
+
+		// Renting the instance takes a while, and it counts once recorded
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		usage.GPUsInUse++
+		mu.Unlock()
+		return nil
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- launch() }()
+	}
+	exceeded := 0
+	for i := 0; i < 2; i++ {
+		if err := <-errs; errors.Is(err, ErrBudgetExceeded) {
+			exceeded++
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if exceeded != 1 || usage.GPUsInUse != 1 {
+		t.Errorf("expected one launch to fit the 1 GPU budget and the other to be refused, got %d GPUs in use and %d refused", usage.GPUsInUse, exceeded)
+	}
+	if len(locks.locks) != 0 {
+		t.Errorf("expected released locks to be forgotten, %d left", len(locks.locks))
+	}
+}
+
+func TestBudgetLocksOverlappingBudgets(t *testing.T) {
+	locks := newBudgetLocks()
+
+	// Launches naming the same budgets in any order must not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		ids := []uint{1, 2, 2}
+		if i%2 == 0 {
+			ids = []uint{2, 1}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := locks.lock(context.Background(), ids)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			unlock()
+		}()
+	}
+	wg.Wait()
+
+	unlock, err := locks.lock(context.Background(), []uint{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := locks.lock(ctx, []uint{2, 1}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected waiting for a held budget to give up with the context, got %v", err)
+	}
+	unlock()
+
+	if len(locks.locks) != 0 {
+		t.Errorf("expected released locks to be forgotten, %d left", len(locks.locks))
+	}
+}
+
+func TestReachedThresholds(t *testing.T) {
+	tests := []struct {
+		spend    float64
+		expected int
+	}{
+		{79.99, 0},
+		{80, 1},
+		{99, 1},
+		{100, 2},
+		{150, 2},
+	}
+	for _, tt := range tests {
+		if got := reachedThresholds(tt.spend, 100); len(got) != tt.expected {
+			t.Errorf("spend %v: expected %d thresholds reached, got %v", tt.spend, tt.expected, got)
+		}
+	}
+
+	if got := reachedThresholds(50, 0); len(got) != 0 {
+		t.Errorf("expected no thresholds without a monthly limit, got %v", got)
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	userID, teamID := uint(1), uint(2)
+
+	if err := validateBudget(&types.BudgetRequest{UserID: &userID, MonthlyLimit: 100, HardCap: true, WebhookURL: "https://example.com/hook"}); err != nil {
+		t.Errorf("expected a valid budget, got %v", err)
+	}
+
+	invalid := []types.BudgetRequest{
+		{MonthlyLimit: 100},
+		{UserID: &userID, TeamID: &teamID},
+		{TeamID: &teamID, HardCap: true},
+		{UserID: &userID, WebhookURL: "example.com/hook"},
+	}
+	for _, req := range invalid {
+		if err := validateBudget(&req); !errors.Is(err, ErrInvalidBudget) {
+			t.Errorf("expected %+v to be rejected, got %v", req, err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCredentialNotFound is returned when credentials don't exist or belong to another user
+	ErrCredentialNotFound = apperr.New(apperr.NotFound, "provider credentials not found")
+
+	// ErrCredentialExists is returned when the user already has credentials for a provider
+	ErrCredentialExists = apperr.New(apperr.Conflict, "credentials for this provider already exist")
+)
+
+// CredentialService manages users' provider API keys
+type CredentialService struct {
+	db   *gorm.DB
+	pool *ProviderPool
+}
+
+// NewCredentialService creates a new credential service
+func NewCredentialService(db *gorm.DB, pool *ProviderPool) *CredentialService {
+	return &CredentialService{
+		db:   db,
+		pool: pool,
+	}
+}
+
+// List returns the user's provider credentials
+func (s *CredentialService) List(ctx context.Context, userID uint) ([]models.UserProvider, error) {
+	var credentials []models.UserProvider
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("provider").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("error loading provider credentials: %v", err)
+	}
+	return credentials, nil
+}
+
+// Create stores credentials for a provider the user has none for yet
+func (s *CredentialService) Create(ctx context.Context, userID uint, req *types.ProviderCredentialRequest) (*models.UserProvider, error) {
+	if _, known := providers.Lookup(req.Provider); !known {
+		return nil, apperr.Errorf(apperr.InvalidArgument, "unsupported provider: %s", req.Provider)
+	}
+
+	credential := models.UserProvider{
+		UserID:     userID,
+		Provider:   string(req.Provider),
+		APIKey:     req.APIKey,
+		APIKeyHint: models.KeyHint(req.APIKey),
+		IsEnabled:  true,
+		Config:     models.JSONMap(req.Config),
+	}
+	if req.IsEnabled != nil {
+		credential.IsEnabled = *req.IsEnabled
+	}
+
+	// Select all fields so an explicit is_enabled=false isn't replaced by the column default.
+	// The unique index on user and provider rejects a second set of credentials, even
+	// one created concurrently.
+	if err := s.db.WithContext(ctx).Select("*").Create(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrCredentialExists
+		}
+		return nil, fmt.Errorf("error saving provider credentials: %v", err)
+	}
+
+	s.pool.Invalidate(userID)
+	return &credential, nil
+}
+
+// Update rotates the key, toggles or reconfigures the user's credentials
+func (s *CredentialService) Update(ctx context.Context, userID, id uint, req *types.UpdateProviderCredentialRequest) (*models.UserProvider, error) {
+	credential, err := s.get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.APIKey != "" {
+		credential.APIKey = req.APIKey
+		credential.APIKeyHint = models.KeyHint(req.APIKey)
+	}
+	if req.IsEnabled != nil {
+		credential.IsEnabled = *req.IsEnabled
+	}
+	if req.Config != nil {
+		credential.Config = models.JSONMap(req.Config)
+	}
+
+	// Save the whole row so the key goes through the encrypting serializer
+	if err := s.db.WithContext(ctx).Save(credential).Error; err != nil {
+		return nil, fmt.Errorf("error updating provider credentials: %v", err)
+	}
+	s.pool.Invalidate(userID)
+
+	return credential, nil
+}
+
+// Delete removes the user's credentials
+func (s *CredentialService) Delete(ctx context.Context, userID, id uint) error {
+	credential, err := s.get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Delete(credential).Error; err != nil {
+		return fmt.Errorf("error deleting provider credentials: %v", err)
+	}
+
+	s.pool.Invalidate(userID)
+	return nil
+}
+
+// Validate checks the credentials by making an authenticated call to the provider.
+// Only a rejected API key makes them invalid; when the provider can't be asked, for
+// example because it is down, the error is returned instead of a verdict.
+func (s *CredentialService) Validate(ctx context.Context, userID, id uint) (*types.CredentialValidation, error) {
+	credential, err := s.get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, known := providers.Lookup(types.GPUProvider(credential.Provider))
+	if !known {
+		return nil, apperr.Errorf(apperr.InvalidArgument, "unsupported provider: %s", credential.Provider)
+	}
+
+	result := &types.CredentialValidation{
+		Provider: descriptor.Name,
+		Valid:    true,
+	}
+	upstreamCtx, cancel := context.WithTimeout(ctx, s.pool.config.UpstreamTimeout)
+	defer cancel()
+
+	if _, err := s.pool.newProvider(descriptor, credential.APIKey).ListInstances(upstreamCtx); err != nil {
+		if apperr.KindOf(err) != apperr.ProviderUnauthorized {
+			return nil, fmt.Errorf("error checking %s credentials: %w", descriptor.Capabilities.DisplayName, err)
+		}
+		result.Valid = false
+		result.Error = err.Error()
+	}
+	result.CheckedAt = time.Now()
+
+	return result, nil
+}
+
+// get loads credentials owned by the user
+func (s *CredentialService) get(ctx context.Context, userID, id uint) (*models.UserProvider, error) {
+	var credential models.UserProvider
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&credential).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, fmt.Errorf("error loading provider credentials: %v", err)
+	}
+	return &credential, nil
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidExpiry is returned when a requested instance expiry is rejected
+var ErrInvalidExpiry = apperr.New(apperr.InvalidArgument, "invalid expiry")
+
+// UpdateExpiry changes when one of the user's instances expires, or what happens then
+func (s *GPUService) UpdateExpiry(ctx context.Context, userID uint, instanceID string, req *types.ExpiryRequest) (*types.GPUInstance, error) {
+	row, err := s.ownedRow(ctx, userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if row.Status == types.StatusTerminated {
+		return nil, fmt.Errorf("%w: the instance is terminated", ErrInvalidExpiry)
+	}
+
+	expiresAt, action, err := updatedExpiry(row.ExpiresAt, row.ExpiryAction, req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil {
+		if err := checkExpiryAction(row.Provider, action); err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{
+		"expires_at":    expiresAt,
+		"expiry_action": action,
+	}
+	if err := s.db.WithContext(ctx).Model(row).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("error saving expiry: %v", err)
+	}
+	row.ExpiresAt = expiresAt
+	row.ExpiryAction = action
+
+	instance := row.ToGPUInstance()
+	return &instance, nil
+}
+
+// launchExpiry resolves the expiry requested when launching an instance, returning
+// a nil time when the instance doesn't expire
+func launchExpiry(expiresAt *time.Time, maxRuntime string, action types.ExpiryAction, now time.Time) (*time.Time, types.ExpiryAction, error) {
+	if action == "" {
+		action = types.ExpiryStop
+	}
+	if action != types.ExpiryStop && action != types.ExpiryDestroy {
+		return nil, "", fmt.Errorf("%w: expiry_action must be stop or destroy", ErrInvalidExpiry)
+	}
+
+	switch {
+	case expiresAt != nil && maxRuntime != "":
+		return nil, "", fmt.Errorf("%w: set expires_at or max_runtime, not both", ErrInvalidExpiry)
+	case maxRuntime != "":
+		runtime, err := parseDuration(maxRuntime)
+		if err != nil || runtime <= 0 {
+			return nil, "", fmt.Errorf("%w: invalid max_runtime %q", ErrInvalidExpiry, maxRuntime)
+		}
+		at := now.Add(runtime)
+		return &at, action, nil
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+		}
+		return expiresAt, action, nil
+	}
+	return nil, action, nil
+}
+
+// updatedExpiry applies an expiry change to an instance's current expiry
+func updatedExpiry(current *time.Time, currentAction types.ExpiryAction, req *types.ExpiryRequest, now time.Time) (*time.Time, types.ExpiryAction, error) {
+	action := currentAction
+	if req.ExpiryAction != "" {
+		action = req.ExpiryAction
+	}
+	if action == "" {
+		action = types.ExpiryStop
+	}
+	if action != types.ExpiryStop && action != types.ExpiryDestroy {
+		return nil, "", fmt.Errorf("%w: expiry_action must be stop or destroy", ErrInvalidExpiry)
+	}
+
+	changes := 0
+	for _, set := range []bool{req.ExpiresAt != nil, req.ExtendBy != "", req.Clear} {
+		if set {
+			changes++
+		}
+	}
+	if changes > 1 {
+		return nil, "", fmt.Errorf("%w: set only one of expires_at, extend_by and clear", ErrInvalidExpiry)
+	}
+
+	switch {
+	case req.Clear:
+		return nil, action, nil
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+		}
+		return req.ExpiresAt, action, nil
+	case req.ExtendBy != "":
+		extension, err := parseDuration(req.ExtendBy)
+		if err != nil || extension <= 0 {
+			return nil, "", fmt.Errorf("%w: invalid extend_by %q", ErrInvalidExpiry, req.ExtendBy)
+		}
+		base := now
+		if current != nil && current.After(now) {
+			base = *current
+		}
+		at := base.Add(extension)
+		return &at, action, nil
+	case req.ExpiryAction != "":
+		return current, action, nil
+	}
+	return nil, "", fmt.Errorf("%w: set expires_at, extend_by, expiry_action or clear", ErrInvalidExpiry)
+}
+
+// checkExpiryAction rejects stopping instances of providers that can only destroy them
+func checkExpiryAction(provider types.GPUProvider, action types.ExpiryAction) error {
+	if action != types.ExpiryStop {
+		return nil
+	}
+	descriptor, known := providers.Lookup(provider)
+	if known && !descriptor.Capabilities.SupportsStartStop {
+		return fmt.Errorf("%w: %s instances cannot be stopped, use expiry_action destroy", ErrInvalidExpiry, descriptor.Capabilities.DisplayName)
+	}
+	return nil
+}
+
+// ExpiryScheduler stops or destroys instances once they expire. Expiry times are
+// stored with the instances, so instances that expired while the server was down are
+// handled on the first pass after it starts.
+type ExpiryScheduler struct {
+	db       *gorm.DB
+	gpu      *GPUService
+	interval time.Duration
+	metrics  *metrics.Metrics
+}
+
+// NewExpiryScheduler creates a new expiry scheduler
+func NewExpiryScheduler(db *gorm.DB, cfg *config.Config, gpu *GPUService, m *metrics.Metrics) *ExpiryScheduler {
+	return &ExpiryScheduler{
+		db:       db,
+		gpu:      gpu,
+		interval: cfg.ExpiryCheckInterval,
+		metrics:  m,
+	}
+}
+
+// Run expires instances on every interval until ctx is cancelled
+func (s *ExpiryScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		s.RunOnce(ctx)
+		s.metrics.ObserveJob("expiry", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce stops or destroys every instance whose expiry has passed
+func (s *ExpiryScheduler) RunOnce(ctx context.Context) {
+	now := time.Now()
+
+	var rows []models.Instance
+	err := s.db.WithContext(ctx).Where("expires_at <= ? AND status <> ?", now, types.StatusTerminated).Order("expires_at").Find(&rows).Error
+	if err != nil {
+		slog.ErrorContext(ctx, "Expiry scheduler: error loading expired instances", "error", err)
+		return
+	}
+
+	for i := range rows {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.expire(ctx, &rows[i], now); err != nil {
+			slog.ErrorContext(ctx, "Expiry scheduler: error expiring instance", "instance_id", rows[i].ToGPUInstance().ID, "error", err)
+		}
+	}
+}
+
+// expire runs an expired instance's expiry action, then clears its expiry
+func (s *ExpiryScheduler) expire(ctx context.Context, row *models.Instance, now time.Time) error {
+	a := automatedAction{
+		actor:     transitionSourceExpiry,
+		action:    auditActionStop,
+		eventType: models.EventExpiryStop,
+		reason:    fmt.Sprintf("Stopped on expiry at %s", formatEventTime(*row.ExpiresAt)),
+		details:   models.JSONMap{"expires_at": *row.ExpiresAt},
+	}
+	if row.ExpiryAction == types.ExpiryDestroy {
+		a.action = auditActionDestroy
+		a.eventType = models.EventExpiryDestroy
+		a.reason = fmt.Sprintf("Destroyed on expiry at %s", formatEventTime(*row.ExpiresAt))
+	}
+
+	stopped := row.Status == types.StatusOffline || row.Status == types.StatusStopping
+	if a.action == auditActionDestroy || !stopped {
+		due, err := automatedRetryDue(s.db.WithContext(ctx), row.ID, a.action, now)
+		if err != nil || !due {
+			return err
+		}
+
+		slog.InfoContext(ctx, "Expiry scheduler: "+a.reason, "instance_id", row.ToGPUInstance().ID)
+		if err := s.gpu.runAutomated(ctx, row, a); err != nil {
+			return err
+		}
+		if a.action == auditActionDestroy {
+			return nil
+		}
+	}
+
+	// A stopped instance keeps its record, so clear the expiry to act on it only once
+	err := s.db.WithContext(context.WithoutCancel(ctx)).Model(row).Update("expires_at", nil).Error
+	if err != nil {
+		return fmt.Errorf("error clearing expiry: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestLaunchExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	monday := now.Add(60 * time.Hour)
+
+	expiresAt, action, err := launchExpiry(nil, "8h", "", now)
+	if err != nil || expiresAt == nil || !expiresAt.Equal(now.Add(8*time.Hour)) || action != types.ExpiryStop {
+		t.Errorf("expected a stop 8h from now, got %v %s %v", expiresAt, action, err)
+	}
+
+	expiresAt, _, err = launchExpiry(nil, "2d", types.ExpiryDestroy, now)
+	if err != nil || !expiresAt.Equal(now.Add(48*time.Hour)) {
+		t.Errorf("expected max_runtime in days to parse, got %v %v", expiresAt, err)
+	}
+
+	expiresAt, action, err = launchExpiry(&monday, "", types.ExpiryDestroy, now)
+	if err != nil || !expiresAt.Equal(monday) || action != types.ExpiryDestroy {
+		t.Errorf("expected a destroy on monday, got %v %s %v", expiresAt, action, err)
+	}
+
+	if expiresAt, _, err := launchExpiry(nil, "", "", now); err != nil || expiresAt != nil {
+		t.Errorf("expected no expiry, got %v %v", expiresAt, err)
+	}
+
+	past := now.Add(-time.Minute)
+	invalid := []struct {
+		expiresAt  *time.Time
+		maxRuntime string
+		action     types.ExpiryAction
+	}{
+		{&monday, "8h", ""},
+		{&past, "", ""},
+		{nil, "soon", ""},
+		{nil, "-1h", ""},
+		{nil, "8h", "hibernate"},
+	}
+	for _, tc := range invalid {
+		if _, _, err := launchExpiry(tc.expiresAt, tc.maxRuntime, tc.action, now); !errors.Is(err, ErrInvalidExpiry) {
+			t.Errorf("expected %+v to be rejected, got %v", tc, err)
+		}
+	}
+}
+
+func TestUpdatedExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+	current := now.Add(time.Hour)
+
+	expiresAt, action, err := updatedExpiry(&current, types.ExpiryDestroy, &types.ExpiryRequest{ExtendBy: "4h"}, now)
+	if err != nil || !expiresAt.Equal(current.Add(4*time.Hour)) || action != types.ExpiryDestroy {
+		t.Errorf("expected the current expiry extended by 4h, got %v %s %v", expiresAt, action, err)
+	}
+
+	// An instance without a future expiry is extended from now
+	past := now.Add(-time.Hour)
+	expiresAt, action, err = updatedExpiry(&past, "", &types.ExpiryRequest{ExtendBy: "1d"}, now)
+	if err != nil || !expiresAt.Equal(now.Add(24*time.Hour)) || action != types.ExpiryStop {
+		t.Errorf("expected a stop a day from now, got %v %s %v", expiresAt, action, err)
+	}
+
+	expiresAt, action, err = updatedExpiry(&current, types.ExpiryStop, &types.ExpiryRequest{ExpiryAction: types.ExpiryDestroy}, now)
+	if err != nil || !expiresAt.Equal(current) || action != types.ExpiryDestroy {
+		t.Errorf("expected only the action to change, got %v %s %v", expiresAt, action, err)
+	}
+
+	if expiresAt, _, err := updatedExpiry(&current, types.ExpiryStop, &types.ExpiryRequest{Clear: true}, now); err != nil || expiresAt != nil {
+		t.Errorf("expected the expiry to be cleared, got %v %v", expiresAt, err)
+	}
+
+	invalid := []types.ExpiryRequest{
+		{},
+		{ExpiresAt: &past},
+		{ExpiresAt: &current, ExtendBy: "1h"},
+		{ExtendBy: "1h", Clear: true},
+		{ExtendBy: "0s"},
+		{ExpiryAction: "hibernate"},
+	}
+	for _, req := range invalid {
+		if _, _, err := updatedExpiry(&current, types.ExpiryStop, &req, now); !errors.Is(err, ErrInvalidExpiry) {
+			t.Errorf("expected %+v to be rejected, got %v", req, err)
+		}
+	}
+}
+
+func TestExpirableOffers(t *testing.T) {
+	offers := []types.GPUInstance{
+		{Provider: types.LambdaLabs, ProviderID: "gpu_1x_a100:us-west-1"},
+		{Provider: types.VastAI, ProviderID: "1"},
+	}
+
+	if kept := expirableOffers(offers, types.ExpiryStop); len(kept) != 1 || kept[0].Provider != types.VastAI {
+		t.Errorf("expected instances that can't be stopped to be dropped, got %v", kept)
+	}
+	if kept := expirableOffers(offers, types.ExpiryDestroy); len(kept) != 2 {
+		t.Errorf("expected every instance to be destroyable, got %v", kept)
+	}
+}
@@ -1,49 +1,68 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
-	"strconv"
 	"strings"
+	"time"
 
 	"gpu-cloud-manager/internal/config"
-	"gpu-cloud-manager/pkg/runpod"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
 	"gpu-cloud-manager/pkg/types"
-	"gpu-cloud-manager/pkg/vastai"
 	"gorm.io/gorm"
 )
 
+// ErrInstanceNotFound is returned when an instance doesn't exist or belongs to another user
+var ErrInstanceNotFound = apperr.New(apperr.NotFound, "instance not found")
+
 // GPUService handles all GPU-related business logic
 type GPUService struct {
-	db           *gorm.DB
-	config       *config.Config
-	vastClient   *vastai.Client
-	runpodClient *runpod.Client
+	db          *gorm.DB
+	config      *config.Config
+	pool        *ProviderPool
+	offers      *OfferCache
+	budgetLocks *budgetLocks
 }
 
 // NewGPUService creates a new GPU service
-func NewGPUService(db *gorm.DB, cfg *config.Config) *GPUService {
-	var vastClient *vastai.Client
-	var runpodClient *runpod.Client
+func NewGPUService(db *gorm.DB, cfg *config.Config, pool *ProviderPool, m *metrics.Metrics) *GPUService {
+	return &GPUService{
+		db:     db,
+		config: cfg,
+		pool:   pool,
+		offers: NewOfferCache(cfg.OfferCacheTTL, cfg.OfferCacheStale, cfg.UpstreamTimeout, m),
 
-	if cfg.VastAIAPIKey != "" {
-		vastClient = vastai.NewClient(cfg.VastAIAPIKey)
+		budgetLocks: newBudgetLocks(),
 	}
+}
 
-	if cfg.RunPodAPIKey != "" {
-		runpodClient = runpod.NewClient(cfg.RunPodAPIKey)
-	}
+// OfferCache returns the cache offer searches are read through
+func (s *GPUService) OfferCache() *OfferCache {
+	return s.offers
+}
 
-	return &GPUService{
-		db:           db,
-		config:       cfg,
-		vastClient:   vastClient,
-		runpodClient: runpodClient,
+// globalProviderKeys returns the provider API keys configured through the environment
+func globalProviderKeys(cfg *config.Config) map[types.GPUProvider]string {
+	return map[types.GPUProvider]string{
+		types.VastAI:     cfg.VastAIAPIKey,
+		types.RunPod:     cfg.RunPodAPIKey,
+		types.LambdaLabs: cfg.LambdaAPIKey,
+		types.Paperspace: cfg.PaperspaceKey,
 	}
 }
 
+// upstreamContext bounds calls to providers by the configured upstream timeout
+func (s *GPUService) upstreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.config.UpstreamTimeout)
+}
+
 // SearchOffers searches for available GPU offers across providers with advanced filtering
-func (s *GPUService) SearchOffers(filter *types.SearchFilter) ([]types.GPUInstance, error) {
+func (s *GPUService) SearchOffers(ctx context.Context, userID uint, filter *types.SearchFilter) ([]types.GPUInstance, []types.ProviderSearchStatus, error) {
 	// Convert basic filter to advanced filter for backward compatibility
 	advancedFilter := &types.AdvancedSearchFilter{
 		Provider:    filter.Provider,
@@ -56,29 +75,33 @@ func (s *GPUService) SearchOffers(filter *types.SearchFilter) ([]types.GPUInstan
 		SortOrder:   "asc",
 	}
 
-	return s.SearchOffersAdvanced(advancedFilter)
+	return s.SearchOffersAdvanced(ctx, userID, advancedFilter)
 }
 
-// SearchOffersAdvanced searches for available GPU offers with advanced filtering
-func (s *GPUService) SearchOffersAdvanced(filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
-	var allOffers []types.GPUInstance
+// SearchOffersAdvanced searches for available GPU offers with advanced filtering.
+// Providers are queried in parallel; offers from those that answered are returned
+// together with the status of every provider queried.
+func (s *GPUService) SearchOffersAdvanced(ctx context.Context, userID uint, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, []types.ProviderSearchStatus, error) {
+	return s.searchOffers(ctx, userID, filter, s.offers)
+}
 
-	// Search Vast.ai offers
-	if s.vastClient != nil && (filter.Provider == "" || filter.Provider == types.VastAI) {
-		vastOffers, err := s.searchVastAI(filter)
-		if err != nil {
-			return nil, fmt.Errorf("error searching Vast.ai offers: %v", err)
-		}
-		allOffers = append(allOffers, vastOffers...)
+// searchOffers searches the user's providers, reading through cache when one is given
+func (s *GPUService) searchOffers(ctx context.Context, userID uint, filter *types.AdvancedSearchFilter, cache *OfferCache) ([]types.GPUInstance, []types.ProviderSearchStatus, error) {
+	registry, err := s.pool.ForUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Search RunPod offers
-	if s.runpodClient != nil && (filter.Provider == "" || filter.Provider == types.RunPod) {
-		runpodOffers, err := s.searchRunPod(filter)
-		if err != nil {
-			return nil, fmt.Errorf("error searching RunPod offers: %v", err)
+	var targets []providers.Provider
+	for _, p := range registry.All() {
+		if filter.Provider == "" || filter.Provider == p.Name() {
+			targets = append(targets, p)
 		}
-		allOffers = append(allOffers, runpodOffers...)
+	}
+
+	allOffers, statuses := fanOutSearch(ctx, cache, targets, filter, s.config.UpstreamTimeout)
+	if allFailed(statuses) {
+		return nil, statuses, ErrAllProvidersFailed
 	}
 
 	// Apply advanced filters
@@ -87,147 +110,90 @@ func (s *GPUService) SearchOffersAdvanced(filter *types.AdvancedSearchFilter) ([
 	// Sort results
 	s.sortOffers(allOffers, filter.SortBy, filter.SortOrder)
 
-	return allOffers, nil
+	return allOffers, statuses, nil
 }
 
-// searchVastAI searches Vast.ai for offers
-func (s *GPUService) searchVastAI(filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
-	vastFilter := &vastai.SearchOffersRequest{
-		AvailableOnly: filter.Available,
-	}
-
-	if filter.GPUModel != "" {
-		vastFilter.GPUName = filter.GPUModel
-	}
-	if filter.MinGPUCount > 0 {
-		vastFilter.MinGPUCount = filter.MinGPUCount
-	}
-	if filter.MaxPrice > 0 {
-		vastFilter.MaxPrice = filter.MaxPrice
-	}
-	if filter.MinRAM > 0 {
-		vastFilter.MinRAM = filter.MinRAM
-	}
-	if filter.Region != "" {
-		vastFilter.Datacenter = filter.Region
-	}
-
-	offers, err := s.vastClient.SearchOffers(vastFilter)
-	if err != nil {
-		return nil, err
-	}
+// applyAdvancedFilters applies advanced filtering to the results
+func (s *GPUService) applyAdvancedFilters(offers []types.GPUInstance, filter *types.AdvancedSearchFilter) []types.GPUInstance {
+	var filtered []types.GPUInstance
 
-	var instances []types.GPUInstance
 	for _, offer := range offers {
-		instance := vastai.ConvertOfferToGPUInstance(offer)
-		// Enhance with GPU model information
-		if gpuInfo, exists := types.GPUModels[instance.GPUModel]; exists {
-			instance.GPUInfo = &gpuInfo
-			instance.Performance = gpuInfo.Performance
-		}
-		instances = append(instances, instance)
-	}
-
-	return instances, nil
-}
-
-// searchRunPod searches RunPod for offers
-func (s *GPUService) searchRunPod(filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
-	gpuTypes, err := s.runpodClient.GetGPUTypes()
-	if err != nil {
-		return nil, err
-	}
-
-	var instances []types.GPUInstance
-	for _, gpuType := range gpuTypes {
-		instance := runpod.ConvertGPUTypeToGPUInstance(gpuType)
-		
-		// Enhance with GPU model information
-		if gpuInfo, exists := types.GPUModels[instance.GPUModel]; exists {
-			instance.GPUInfo = &gpuInfo
-			instance.Performance = gpuInfo.Performance
+		if matchesAdvancedFilter(offer, filter) {
+			filtered = append(filtered, offer)
 		}
-		
-		instances = append(instances, instance)
 	}
 
-	return instances, nil
+	return filtered
 }
 
-// applyAdvancedFilters applies advanced filtering to the results
-func (s *GPUService) applyAdvancedFilters(offers []types.GPUInstance, filter *types.AdvancedSearchFilter) []types.GPUInstance {
-	var filtered []types.GPUInstance
-
-	for _, offer := range offers {
-		// GPU Models filter (multiple models)
-		if len(filter.GPUModels) > 0 {
-			found := false
-			for _, model := range filter.GPUModels {
-				if strings.Contains(strings.ToLower(offer.GPUModel), strings.ToLower(model)) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
+// matchesAdvancedFilter checks an offer against the filters providers don't apply themselves
+func matchesAdvancedFilter(offer types.GPUInstance, filter *types.AdvancedSearchFilter) bool {
+	// GPU Models filter (multiple models)
+	if len(filter.GPUModels) > 0 {
+		found := false
+		for _, model := range filter.GPUModels {
+			if strings.Contains(strings.ToLower(offer.GPUModel), strings.ToLower(model)) {
+				found = true
+				break
 			}
 		}
-
-		// GPU Category filter
-		if filter.GPUCategory != "" && offer.GPUInfo != nil {
-			if types.GPUCategory(offer.GPUInfo.Category) != filter.GPUCategory {
-				continue
-			}
+		if !found {
+			return false
 		}
+	}
 
-		// GPU Count range
-		if filter.MaxGPUCount > 0 && offer.GPUCount > filter.MaxGPUCount {
-			continue
+	// GPU Category filter
+	if filter.GPUCategory != "" && offer.GPUInfo != nil {
+		if types.GPUCategory(offer.GPUInfo.Category) != filter.GPUCategory {
+			return false
 		}
+	}
 
-		// Price range
-		if filter.MinPrice > 0 && offer.PricePerHour < filter.MinPrice {
-			continue
-		}
+	// GPU Count range
+	if filter.MaxGPUCount > 0 && offer.GPUCount > filter.MaxGPUCount {
+		return false
+	}
 
-		// RAM range
-		if filter.MaxRAM > 0 && offer.RAM > filter.MaxRAM {
-			continue
-		}
+	// Price range
+	if filter.MinPrice > 0 && offer.PricePerHour < filter.MinPrice {
+		return false
+	}
 
-		// Storage filter
-		if filter.MinStorage > 0 && offer.Storage < filter.MinStorage {
-			continue
-		}
+	// RAM range
+	if filter.MaxRAM > 0 && offer.RAM > filter.MaxRAM {
+		return false
+	}
 
-		// Regions filter (multiple regions)
-		if len(filter.Regions) > 0 {
-			found := false
-			for _, region := range filter.Regions {
-				if strings.Contains(strings.ToLower(offer.Region), strings.ToLower(region)) {
-					found = true
-					break
-				}
-			}
-			if !found {
-				continue
+	// Storage filter
+	if filter.MinStorage > 0 && offer.Storage < filter.MinStorage {
+		return false
+	}
+
+	// Regions filter (multiple regions)
+	if len(filter.Regions) > 0 {
+		found := false
+		for _, region := range filter.Regions {
+			if strings.Contains(strings.ToLower(offer.Region), strings.ToLower(region)) {
+				found = true
+				break
 			}
 		}
-
-		// Reliability filter
-		if filter.MinReliability > 0 && offer.Reliability < filter.MinReliability {
-			continue
+		if !found {
+			return false
 		}
+	}
 
-		// Performance filter
-		if filter.MinPerformance > 0 && offer.Performance < filter.MinPerformance {
-			continue
-		}
+	// Reliability filter
+	if filter.MinReliability > 0 && offer.Reliability < filter.MinReliability {
+		return false
+	}
 
-		filtered = append(filtered, offer)
+	// Performance filter
+	if filter.MinPerformance > 0 && offer.Performance < filter.MinPerformance {
+		return false
 	}
 
-	return filtered
+	return true
 }
 
 // sortOffers sorts the offers based on the specified criteria
@@ -264,389 +230,331 @@ func (s *GPUService) sortOffers(offers []types.GPUInstance, sortBy, sortOrder st
 	})
 }
 
-// GetInstances retrieves all instances for the user
-func (s *GPUService) GetInstances() ([]types.GPUInstance, error) {
-	var allInstances []types.GPUInstance
-
-	// Get Vast.ai instances
-	if s.vastClient != nil {
-		instances, err := s.vastClient.GetInstances()
-		if err != nil {
-			return nil, fmt.Errorf("error getting Vast.ai instances: %v", err)
-		}
-
-		for _, instance := range instances {
-			gpuInstance := vastai.ConvertInstanceToGPUInstance(instance)
-			// Enhance with GPU model information
-			if gpuInfo, exists := types.GPUModels[gpuInstance.GPUModel]; exists {
-				gpuInstance.GPUInfo = &gpuInfo
-				gpuInstance.Performance = gpuInfo.Performance
-			}
-			allInstances = append(allInstances, gpuInstance)
-		}
-	}
-
-	// Get RunPod instances
-	if s.runpodClient != nil {
-		pods, err := s.runpodClient.SearchPods()
-		if err != nil {
-			return nil, fmt.Errorf("error getting RunPod instances: %v", err)
-		}
-
-		for _, pod := range pods {
-			gpuInstance := runpod.ConvertPodToGPUInstance(pod)
-			// Enhance with GPU model information
-			if gpuInfo, exists := types.GPUModels[gpuInstance.GPUModel]; exists {
-				gpuInstance.GPUInfo = &gpuInfo
-				gpuInstance.Performance = gpuInfo.Performance
-			}
-			allInstances = append(allInstances, gpuInstance)
-		}
-	}
-
-	return allInstances, nil
-}
-
-// CreateInstance creates a new GPU instance
-func (s *GPUService) CreateInstance(req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
-	switch req.Provider {
-	case types.VastAI:
-		return s.createVastAIInstance(req)
-	case types.RunPod:
-		return s.createRunPodInstance(req)
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", req.Provider)
+// GetInstances retrieves all instances owned by the user
+func (s *GPUService) GetInstances(ctx context.Context, userID uint) ([]types.GPUInstance, error) {
+	var rows []models.Instance
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("error loading instances: %v", err)
 	}
-}
 
-// createVastAIInstance creates a Vast.ai instance
-func (s *GPUService) createVastAIInstance(req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
-	if s.vastClient == nil {
-		return nil, fmt.Errorf("Vast.ai client not configured")
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
 	}
-
-	offerID, err := strconv.Atoi(req.OfferID)
+	costs, err := s.accruedCosts(ctx, ids, time.Now())
 	if err != nil {
-		return nil, fmt.Errorf("invalid offer ID: %v", err)
+		return nil, err
 	}
 
-	vastReq := &vastai.CreateInstanceRequest{
-		OfferID:       offerID,
-		Price:         0, // Use default price from offer
-		DiskSizeGB:    10, // Default disk size
-		Image:         req.Image,
-		Label:         req.Label,
-		OnStartScript: req.OnStartScript,
+	instances := make([]types.GPUInstance, 0, len(rows))
+	for _, row := range rows {
+		instance := row.ToGPUInstance()
+		instance.AccruedCost = costs[row.ID]
+		instances = append(instances, instance)
 	}
 
-	if vastReq.Image == "" {
-		vastReq.Image = "pytorch/pytorch:latest" // Default image
-	}
+	return instances, nil
+}
 
-	// Override with resource requirements
-	if req.Resources != nil {
-		if req.Resources.MinStorage > 0 {
-			vastReq.DiskSizeGB = req.Resources.MinStorage
+// CreateInstance creates a new GPU instance and records it for the user, unless
+// running it would break one of the user's budgets
+func (s *GPUService) CreateInstance(ctx context.Context, userID uint, req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
+	launch := *req
+	now := time.Now()
+	expiresAt, action, err := launchExpiry(req.ExpiresAt, req.MaxRuntime, req.ExpiryAction, now)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil {
+		if err := checkExpiryAction(req.Provider, action); err != nil {
+			return nil, err
 		}
 	}
+	launch.ExpiresAt, launch.MaxRuntime, launch.ExpiryAction = expiresAt, "", action
 
-	instance, err := s.vastClient.CreateInstance(vastReq)
+	p, err := s.provider(ctx, userID, req.Provider)
 	if err != nil {
-		return nil, fmt.Errorf("error creating Vast.ai instance: %v", err)
+		return nil, err
 	}
 
-	result := vastai.ConvertInstanceToGPUInstance(*instance)
-	// Enhance with GPU model information
-	if gpuInfo, exists := types.GPUModels[result.GPUModel]; exists {
-		result.GPUInfo = &gpuInfo
-		result.Performance = gpuInfo.Performance
+	costOf := func(now time.Time) (launchCost, error) {
+		return s.offerLaunchCost(ctx, p, req.OfferID, expiresAt, now)
 	}
+	var instance, result *types.GPUInstance
+	err = s.launchWithinBudgets(ctx, userID, costOf, func(db *gorm.DB) error {
+		upstreamCtx, cancel := s.upstreamContext(ctx)
+		defer cancel()
 
-	return &result, nil
-}
-
-// createRunPodInstance creates a RunPod instance
-func (s *GPUService) createRunPodInstance(req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
-	if s.runpodClient == nil {
-		return nil, fmt.Errorf("RunPod client not configured")
-	}
-
-	runpodReq := &runpod.CreatePodRequest{
-		Name:            req.Label,
-		ImageName:       req.Image,
-		GPUTypeID:       req.OfferID,
-		CloudType:       "COMMUNITY", // Default to community cloud
-		SupportPublicIp: true,
-		StartJupyter:    false,
-		StartSsh:        true,
-		ContainerDisk:   10, // Default container disk
-		VolumeInGb:      0,  // No additional volume by default
-		VolumeMountPath: "/workspace",
-		Ports:           "22/tcp,8888/tcp", // SSH and Jupyter
-	}
-
-	if runpodReq.ImageName == "" {
-		runpodReq.ImageName = "pytorch/pytorch:latest"
-	}
-
-	// Convert environment variables
-	if req.Environment != nil {
-		for key, value := range req.Environment {
-			runpodReq.Env = append(runpodReq.Env, runpod.EnvVar{
-				Key:   key,
-				Value: value,
-			})
+		created, err := p.CreateInstance(upstreamCtx, &launch)
+		if err != nil {
+			return fmt.Errorf("error creating %s instance: %w", p.Capabilities().DisplayName, err)
 		}
+		instance = created
+		result, err = s.recordInstance(db, userID, &launch, created)
+		return err
+	})
+	if err != nil {
+		return nil, unrecordedInstance(instance, result, err)
 	}
+	return result, nil
+}
 
-	// Override with resource requirements
-	if req.Resources != nil {
-		if req.Resources.MinStorage > 0 {
-			runpodReq.VolumeInGb = req.Resources.MinStorage
-		}
+// unrecordedInstance returns the error a launch failed with. When the instance was
+// created and recorded but the budget transaction couldn't commit, it says so with the
+// instance's ID to allow manual cleanup.
+func unrecordedInstance(instance, recorded *types.GPUInstance, err error) error {
+	if instance != nil && recorded != nil {
+		return fmt.Errorf("instance %s was created but could not be recorded: %v", instance.ID, err)
 	}
+	return err
+}
 
-	// Handle ports
-	if len(req.Ports) > 0 {
-		var ports []string
-		for _, port := range req.Ports {
-			protocol := "tcp"
-			if port.Protocol != "" {
-				protocol = port.Protocol
-			}
-			ports = append(ports, fmt.Sprintf("%d/%s", port.ContainerPort, protocol))
+// recordInstance stores an instance the provider has just created for the user through
+// db, which should outlive the request's context. The request's expiry must already be
+// resolved to ExpiresAt.
+func (s *GPUService) recordInstance(db *gorm.DB, userID uint, req *types.CreateInstanceRequest, instance *types.GPUInstance) (*types.GPUInstance, error) {
+	row := models.Instance{
+		OfferID:      req.OfferID,
+		Image:        req.Image,
+		Label:        req.Label,
+		ExpiresAt:    req.ExpiresAt,
+		ExpiryAction: req.ExpiryAction,
+		LaunchConfig: models.LaunchConfig{
+			OnStartScript: req.OnStartScript,
+			SSHKey:        req.SSHKey,
+			Environment:   req.Environment,
+			Ports:         req.Ports,
+			Resources:     req.Resources,
+		},
+	}
+	row.FromGPUInstance(*instance, userID)
+	if row.Status == "" {
+		row.Status = types.StatusStarting
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		err := tx.Create(&models.StatusTransition{
+			InstanceID: row.ID,
+			ToStatus:   row.Status,
+			Source:     transitionSourceAPI,
+			OccurredAt: row.CreatedAt,
+		}).Error
+		if err != nil {
+			return err
 		}
-		runpodReq.Ports = strings.Join(ports, ",")
-	}
-
-	pod, err := s.runpodClient.CreatePod(runpodReq)
+		return syncCostSegment(tx, &row, row.CreatedAt)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error creating RunPod instance: %v", err)
-	}
-
-	result := runpod.ConvertPodToGPUInstance(*pod)
-	// Enhance with GPU model information
-	if gpuInfo, exists := types.GPUModels[result.GPUModel]; exists {
-		result.GPUInfo = &gpuInfo
-		result.Performance = gpuInfo.Performance
+		// The instance exists at the provider, so surface its ID to allow manual cleanup
+		return nil, fmt.Errorf("instance %s was created but could not be recorded: %v", instance.ID, err)
 	}
 
+	result := row.ToGPUInstance()
+	result.GPUInfo = instance.GPUInfo
+	result.Performance = instance.Performance
 	return &result, nil
 }
 
-// DestroyInstance terminates an instance
-func (s *GPUService) DestroyInstance(instanceID string) error {
-	provider, providerID, err := s.parseInstanceID(instanceID)
+// DestroyInstance terminates one of the user's instances
+func (s *GPUService) DestroyInstance(ctx context.Context, userID uint, instanceID string) error {
+	row, p, err := s.ownedInstance(ctx, userID, instanceID)
 	if err != nil {
 		return err
 	}
 
-	switch provider {
-	case types.VastAI:
-		if s.vastClient == nil {
-			return fmt.Errorf("Vast.ai client not configured")
-		}
+	return s.destroyInstance(ctx, row, p, transitionSourceAPI)
+}
 
-		id, err := strconv.Atoi(providerID)
-		if err != nil {
-			return fmt.Errorf("invalid provider ID: %v", err)
-		}
+// destroyInstance terminates an instance at its provider, then marks its record
+// terminated and deletes it along with its idle policy
+func (s *GPUService) destroyInstance(ctx context.Context, row *models.Instance, p providers.Provider, source string) error {
+	upstreamCtx, cancel := s.upstreamContext(ctx)
+	defer cancel()
 
-		return s.vastClient.DestroyInstance(id)
+	if err := p.DestroyInstance(upstreamCtx, row.ProviderID); err != nil {
+		return err
+	}
 
-	case types.RunPod:
-		if s.runpodClient == nil {
-			return fmt.Errorf("RunPod client not configured")
+	return s.db.WithContext(context.WithoutCancel(ctx)).Transaction(func(tx *gorm.DB) error {
+		if err := transitionStatus(tx, row, types.StatusTerminated, source, time.Now()); err != nil {
+			return err
 		}
-
-		return s.runpodClient.TerminatePod(providerID)
-
-	default:
-		return fmt.Errorf("unsupported provider: %s", provider)
-	}
+		if err := tx.Where("instance_id = ?", row.ID).Delete(&models.IdlePolicy{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(row).Error
+	})
 }
 
-// StartInstance starts a stopped instance
-func (s *GPUService) StartInstance(instanceID string) error {
-	provider, providerID, err := s.parseInstanceID(instanceID)
+// StartInstance starts one of the user's stopped instances, unless running it would
+// break one of the user's budgets
+func (s *GPUService) StartInstance(ctx context.Context, userID uint, instanceID string) error {
+	row, p, err := s.ownedInstance(ctx, userID, instanceID)
 	if err != nil {
 		return err
 	}
 
-	switch provider {
-	case types.VastAI:
-		if s.vastClient == nil {
-			return fmt.Errorf("Vast.ai client not configured")
-		}
-
-		id, err := strconv.Atoi(providerID)
-		if err != nil {
-			return fmt.Errorf("invalid provider ID: %v", err)
-		}
-
-		return s.vastClient.StartInstance(id)
+	start := func(db *gorm.DB) error {
+		upstreamCtx, cancel := s.upstreamContext(ctx)
+		defer cancel()
 
-	case types.RunPod:
-		if s.runpodClient == nil {
-			return fmt.Errorf("RunPod client not configured")
+		if err := p.StartInstance(upstreamCtx, row.ProviderID); err != nil {
+			return err
 		}
 
-		return s.runpodClient.ResumePod(providerID)
+		return transitionStatus(db, row, types.StatusStarting, transitionSourceAPI, time.Now())
+	}
 
-	default:
-		return fmt.Errorf("unsupported provider: %s", provider)
+	// An instance billed as running already counts against the budgets
+	if billingState(row.Status) == models.BillingRunning {
+		return start(s.db.WithContext(context.WithoutCancel(ctx)))
 	}
+	costOf := func(now time.Time) (launchCost, error) {
+		return newLaunchCost(row.GPUCount, row.PricePerHour, row.ExpiresAt, now), nil
+	}
+	return s.launchWithinBudgets(ctx, userID, costOf, start)
 }
 
-// StopInstance stops a running instance
-func (s *GPUService) StopInstance(instanceID string) error {
-	provider, providerID, err := s.parseInstanceID(instanceID)
+// StopInstance stops one of the user's running instances
+func (s *GPUService) StopInstance(ctx context.Context, userID uint, instanceID string) error {
+	row, p, err := s.ownedInstance(ctx, userID, instanceID)
 	if err != nil {
 		return err
 	}
 
-	switch provider {
-	case types.VastAI:
-		if s.vastClient == nil {
-			return fmt.Errorf("Vast.ai client not configured")
-		}
-
-		id, err := strconv.Atoi(providerID)
-		if err != nil {
-			return fmt.Errorf("invalid provider ID: %v", err)
-		}
-
-		return s.vastClient.StopInstance(id)
-
-	case types.RunPod:
-		if s.runpodClient == nil {
-			return fmt.Errorf("RunPod client not configured")
-		}
+	return s.stopInstance(ctx, row, p, transitionSourceAPI)
+}
 
-		return s.runpodClient.StopPod(providerID)
+// stopInstance stops an instance at its provider and records it as stopping
+func (s *GPUService) stopInstance(ctx context.Context, row *models.Instance, p providers.Provider, source string) error {
+	upstreamCtx, cancel := s.upstreamContext(ctx)
+	defer cancel()
 
-	default:
-		return fmt.Errorf("unsupported provider: %s", provider)
+	if err := p.StopInstance(upstreamCtx, row.ProviderID); err != nil {
+		return err
 	}
+
+	return transitionStatus(s.db.WithContext(context.WithoutCancel(ctx)), row, types.StatusStopping, source, time.Now())
 }
 
-// GetInstance retrieves details of a specific instance
-func (s *GPUService) GetInstance(instanceID string) (*types.GPUInstance, error) {
-	provider, providerID, err := s.parseInstanceID(instanceID)
+// GetInstance retrieves live details of one of the user's instances
+func (s *GPUService) GetInstance(ctx context.Context, userID uint, instanceID string) (*types.GPUInstance, error) {
+	row, p, err := s.ownedInstance(ctx, userID, instanceID)
 	if err != nil {
 		return nil, err
 	}
 
-	switch provider {
-	case types.VastAI:
-		if s.vastClient == nil {
-			return nil, fmt.Errorf("Vast.ai client not configured")
-		}
-
-		id, err := strconv.Atoi(providerID)
-		if err != nil {
-			return nil, fmt.Errorf("invalid provider ID: %v", err)
-		}
-
-		instance, err := s.vastClient.GetInstance(id)
-		if err != nil {
-			return nil, fmt.Errorf("error getting Vast.ai instance: %v", err)
-		}
+	upstreamCtx, cancel := s.upstreamContext(ctx)
+	defer cancel()
 
-		result := vastai.ConvertInstanceToGPUInstance(*instance)
-		// Enhance with GPU model information
-		if gpuInfo, exists := types.GPUModels[result.GPUModel]; exists {
-			result.GPUInfo = &gpuInfo
-			result.Performance = gpuInfo.Performance
-		}
+	instance, err := p.GetInstance(upstreamCtx, row.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s instance: %w", p.Capabilities().DisplayName, err)
+	}
 
-		return &result, nil
+	// Live provider data wins, but keep what we recorded at launch when the provider omits it
+	if instance.Name == "" {
+		instance.Name = row.Name
+	}
+	if instance.GPUModel == "" {
+		instance.GPUModel = row.GPUModel
+		instance.GPUCount = row.GPUCount
+	}
+	instance.CreatedAt = row.CreatedAt
+	instance.ExpiresAt = row.ExpiresAt
+	instance.ExpiryAction = row.ExpiryAction
 
-	case types.RunPod:
-		// RunPod doesn't have a direct get instance by ID, so we search through all pods
-		pods, err := s.runpodClient.SearchPods()
-		if err != nil {
-			return nil, fmt.Errorf("error getting RunPod instances: %v", err)
-		}
+	costs, err := s.accruedCosts(ctx, []uint{row.ID}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	instance.AccruedCost = costs[row.ID]
 
-		for _, pod := range pods {
-			if pod.ID == providerID {
-				result := runpod.ConvertPodToGPUInstance(pod)
-				// Enhance with GPU model information
-				if gpuInfo, exists := types.GPUModels[result.GPUModel]; exists {
-					result.GPUInfo = &gpuInfo
-					result.Performance = gpuInfo.Performance
-				}
-				return &result, nil
-			}
-		}
+	return instance, nil
+}
 
-		return nil, fmt.Errorf("RunPod instance not found: %s", providerID)
+// ownedInstance loads the user's instance record together with its provider
+func (s *GPUService) ownedInstance(ctx context.Context, userID uint, instanceID string) (*models.Instance, providers.Provider, error) {
+	row, err := s.ownedRow(ctx, userID, instanceID)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	p, err := s.provider(ctx, userID, row.Provider)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	return row, p, nil
 }
 
-// parseInstanceID parses our internal instance ID format (provider_id)
-func (s *GPUService) parseInstanceID(instanceID string) (types.GPUProvider, string, error) {
-	if len(instanceID) < 5 {
-		return "", "", fmt.Errorf("invalid instance ID format")
+// ownedRow loads the user's instance record by its API ID
+func (s *GPUService) ownedRow(ctx context.Context, userID uint, instanceID string) (*models.Instance, error) {
+	name, providerID, err := providers.ParseInstanceID(instanceID)
+	if err != nil {
+		return nil, err
 	}
 
-	if strings.HasPrefix(instanceID, "vast_") {
-		return types.VastAI, instanceID[5:], nil
-	}
-	if strings.HasPrefix(instanceID, "runpod_") {
-		return types.RunPod, instanceID[7:], nil
+	var row models.Instance
+	err = s.db.WithContext(ctx).Where("user_id = ? AND provider = ? AND provider_id = ?", userID, name, providerID).First(&row).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInstanceNotFound
+		}
+		return nil, fmt.Errorf("error loading instance: %v", err)
 	}
 
-	return "", "", fmt.Errorf("unknown provider in instance ID: %s", instanceID)
+	return &row, nil
 }
 
-// GetSupportedProviders returns a list of configured providers with details
-func (s *GPUService) GetSupportedProviders() []types.ProviderInfo {
-	var providers []types.ProviderInfo
+// provider returns the user's configured provider with the given name
+func (s *GPUService) provider(ctx context.Context, userID uint, name types.GPUProvider) (providers.Provider, error) {
+	descriptor, known := providers.Lookup(name)
+	if !known {
+		return nil, apperr.Errorf(apperr.InvalidArgument, "unsupported provider: %s", name)
+	}
 
-	// Vast.ai
-	vastInfo := types.ProviderInfo{
-		Name:        types.VastAI,
-		DisplayName: "Vast.ai",
-		Website:     "https://vast.ai",
-		Regions:     []string{"US-East", "US-West", "Europe", "Asia"},
-		Features:    []string{"SSH Access", "Docker Support", "Jupyter Notebooks", "Custom Images"},
-		IsConfigured: s.vastClient != nil,
+	registry, err := s.pool.ForUser(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	
-	if s.vastClient != nil {
-		// Get available GPU models from static list for now
-		for model := range types.GPUModels {
-			vastInfo.GPUModels = append(vastInfo.GPUModels, model)
-		}
+
+	p, ok := registry.Get(name)
+	if !ok {
+		return nil, apperr.Errorf(apperr.InvalidArgument, "%s client not configured", descriptor.Capabilities.DisplayName)
 	}
-	
-	providers = append(providers, vastInfo)
 
-	// RunPod
-	runpodInfo := types.ProviderInfo{
-		Name:        types.RunPod,
-		DisplayName: "RunPod",
-		Website:     "https://runpod.io",
-		Regions:     []string{"Global", "US", "Europe", "Asia"},
-		Features:    []string{"GraphQL API", "Jupyter Support", "SSH Access", "Community & Secure Cloud"},
-		IsConfigured: s.runpodClient != nil,
+	return p, nil
+}
+
+// GetSupportedProviders returns a list of known providers with details, marking
+// those the user has credentials for as configured
+func (s *GPUService) GetSupportedProviders(ctx context.Context, userID uint) ([]types.ProviderInfo, error) {
+	registry, err := s.pool.ForUser(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	
-	if s.runpodClient != nil {
-		// Get available GPU models from static list for now
-		for model := range types.GPUModels {
-			runpodInfo.GPUModels = append(runpodInfo.GPUModels, model)
+
+	var providerInfos []types.ProviderInfo
+
+	for _, d := range providers.Catalog {
+		_, configured := registry.Get(d.Name)
+		info := d.Capabilities.ProviderInfo(d.Name, configured)
+
+		if configured {
+			// Get available GPU models from static list for now
+			for model := range types.GPUModels {
+				info.GPUModels = append(info.GPUModels, model)
+			}
 		}
+
+		providerInfos = append(providerInfos, info)
 	}
-	
-	providers = append(providers, runpodInfo)
 
-	return providers
+	return providerInfos, nil
 }
 
 // GetGPUModels returns information about all available GPU models
@@ -654,8 +562,9 @@ func (s *GPUService) GetGPUModels() map[string]types.GPUModel {
 	return types.GPUModels
 }
 
-// GetMarketplaceStats returns marketplace statistics
-func (s *GPUService) GetMarketplaceStats() (*types.MarketplaceStats, error) {
+// GetMarketplaceStats returns marketplace statistics, along with the status of the
+// provider searches they were computed from
+func (s *GPUService) GetMarketplaceStats(ctx context.Context, userID uint) (*types.MarketplaceStats, []types.ProviderSearchStatus, error) {
 	// This would typically aggregate data from multiple providers
 	// For now, we'll return basic statistics
 	
@@ -669,9 +578,9 @@ func (s *GPUService) GetMarketplaceStats() (*types.MarketplaceStats, error) {
 		Available: true,
 	}
 	
-	offers, err := s.SearchOffersAdvanced(filter)
+	offers, statuses, err := s.SearchOffersAdvanced(ctx, userID, filter)
 	if err != nil {
-		return nil, err
+		return nil, statuses, err
 	}
 
 	stats.TotalInstances = len(offers)
@@ -762,5 +671,5 @@ func (s *GPUService) GetMarketplaceStats() (*types.MarketplaceStats, error) {
 		}
 	}
 
-	return stats, nil
+	return stats, statuses, nil
 }
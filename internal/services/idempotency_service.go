@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/apperr"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = apperr.New(apperr.Conflict, "Idempotency-Key was already used for a different request")
+
+	// ErrIdempotencyInProgress is returned when the original request for a key hasn't finished
+	ErrIdempotencyInProgress = apperr.New(apperr.Conflict, "a request with this Idempotency-Key is still in progress")
+)
+
+const (
+	// idempotencyPruneInterval is how often expired idempotency records are deleted
+	idempotencyPruneInterval = time.Hour
+
+	// idempotencyLease is how long a key stays reserved for a request that hasn't
+	// finished. It outlasts the slowest request, so a reservation older than this was
+	// abandoned, e.g. by a crash, and the key can be taken again.
+	idempotencyLease = 10 * time.Minute
+)
+
+// IdempotencyService stores responses to requests made with an Idempotency-Key so
+// retries return the original result instead of repeating the request
+type IdempotencyService struct {
+	db      *gorm.DB
+	ttl     time.Duration
+	metrics *metrics.Metrics
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(db *gorm.DB, cfg *config.Config, m *metrics.Metrics) *IdempotencyService {
+	return &IdempotencyService{
+		db:      db,
+		ttl:     cfg.IdempotencyTTL,
+		metrics: m,
+	}
+}
+
+// Begin reserves a key for a request. When the key was already used for the same
+// request, the stored record is returned with replay set so its response can be sent
+// again; otherwise the new in-progress record is returned and must be completed or released.
+// Keys whose record expired, or whose reservation was abandoned, are taken again.
+func (s *IdempotencyService) Begin(ctx context.Context, userID uint, key, requestHash string) (record *models.IdempotencyRecord, replay bool, err error) {
+	now := time.Now()
+
+	// A second pass is only needed when an expired record had to be cleared first
+	for pass := 0; pass < 2; pass++ {
+		reserved := models.IdempotencyRecord{
+			UserID:      userID,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   now.Add(s.ttl),
+		}
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&reserved)
+		if result.Error != nil {
+			return nil, false, fmt.Errorf("error reserving idempotency key: %v", result.Error)
+		}
+		if result.RowsAffected == 1 {
+			return &reserved, false, nil
+		}
+
+		var existing models.IdempotencyRecord
+		err := s.db.WithContext(ctx).Where("user_id = ? AND key = ?", userID, key).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error loading idempotency key: %v", err)
+		}
+
+		switch {
+		case existing.ExpiresAt.Before(now):
+			err := s.db.WithContext(ctx).Where("id = ? AND expires_at < ?", existing.ID, now).Delete(&models.IdempotencyRecord{}).Error
+			if err != nil {
+				return nil, false, fmt.Errorf("error clearing expired idempotency key: %v", err)
+			}
+		case abandonedReservation(&existing, now):
+			err := s.db.WithContext(ctx).Where("id = ? AND status_code = 0 AND created_at < ?", existing.ID, now.Add(-idempotencyLease)).Delete(&models.IdempotencyRecord{}).Error
+			if err != nil {
+				return nil, false, fmt.Errorf("error clearing abandoned idempotency key: %v", err)
+			}
+		case existing.RequestHash != requestHash:
+			return nil, false, ErrIdempotencyKeyReused
+		case existing.StatusCode == 0:
+			return nil, false, ErrIdempotencyInProgress
+		default:
+			return &existing, true, nil
+		}
+	}
+
+	return nil, false, ErrIdempotencyInProgress
+}
+
+// abandonedReservation reports whether record reserves its key for a request that
+// hasn't finished within idempotencyLease
+func abandonedReservation(record *models.IdempotencyRecord, now time.Time) bool {
+	return record.StatusCode == 0 && record.CreatedAt.Before(now.Add(-idempotencyLease))
+}
+
+// Complete stores the response to a reserved request
+func (s *IdempotencyService) Complete(ctx context.Context, record *models.IdempotencyRecord, statusCode int, body []byte) error {
+	err := s.db.WithContext(ctx).Model(record).Updates(map[string]interface{}{
+		"status_code":   statusCode,
+		"response_body": string(body),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("error saving idempotent response: %v", err)
+	}
+	return nil
+}
+
+// Release gives up a reservation whose request produced no response, so it can be retried
+func (s *IdempotencyService) Release(ctx context.Context, record *models.IdempotencyRecord) error {
+	if err := s.db.WithContext(ctx).Delete(record).Error; err != nil {
+		return fmt.Errorf("error releasing idempotency key: %v", err)
+	}
+	return nil
+}
+
+// Run deletes expired records every hour until ctx is cancelled
+func (s *IdempotencyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(idempotencyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		err := s.db.WithContext(ctx).Where("expires_at < ?", start).Delete(&models.IdempotencyRecord{}).Error
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Idempotency: error pruning expired keys", "error", err)
+		}
+		s.metrics.ObserveJob("idempotency_prune", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
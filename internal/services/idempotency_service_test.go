@@ -0,0 +1,27 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+)
+
+func TestAbandonedReservation(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		record   models.IdempotencyRecord
+		expected bool
+	}{
+		{"in progress", models.IdempotencyRecord{CreatedAt: now.Add(-time.Minute)}, false},
+		{"past the lease", models.IdempotencyRecord{CreatedAt: now.Add(-idempotencyLease - time.Second)}, true},
+		{"completed", models.IdempotencyRecord{StatusCode: 201, CreatedAt: now.Add(-time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		if got := abandonedReservation(&tt.record, now); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrIdlePolicyNotFound is returned when an instance has no idle policy
+	ErrIdlePolicyNotFound = apperr.New(apperr.NotFound, "idle policy not found")
+
+	// ErrInvalidIdlePolicy is returned when an idle policy's settings are rejected
+	ErrInvalidIdlePolicy = apperr.New(apperr.InvalidArgument, "invalid idle policy")
+)
+
+// IdlePolicyService manages per-instance idle policies and enforces them in the background
+type IdlePolicyService struct {
+	db           *gorm.DB
+	gpu          *GPUService
+	interval     time.Duration
+	retention    time.Duration // How long utilization samples are kept
+	maxSampleAge time.Duration // Instances without a sample this recent are never considered idle
+	metrics      *metrics.Metrics
+}
+
+// NewIdlePolicyService creates a new idle policy service
+func NewIdlePolicyService(db *gorm.DB, cfg *config.Config, gpu *GPUService, m *metrics.Metrics) *IdlePolicyService {
+	return &IdlePolicyService{
+		db:           db,
+		gpu:          gpu,
+		interval:     cfg.IdleCheckInterval,
+		retention:    cfg.UtilizationRetention,
+		maxSampleAge: 3 * cfg.ReconcileInterval,
+		metrics:      m,
+	}
+}
+
+// Get returns the idle policy of one of the user's instances
+func (s *IdlePolicyService) Get(ctx context.Context, userID uint, instanceID string) (*models.IdlePolicy, error) {
+	row, err := s.gpu.ownedRow(ctx, userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy models.IdlePolicy
+	if err := s.db.WithContext(ctx).Where("instance_id = ?", row.ID).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrIdlePolicyNotFound
+		}
+		return nil, fmt.Errorf("error loading idle policy: %v", err)
+	}
+	return &policy, nil
+}
+
+// Set creates or replaces the idle policy of one of the user's instances
+func (s *IdlePolicyService) Set(ctx context.Context, userID uint, instanceID string, req *types.IdlePolicyRequest) (*models.IdlePolicy, error) {
+	if err := validateIdlePolicy(req, s.retention); err != nil {
+		return nil, err
+	}
+
+	row, err := s.gpu.ownedRow(ctx, userID, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIdleDetection(row.Provider, req); err != nil {
+		return nil, err
+	}
+
+	policy := models.IdlePolicy{IsEnabled: true}
+	if err := s.db.WithContext(ctx).Where(models.IdlePolicy{InstanceID: row.ID}).FirstOrInit(&policy).Error; err != nil {
+		return nil, fmt.Errorf("error loading idle policy: %v", err)
+	}
+
+	policy.UtilizationThreshold = req.UtilizationThreshold
+	policy.IdleMinutes = req.IdleMinutes
+	policy.DestroyAfterStoppedHours = req.DestroyAfterStoppedHours
+	policy.GraceMinutes = req.GraceMinutes
+	if req.IsEnabled != nil {
+		policy.IsEnabled = *req.IsEnabled
+	}
+
+	if err := s.db.WithContext(ctx).Save(&policy).Error; err != nil {
+		return nil, fmt.Errorf("error saving idle policy: %v", err)
+	}
+	return &policy, nil
+}
+
+// Delete removes the idle policy of one of the user's instances
+func (s *IdlePolicyService) Delete(ctx context.Context, userID uint, instanceID string) error {
+	row, err := s.gpu.ownedRow(ctx, userID, instanceID)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.WithContext(ctx).Where("instance_id = ?", row.ID).Delete(&models.IdlePolicy{})
+	if result.Error != nil {
+		return fmt.Errorf("error deleting idle policy: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdlePolicyNotFound
+	}
+	return nil
+}
+
+// Run evaluates idle policies on every interval until ctx is cancelled
+func (s *IdlePolicyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		s.EvaluateOnce(ctx)
+		s.metrics.ObserveJob("idle_policies", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EvaluateOnce applies every enabled idle policy once and prunes old utilization samples
+func (s *IdlePolicyService) EvaluateOnce(ctx context.Context) {
+	now := time.Now()
+
+	var policies []models.IdlePolicy
+	if err := s.db.WithContext(ctx).Preload("Instance").Where("is_enabled = ?", true).Find(&policies).Error; err != nil {
+		slog.ErrorContext(ctx, "Idle policies: error loading policies", "error", err)
+		return
+	}
+
+	for i := range policies {
+		if ctx.Err() != nil {
+			return
+		}
+		policy := &policies[i]
+		if policy.Instance.ID == 0 {
+			// The instance record was deleted
+			continue
+		}
+		if err := s.evaluate(ctx, policy, now); err != nil {
+			slog.ErrorContext(ctx, "Idle policies: error evaluating policy", "policy_id", policy.ID, "instance_id", policy.Instance.ToGPUInstance().ID, "error", err)
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Where("sampled_at < ?", now.Add(-s.retention)).Delete(&models.UtilizationSample{}).Error; err != nil {
+		slog.ErrorContext(ctx, "Idle policies: error pruning utilization samples", "error", err)
+	}
+}
+
+// evaluate warns about, stops or destroys one policy's instance when it is due
+func (s *IdlePolicyService) evaluate(ctx context.Context, policy *models.IdlePolicy, now time.Time) error {
+	row := &policy.Instance
+
+	state, err := s.idleState(ctx, policy, row, now)
+	if err != nil {
+		return err
+	}
+
+	action, due := decideIdleAction(policy, state, now)
+	switch action {
+	case idleActionWarnStop:
+		message := fmt.Sprintf("GPU utilization has been below %g%% since %s; the instance will be stopped at %s",
+			policy.UtilizationThreshold, formatEventTime(*state.idleSince), formatEventTime(due))
+		return s.warn(ctx, row, models.EventIdleStopWarning, message)
+	case idleActionWarnDestroy:
+		message := fmt.Sprintf("The instance has been stopped since %s; it will be destroyed at %s",
+			formatEventTime(state.since), formatEventTime(due))
+		return s.warn(ctx, row, models.EventIdleDestroyWarning, message)
+	case idleActionStop:
+		return s.act(ctx, row, automatedAction{
+			actor:     transitionSourceIdlePolicy,
+			action:    auditActionStop,
+			eventType: models.EventIdleStop,
+			reason: fmt.Sprintf("Stopped by idle policy: GPU utilization below %g%% since %s",
+				policy.UtilizationThreshold, formatEventTime(*state.idleSince)),
+			details: models.JSONMap{
+				"policy_id":                     policy.ID,
+				"utilization_threshold_percent": policy.UtilizationThreshold,
+				"idle_minutes":                  policy.IdleMinutes,
+				"idle_since":                    *state.idleSince,
+			},
+		}, now)
+	case idleActionDestroy:
+		return s.act(ctx, row, automatedAction{
+			actor:     transitionSourceIdlePolicy,
+			action:    auditActionDestroy,
+			eventType: models.EventIdleDestroy,
+			reason:    fmt.Sprintf("Destroyed by idle policy: stopped since %s", formatEventTime(state.since)),
+			details: models.JSONMap{
+				"policy_id":                   policy.ID,
+				"destroy_after_stopped_hours": policy.DestroyAfterStoppedHours,
+				"stopped_since":               state.since,
+			},
+		}, now)
+	}
+	return nil
+}
+
+// warn records a warning event for the instance's owner
+func (s *IdlePolicyService) warn(ctx context.Context, row *models.Instance, eventType, message string) error {
+	event := models.InstanceEvent{InstanceID: row.ID, Type: eventType, Message: message}
+	if err := s.db.WithContext(ctx).Create(&event).Error; err != nil {
+		return fmt.Errorf("error recording %s event: %v", eventType, err)
+	}
+	slog.InfoContext(ctx, "Idle policies: "+message, "instance_id", row.ToGPUInstance().ID)
+	return nil
+}
+
+// act runs an automated action unless its last attempt failed too recently
+func (s *IdlePolicyService) act(ctx context.Context, row *models.Instance, a automatedAction, now time.Time) error {
+	due, err := automatedRetryDue(s.db.WithContext(ctx), row.ID, a.action, now)
+	if err != nil || !due {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Idle policies: "+a.reason, "instance_id", row.ToGPUInstance().ID)
+	return s.gpu.runAutomated(ctx, row, a)
+}
+
+// idleState gathers what a policy is evaluated against
+func (s *IdlePolicyService) idleState(ctx context.Context, policy *models.IdlePolicy, row *models.Instance, now time.Time) (idleState, error) {
+	db := s.db.WithContext(ctx)
+	state := idleState{status: row.Status, since: row.CreatedAt}
+
+	var transition models.StatusTransition
+	err := db.Where("instance_id = ? AND to_status = ?", row.ID, row.Status).Order("occurred_at DESC").Limit(1).Find(&transition).Error
+	if err != nil {
+		return state, fmt.Errorf("error loading status transitions: %v", err)
+	}
+	if transition.ID != 0 {
+		state.since = transition.OccurredAt
+	}
+
+	warningType := models.EventIdleStopWarning
+	if row.Status == types.StatusOffline {
+		warningType = models.EventIdleDestroyWarning
+	}
+	var warning models.InstanceEvent
+	err = db.Where("instance_id = ? AND type = ?", row.ID, warningType).Order("created_at DESC").Limit(1).Find(&warning).Error
+	if err != nil {
+		return state, fmt.Errorf("error loading instance events: %v", err)
+	}
+	if warning.ID != 0 {
+		state.warnedAt = &warning.CreatedAt
+	}
+
+	if row.Status == types.StatusRunning && policy.IdleMinutes > 0 {
+		var stats utilizationStats
+		err := db.Model(&models.UtilizationSample{}).
+			Select("MIN(sampled_at) AS first_at, MAX(sampled_at) AS last_at, MAX(CASE WHEN gpu_percent >= ? THEN sampled_at END) AS last_busy_at", policy.UtilizationThreshold).
+			Where("instance_id = ? AND sampled_at >= ?", row.ID, state.since).
+			Scan(&stats).Error
+		if err != nil {
+			return state, fmt.Errorf("error loading utilization samples: %v", err)
+		}
+		state.idleSince = idleSince(stats, now, s.maxSampleAge)
+	}
+
+	return state, nil
+}
+
+// idleAction is what an idle policy calls for
+type idleAction int
+
+const (
+	idleActionNone idleAction = iota
+	idleActionWarnStop
+	idleActionStop
+	idleActionWarnDestroy
+	idleActionDestroy
+)
+
+// idleState is what an idle policy is evaluated against
+type idleState struct {
+	status    types.InstanceStatus
+	since     time.Time  // When the instance entered its current status
+	idleSince *time.Time // Start of the current idle stretch, if recent samples show one
+	warnedAt  *time.Time // Latest warning recorded for the action the status leads to
+}
+
+// decideIdleAction returns the action a policy calls for now, and when the stop or
+// destroy it leads to is due
+func decideIdleAction(policy *models.IdlePolicy, state idleState, now time.Time) (idleAction, time.Time) {
+	grace := time.Duration(policy.GraceMinutes) * time.Minute
+
+	switch {
+	case state.status == types.StatusRunning && policy.IdleMinutes > 0 && state.idleSince != nil:
+		deadline := state.idleSince.Add(time.Duration(policy.IdleMinutes) * time.Minute)
+		return graceAction(*state.idleSince, deadline, grace, state.warnedAt, now, idleActionWarnStop, idleActionStop)
+	case state.status == types.StatusOffline && policy.DestroyAfterStoppedHours > 0:
+		deadline := state.since.Add(time.Duration(policy.DestroyAfterStoppedHours) * time.Hour)
+		return graceAction(state.since, deadline, grace, state.warnedAt, now, idleActionWarnDestroy, idleActionDestroy)
+	}
+	return idleActionNone, time.Time{}
+}
+
+// graceAction makes sure a warning recorded after start precedes act by at least
+// grace: the warning is due grace before deadline, and act once both deadline has
+// passed and the warning is grace old
+func graceAction(start, deadline time.Time, grace time.Duration, warnedAt *time.Time, now time.Time, warn, act idleAction) (idleAction, time.Time) {
+	if grace <= 0 {
+		if now.Before(deadline) {
+			return idleActionNone, deadline
+		}
+		return act, deadline
+	}
+
+	if warnedAt == nil || warnedAt.Before(start) {
+		if now.Before(deadline.Add(-grace)) {
+			return idleActionNone, deadline
+		}
+		due := now.Add(grace)
+		if due.Before(deadline) {
+			due = deadline
+		}
+		return warn, due
+	}
+
+	due := warnedAt.Add(grace)
+	if due.Before(deadline) {
+		due = deadline
+	}
+	if now.Before(due) {
+		return idleActionNone, due
+	}
+	return act, due
+}
+
+// utilizationStats summarizes an instance's utilization samples
+type utilizationStats struct {
+	FirstAt    *time.Time
+	LastAt     *time.Time
+	LastBusyAt *time.Time // Latest sample at or above the policy's threshold
+}
+
+// idleSince returns when an instance's GPUs last went idle. It returns nil when the
+// latest sample is busy, or too old to judge the instance by.
+func idleSince(stats utilizationStats, now time.Time, maxAge time.Duration) *time.Time {
+	if stats.LastAt == nil || now.Sub(*stats.LastAt) > maxAge {
+		return nil
+	}
+	if stats.LastBusyAt == nil {
+		return stats.FirstAt
+	}
+	if !stats.LastBusyAt.Before(*stats.LastAt) {
+		return nil
+	}
+	return stats.LastBusyAt
+}
+
+// validateIdlePolicy checks an idle policy request
+func validateIdlePolicy(req *types.IdlePolicyRequest, retention time.Duration) error {
+	if req.IdleMinutes <= 0 && req.DestroyAfterStoppedHours <= 0 {
+		return fmt.Errorf("%w: set idle_minutes, destroy_after_stopped_hours or both", ErrInvalidIdlePolicy)
+	}
+	if req.IdleMinutes < 0 || req.DestroyAfterStoppedHours < 0 || req.GraceMinutes < 0 {
+		return fmt.Errorf("%w: durations must not be negative", ErrInvalidIdlePolicy)
+	}
+	if req.IdleMinutes > 0 {
+		if req.UtilizationThreshold <= 0 || req.UtilizationThreshold > 100 {
+			return fmt.Errorf("%w: utilization_threshold_percent must be above 0 and at most 100", ErrInvalidIdlePolicy)
+		}
+		if time.Duration(req.IdleMinutes)*time.Minute > retention {
+			return fmt.Errorf("%w: idle_minutes must not exceed the utilization retention of %s", ErrInvalidIdlePolicy, retention)
+		}
+	}
+	return nil
+}
+
+// checkIdleDetection rejects stopping idle instances of providers that don't report
+// the GPU utilization idleness is judged by, since such a policy would never act
+func checkIdleDetection(provider types.GPUProvider, req *types.IdlePolicyRequest) error {
+	if req.IdleMinutes <= 0 {
+		return nil
+	}
+	descriptor, known := providers.Lookup(provider)
+	if known && !descriptor.Capabilities.ReportsUtilization {
+		return fmt.Errorf("%w: %s doesn't report GPU utilization, so idle_minutes can't be used", ErrInvalidIdlePolicy, descriptor.Capabilities.DisplayName)
+	}
+	return nil
+}
+
+// formatEventTime formats a time for event messages
+func formatEventTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
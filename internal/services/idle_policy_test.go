@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestDecideIdleActionStop(t *testing.T) {
+	idleStart := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	policy := &models.IdlePolicy{UtilizationThreshold: 5, IdleMinutes: 30, GraceMinutes: 5}
+	state := idleState{status: types.StatusRunning, since: idleStart.Add(-time.Hour), idleSince: &idleStart}
+	deadline := idleStart.Add(30 * time.Minute)
+
+	if action, _ := decideIdleAction(policy, state, idleStart.Add(20*time.Minute)); action != idleActionNone {
+		t.Errorf("expected no action before the grace period, got %d", action)
+	}
+
+	action, due := decideIdleAction(policy, state, idleStart.Add(25*time.Minute))
+	if action != idleActionWarnStop || !due.Equal(deadline) {
+		t.Errorf("expected a warning for a stop at %s, got %d at %s", deadline, action, due)
+	}
+
+	// Without a warning the stop waits for one, even after the deadline
+	late := deadline.Add(10 * time.Minute)
+	action, due = decideIdleAction(policy, state, late)
+	if action != idleActionWarnStop || !due.Equal(late.Add(5*time.Minute)) {
+		t.Errorf("expected a late warning pushing the stop back, got %d at %s", action, due)
+	}
+
+	warnedAt := idleStart.Add(25 * time.Minute)
+	state.warnedAt = &warnedAt
+	if action, _ := decideIdleAction(policy, state, deadline.Add(-time.Second)); action != idleActionNone {
+		t.Errorf("expected no action before the deadline, got %d", action)
+	}
+	if action, _ := decideIdleAction(policy, state, deadline); action != idleActionStop {
+		t.Errorf("expected a stop at the deadline, got %d", action)
+	}
+
+	// A warning from an earlier idle stretch doesn't count
+	earlier := idleStart.Add(-time.Hour)
+	state.warnedAt = &earlier
+	if action, _ := decideIdleAction(policy, state, deadline); action != idleActionWarnStop {
+		t.Errorf("expected a new warning, got %d", action)
+	}
+
+	// Busy or unknown utilization never stops the instance
+	state.idleSince = nil
+	if action, _ := decideIdleAction(policy, state, deadline.Add(time.Hour)); action != idleActionNone {
+		t.Errorf("expected no action without an idle stretch, got %d", action)
+	}
+}
+
+func TestDecideIdleActionWithoutGrace(t *testing.T) {
+	idleStart := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	policy := &models.IdlePolicy{UtilizationThreshold: 5, IdleMinutes: 30}
+	state := idleState{status: types.StatusRunning, since: idleStart, idleSince: &idleStart}
+
+	if action, _ := decideIdleAction(policy, state, idleStart.Add(29*time.Minute)); action != idleActionNone {
+		t.Errorf("expected no action before the deadline, got %d", action)
+	}
+	if action, _ := decideIdleAction(policy, state, idleStart.Add(30*time.Minute)); action != idleActionStop {
+		t.Errorf("expected an immediate stop at the deadline, got %d", action)
+	}
+}
+
+func TestDecideIdleActionDestroy(t *testing.T) {
+	stoppedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	policy := &models.IdlePolicy{IdleMinutes: 30, DestroyAfterStoppedHours: 24, GraceMinutes: 60}
+	state := idleState{status: types.StatusOffline, since: stoppedAt}
+	deadline := stoppedAt.Add(24 * time.Hour)
+
+	action, due := decideIdleAction(policy, state, deadline.Add(-time.Hour))
+	if action != idleActionWarnDestroy || !due.Equal(deadline) {
+		t.Errorf("expected a warning for a destroy at %s, got %d at %s", deadline, action, due)
+	}
+
+	warnedAt := deadline.Add(-time.Hour)
+	state.warnedAt = &warnedAt
+	if action, _ := decideIdleAction(policy, state, deadline); action != idleActionDestroy {
+		t.Errorf("expected a destroy at the deadline, got %d", action)
+	}
+
+	policy.DestroyAfterStoppedHours = 0
+	if action, _ := decideIdleAction(policy, state, deadline); action != idleActionNone {
+		t.Errorf("expected no destroy when disabled, got %d", action)
+	}
+
+	// Only running and stopped instances are acted on
+	policy.DestroyAfterStoppedHours = 24
+	state.status = types.StatusStopping
+	if action, _ := decideIdleAction(policy, state, deadline); action != idleActionNone {
+		t.Errorf("expected no action while stopping, got %d", action)
+	}
+}
+
+func TestIdleSince(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := now.Add(-2 * time.Hour)
+	busy := now.Add(-40 * time.Minute)
+	last := now.Add(-time.Minute)
+	old := now.Add(-10 * time.Minute)
+
+	if got := idleSince(utilizationStats{FirstAt: &first, LastAt: &last}, now, 3*time.Minute); got == nil || !got.Equal(first) {
+		t.Errorf("expected idle since the first sample, got %v", got)
+	}
+	if got := idleSince(utilizationStats{FirstAt: &first, LastAt: &last, LastBusyAt: &busy}, now, 3*time.Minute); got == nil || !got.Equal(busy) {
+		t.Errorf("expected idle since the last busy sample, got %v", got)
+	}
+	if got := idleSince(utilizationStats{FirstAt: &first, LastAt: &last, LastBusyAt: &last}, now, 3*time.Minute); got != nil {
+		t.Errorf("expected no idle stretch when the latest sample is busy, got %v", got)
+	}
+	if got := idleSince(utilizationStats{FirstAt: &first, LastAt: &old}, now, 3*time.Minute); got != nil {
+		t.Errorf("expected no idle stretch with stale samples, got %v", got)
+	}
+	if got := idleSince(utilizationStats{}, now, 3*time.Minute); got != nil {
+		t.Errorf("expected no idle stretch without samples, got %v", got)
+	}
+}
+
+func TestValidateIdlePolicy(t *testing.T) {
+	retention := 7 * 24 * time.Hour
+
+	valid := []types.IdlePolicyRequest{
+		{UtilizationThreshold: 5, IdleMinutes: 30, GraceMinutes: 5},
+		{DestroyAfterStoppedHours: 48},
+		{UtilizationThreshold: 10, IdleMinutes: 60, DestroyAfterStoppedHours: 24},
+	}
+	for _, req := range valid {
+		if err := validateIdlePolicy(&req, retention); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", req, err)
+		}
+	}
+
+	invalid := []types.IdlePolicyRequest{
+		{},
+		{IdleMinutes: 30},
+		{UtilizationThreshold: 101, IdleMinutes: 30},
+		{UtilizationThreshold: 5, IdleMinutes: 8 * 24 * 60},
+		{DestroyAfterStoppedHours: 24, GraceMinutes: -1},
+	}
+	for _, req := range invalid {
+		if err := validateIdlePolicy(&req, retention); !errors.Is(err, ErrInvalidIdlePolicy) {
+			t.Errorf("expected %+v to be rejected, got %v", req, err)
+		}
+	}
+}
+
+func TestCheckIdleDetection(t *testing.T) {
+	stop := &types.IdlePolicyRequest{UtilizationThreshold: 5, IdleMinutes: 30}
+	if err := checkIdleDetection(types.RunPod, stop); err != nil {
+		t.Errorf("expected RunPod instances to accept idle stops, got %v", err)
+	}
+	for _, provider := range []types.GPUProvider{types.VastAI, types.LambdaLabs, types.Paperspace} {
+		if err := checkIdleDetection(provider, stop); !errors.Is(err, ErrInvalidIdlePolicy) {
+			t.Errorf("expected an idle stop on %s to be rejected, got %v", provider, err)
+		}
+	}
+
+	destroy := &types.IdlePolicyRequest{DestroyAfterStoppedHours: 24}
+	if err := checkIdleDetection(types.VastAI, destroy); err != nil {
+		t.Errorf("expected destroying stopped instances not to need utilization, got %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// Sources recorded on status transitions
+const (
+	transitionSourceAPI        = "api"
+	transitionSourceReconciler = "reconciler"
+	transitionSourceIdlePolicy = "idle_policy"
+	transitionSourceExpiry     = "expiry"
+	transitionSourceBudget     = "budget"
+)
+
+// transitionStatus moves an instance to a new status, records the transition and
+// updates its cost segments. It is a no-op when the status is unchanged.
+func transitionStatus(tx *gorm.DB, row *models.Instance, to types.InstanceStatus, source string, at time.Time) error {
+	from := row.Status
+	if from == to {
+		return nil
+	}
+
+	if err := tx.Model(row).Update("status", to).Error; err != nil {
+		return err
+	}
+	row.Status = to
+
+	err := tx.Create(&models.StatusTransition{
+		InstanceID: row.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		Source:     source,
+		OccurredAt: at,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return syncCostSegment(tx, row, at)
+}
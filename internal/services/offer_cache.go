@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/types"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// OfferCache caches provider offer searches in process.
+//
+// Offers are keyed by provider account and the provider-side parts of the search
+// filter, so users share entries and fetches only when they search with the same API
+// key: one account's failures, such as a revoked key, never reach another. Entries
+// younger than ttl are served as-is; entries up to ttl+stale old are served while a
+// background refresh fetches new data. Concurrent fetches of the same key are
+// collapsed into one upstream request. With a zero ttl nothing is stored, but
+// concurrent fetches are still shared.
+type OfferCache struct {
+	ttl     time.Duration
+	stale   time.Duration
+	timeout time.Duration // Deadline for fetches, which outlive the request that started them
+	metrics *metrics.Metrics
+
+	mu          sync.Mutex
+	entries     map[string]offerCacheEntry
+	subscribers []RefreshFunc
+	group       singleflight.Group
+}
+
+// RefreshFunc is told about the offers returned by every successful provider fetch
+type RefreshFunc func(provider types.GPUProvider, offers []types.GPUInstance)
+
+// offerCacheEntry is one cached provider search
+type offerCacheEntry struct {
+	offers    []types.GPUInstance
+	fetchedAt time.Time
+}
+
+// NewOfferCache creates an offer cache that records its hit rate in m
+func NewOfferCache(ttl, stale, timeout time.Duration, m *metrics.Metrics) *OfferCache {
+	return &OfferCache{
+		ttl:     ttl,
+		stale:   stale,
+		timeout: timeout,
+		metrics: m,
+		entries: make(map[string]offerCacheEntry),
+	}
+}
+
+// OnRefresh registers fn to run, in its own goroutine, after each successful fetch
+func (c *OfferCache) OnRefresh(fn RefreshFunc) {
+	c.mu.Lock()
+	c.subscribers = append(c.subscribers, fn)
+	c.mu.Unlock()
+}
+
+// Search returns the provider's offers for the filter, from cache when possible,
+// along with when they were fetched from the provider
+func (c *OfferCache) Search(ctx context.Context, p providers.Provider, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, time.Time, error) {
+	key := offerCacheKey(p.Name(), providers.AccountFingerprint(p), filter)
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok && c.ttl > 0 {
+		age := now.Sub(entry.fetchedAt)
+		if age < c.ttl {
+			c.metrics.CacheLookup("offers", metrics.CacheHit)
+			return entry.offers, entry.fetchedAt, nil
+		}
+		if age < c.ttl+c.stale {
+			// Serve stale data and refresh behind the request
+			c.metrics.CacheLookup("offers", metrics.CacheStale)
+			go c.refresh(key, p, filter)
+			return entry.offers, entry.fetchedAt, nil
+		}
+	}
+	c.metrics.CacheLookup("offers", metrics.CacheMiss)
+
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		return c.fetch(key, p, filter)
+	})
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, time.Time{}, res.Err
+		}
+		entry := res.Val.(offerCacheEntry)
+		return entry.offers, entry.fetchedAt, nil
+	case <-ctx.Done():
+		// The shared fetch carries on and fills the cache for the next caller
+		return nil, time.Time{}, ctx.Err()
+	}
+}
+
+// refresh fetches a key in the background, unless a fetch is already running
+func (c *OfferCache) refresh(key string, p providers.Provider, filter *types.AdvancedSearchFilter) {
+	_, err, _ := c.group.Do(key, func() (interface{}, error) {
+		return c.fetch(key, p, filter)
+	})
+	if err != nil {
+		slog.Error("Offer cache: error refreshing offers", "provider", p.Name(), "error", err)
+	}
+}
+
+// fetch queries the provider and stores the result. It runs detached from any
+// request so one caller going away doesn't fail the others waiting on it.
+func (c *OfferCache) fetch(key string, p providers.Provider, filter *types.AdvancedSearchFilter) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	offers, err := p.SearchOffers(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := offerCacheEntry{offers: offers, fetchedAt: time.Now()}
+
+	c.mu.Lock()
+	if c.ttl > 0 {
+		c.entries[key] = entry
+		c.evictExpiredLocked(entry.fetchedAt)
+	}
+	subscribers := c.subscribers
+	c.mu.Unlock()
+
+	for _, fn := range subscribers {
+		go fn(p.Name(), offers)
+	}
+
+	return entry, nil
+}
+
+// evictExpiredLocked drops entries too old to be served even as stale data
+func (c *OfferCache) evictExpiredLocked(now time.Time) {
+	for key, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl+c.stale {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// offerCacheKey normalizes the provider-side parts of a search filter for the account
+// with the given fingerprint. Fields applied after offers are fetched (sorting,
+// categories, reliability...) are left out so searches differing only in those share
+// an entry.
+func offerCacheKey(provider types.GPUProvider, account string, filter *types.AdvancedSearchFilter) string {
+	return fmt.Sprintf("%s|account=%s|gpu=%s|min_gpus=%d|max_price=%.4f|min_ram=%d|region=%s|available=%t",
+		provider,
+		account,
+		strings.ToLower(strings.TrimSpace(filter.GPUModel)),
+		filter.MinGPUCount,
+		filter.MaxPrice,
+		filter.MinRAM,
+		strings.ToLower(strings.TrimSpace(filter.Region)),
+		filter.Available,
+	)
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// countingProvider counts offer searches and holds each one until release is closed
+type countingProvider struct {
+	stubProvider
+	calls   int32
+	release chan struct{}
+}
+
+func (p *countingProvider) SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
+	atomic.AddInt32(&p.calls, 1)
+	if p.release != nil {
+		<-p.release
+	}
+	return p.offers, nil
+}
+
+func TestOfferCacheServesFreshEntries(t *testing.T) {
+	cache := NewOfferCache(time.Minute, time.Minute, time.Second, nil)
+	provider := &countingProvider{stubProvider: stubProvider{name: types.VastAI, offers: []types.GPUInstance{{ID: "vast_1"}}}}
+
+	for i := 0; i < 3; i++ {
+		offers, _, err := cache.Search(context.Background(), provider, &types.AdvancedSearchFilter{GPUModel: "RTX 4090"})
+		if err != nil || len(offers) != 1 {
+			t.Fatalf("unexpected offers %v, err %v", offers, err)
+		}
+	}
+	if calls := atomic.LoadInt32(&provider.calls); calls != 1 {
+		t.Errorf("expected one upstream search, got %d", calls)
+	}
+
+	// Case and post-fetch filters don't change the key; provider-side filters do
+	cache.Search(context.Background(), provider, &types.AdvancedSearchFilter{GPUModel: "rtx 4090 ", SortBy: "price"})
+	if calls := atomic.LoadInt32(&provider.calls); calls != 1 {
+		t.Errorf("expected equivalent filters to share an entry, got %d searches", calls)
+	}
+	cache.Search(context.Background(), provider, &types.AdvancedSearchFilter{GPUModel: "A100"})
+	if calls := atomic.LoadInt32(&provider.calls); calls != 2 {
+		t.Errorf("expected a different GPU model to miss, got %d searches", calls)
+	}
+}
+
+func TestOfferCacheServesStaleWhileRefreshing(t *testing.T) {
+	cache := NewOfferCache(time.Minute, time.Minute, time.Second, nil)
+	provider := &countingProvider{stubProvider: stubProvider{name: types.RunPod}}
+	filter := &types.AdvancedSearchFilter{}
+
+	fetchedAt := time.Now().Add(-90 * time.Second)
+	cache.entries[offerCacheKey(provider.Name(), "", filter)] = offerCacheEntry{
+		offers:    []types.GPUInstance{{ID: "runpod_old"}},
+		fetchedAt: fetchedAt,
+	}
+
+	offers, age, err := cache.Search(context.Background(), provider, filter)
+	if err != nil || len(offers) != 1 || offers[0].ID != "runpod_old" {
+		t.Fatalf("expected the stale entry, got %v, err %v", offers, err)
+	}
+	if !age.Equal(fetchedAt) {
+		t.Errorf("expected the stale fetch time, got %s", age)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&provider.calls) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&provider.calls) != 1 {
+		t.Errorf("expected a background refresh")
+	}
+}
+
+func TestOfferCacheCollapsesConcurrentMisses(t *testing.T) {
+	cache := NewOfferCache(time.Minute, time.Minute, time.Second, nil)
+	provider := &countingProvider{
+		stubProvider: stubProvider{name: types.LambdaLabs, offers: []types.GPUInstance{{ID: "lambda_1"}}},
+		release:      make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := cache.Search(context.Background(), provider, &types.AdvancedSearchFilter{}); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(provider.release)
+	wg.Wait()
+
+	if calls := atomic.LoadInt32(&provider.calls); calls != 1 {
+		t.Errorf("expected concurrent searches to share one upstream search, got %d", calls)
+	}
+}
+
+func TestOfferCacheCallerCancellation(t *testing.T) {
+	cache := NewOfferCache(time.Minute, time.Minute, time.Second, nil)
+	provider := &countingProvider{
+		stubProvider: stubProvider{name: types.Paperspace, offers: []types.GPUInstance{{ID: "paperspace_1"}}},
+		release:      make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := cache.Search(ctx, provider, &types.AdvancedSearchFilter{}); err != context.DeadlineExceeded {
+		t.Fatalf("expected the caller's deadline, got %v", err)
+	}
+
+	// The abandoned fetch still completes and fills the cache
+	close(provider.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		cache.mu.Lock()
+		_, ok := cache.entries[offerCacheKey(provider.Name(), "", &types.AdvancedSearchFilter{})]
+		cache.mu.Unlock()
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the fetch to fill the cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOfferCacheNotifiesRefreshes(t *testing.T) {
+	cache := NewOfferCache(0, time.Minute, time.Second, nil)
+	provider := &countingProvider{stubProvider: stubProvider{name: types.VastAI, offers: []types.GPUInstance{{ID: "vast_1"}}}}
+
+	refreshed := make(chan []types.GPUInstance, 2)
+	cache.OnRefresh(func(name types.GPUProvider, offers []types.GPUInstance) {
+		if name != types.VastAI {
+			t.Errorf("unexpected provider %s", name)
+		}
+		refreshed <- offers
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := cache.Search(context.Background(), provider, &types.AdvancedSearchFilter{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		select {
+		case offers := <-refreshed:
+			if len(offers) != 1 {
+				t.Errorf("expected the fetched offers, got %v", offers)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected a refresh notification")
+		}
+	}
+
+	// A zero TTL shares fetches but keeps nothing
+	if calls := atomic.LoadInt32(&provider.calls); calls != 2 || len(cache.entries) != 0 {
+		t.Errorf("expected 2 uncached searches, got %d with %d entries", calls, len(cache.entries))
+	}
+}
+
+func TestOfferCacheSeparatesAccounts(t *testing.T) {
+	cache := NewOfferCache(time.Minute, time.Minute, time.Second, nil)
+	revoked := &stubProvider{name: types.VastAI, err: errors.New("401 unauthorized")}
+	valid := &countingProvider{stubProvider: stubProvider{name: types.VastAI, offers: []types.GPUInstance{{ID: "vast_1"}}}}
+	account := func(p providers.Provider, apiKey string) providers.Provider {
+		d := providers.Descriptor{Name: types.VastAI, New: func(string) providers.Provider { return p }}
+		return d.NewInstrumented(apiKey, nil)
+	}
+
+	if _, _, err := cache.Search(context.Background(), account(revoked, "revoked_key"), &types.AdvancedSearchFilter{}); err == nil {
+		t.Fatal("expected the revoked key's search to fail")
+	}
+	offers, _, err := cache.Search(context.Background(), account(valid, "valid_key"), &types.AdvancedSearchFilter{})
+	if err != nil || len(offers) != 1 {
+		t.Fatalf("expected another account's failure not to be shared, got %v, err %v", offers, err)
+	}
+
+	cache.Search(context.Background(), account(valid, "other_key"), &types.AdvancedSearchFilter{})
+	if calls := atomic.LoadInt32(&valid.calls); calls != 2 {
+		t.Errorf("expected each account to be searched with its own key, got %d searches", calls)
+	}
+}
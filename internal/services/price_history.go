@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidHistoryQuery is returned for price history queries that can't be answered
+var ErrInvalidHistoryQuery = apperr.New(apperr.InvalidArgument, "invalid price history query")
+
+const (
+	// defaultHistoryWindow is how far back a history query looks when it gives no start
+	defaultHistoryWindow = 7 * 24 * time.Hour
+
+	// maxHistoryBuckets bounds how many intervals a single history query may span
+	maxHistoryBuckets = 2000
+
+	// minHistoryInterval is the finest bucket size offered
+	minHistoryInterval = time.Minute
+)
+
+// PriceHistoryQuery selects recorded prices for a GPU model
+type PriceHistoryQuery struct {
+	GPUModel string
+	Provider types.GPUProvider // Optional
+	Interval time.Duration
+	From     time.Time // Defaults to defaultHistoryWindow before To
+	To       time.Time // Defaults to now
+}
+
+// PriceHistoryService records marketplace prices over time and answers history queries
+type PriceHistoryService struct {
+	db       *gorm.DB
+	config   *config.Config
+	registry *providers.Registry
+	metrics  *metrics.Metrics
+}
+
+// NewPriceHistoryService creates a price history service. Snapshots are taken with
+// the globally configured provider keys, as marketplace prices don't depend on the user.
+func NewPriceHistoryService(db *gorm.DB, cfg *config.Config, m *metrics.Metrics) *PriceHistoryService {
+	return &PriceHistoryService{
+		db:       db,
+		config:   cfg,
+		registry: providers.NewRegistryFromKeys(globalProviderKeys(cfg), m),
+		metrics:  m,
+	}
+}
+
+// Run takes a snapshot on every interval until ctx is cancelled
+func (s *PriceHistoryService) Run(ctx context.Context) {
+	if len(s.registry.All()) == 0 {
+		slog.WarnContext(ctx, "Price history: no provider API keys configured, snapshots disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.config.PriceSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := s.SnapshotOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "Price history: error taking snapshot", "error", err)
+		}
+		s.metrics.ObserveJob("price_snapshot", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SnapshotOnce records the current price of every available offer and drops
+// snapshots older than the retention period
+func (s *PriceHistoryService) SnapshotOnce(ctx context.Context) error {
+	filter := &types.AdvancedSearchFilter{Available: true}
+	offers, statuses := fanOutSearch(ctx, nil, s.registry.All(), filter, s.config.UpstreamTimeout)
+	for _, status := range statuses {
+		if status.Status != types.ProviderQueryOK {
+			slog.WarnContext(ctx, "Price history: provider search failed", "provider", status.Provider, "status", status.Status, "error", status.Error)
+		}
+	}
+
+	capturedAt := time.Now().UTC()
+	snapshots := make([]models.PriceSnapshot, 0, len(offers))
+	for _, offer := range offers {
+		// History is priced per GPU, so offers without a GPU count can't be compared
+		if offer.GPUModel == "" || offer.PricePerHour <= 0 || offer.GPUCount <= 0 {
+			continue
+		}
+		snapshots = append(snapshots, models.NewPriceSnapshot(offer, capturedAt))
+	}
+
+	if len(snapshots) > 0 {
+		if err := s.db.WithContext(ctx).CreateInBatches(snapshots, 500).Error; err != nil {
+			return fmt.Errorf("error saving price snapshots: %v", err)
+		}
+	}
+
+	cutoff := capturedAt.Add(-s.config.PriceHistoryRetention)
+	if err := s.db.WithContext(ctx).Where("captured_at < ?", cutoff).Delete(&models.PriceSnapshot{}).Error; err != nil {
+		return fmt.Errorf("error pruning price snapshots: %v", err)
+	}
+
+	return nil
+}
+
+// History returns the price distribution of a GPU model in each interval of the
+// query's time range. Intervals without snapshots are left out.
+func (s *PriceHistoryService) History(ctx context.Context, query PriceHistoryQuery) (*types.PriceHistory, error) {
+	if strings.TrimSpace(query.GPUModel) == "" {
+		return nil, fmt.Errorf("%w: gpu_model is required", ErrInvalidHistoryQuery)
+	}
+	if query.Interval < minHistoryInterval {
+		return nil, fmt.Errorf("%w: interval must be at least %s", ErrInvalidHistoryQuery, minHistoryInterval)
+	}
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultHistoryWindow)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidHistoryQuery)
+	}
+	if query.To.Sub(query.From)/query.Interval > maxHistoryBuckets {
+		return nil, fmt.Errorf("%w: range spans more than %d intervals", ErrInvalidHistoryQuery, maxHistoryBuckets)
+	}
+
+	buckets, err := s.buckets(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.PriceHistory{
+		GPUModel: query.GPUModel,
+		Provider: query.Provider,
+		Interval: int64(query.Interval.Seconds()),
+		From:     query.From.UTC(),
+		To:       query.To.UTC(),
+		Buckets:  buckets,
+	}, nil
+}
+
+// priceBucketColumns summarizes the price per GPU of the snapshots in each bucket.
+// Buckets are counted in whole intervals since the Unix epoch, and percentiles
+// interpolate linearly between the closest ranks.
+const priceBucketColumns = `to_timestamp(floor(extract(epoch FROM captured_at) / @interval) * @interval) AS start,
+	COUNT(*) AS samples,
+	MIN((price_per_hour / gpu_count)::float8) AS min,
+	percentile_cont(0.10) WITHIN GROUP (ORDER BY (price_per_hour / gpu_count)::float8) AS p10,
+	percentile_cont(0.25) WITHIN GROUP (ORDER BY (price_per_hour / gpu_count)::float8) AS p25,
+	percentile_cont(0.50) WITHIN GROUP (ORDER BY (price_per_hour / gpu_count)::float8) AS median,
+	percentile_cont(0.75) WITHIN GROUP (ORDER BY (price_per_hour / gpu_count)::float8) AS p75,
+	percentile_cont(0.90) WITHIN GROUP (ORDER BY (price_per_hour / gpu_count)::float8) AS p90,
+	MAX((price_per_hour / gpu_count)::float8) AS max`
+
+// buckets aggregates the snapshots matching a validated query into its intervals
+func (s *PriceHistoryService) buckets(ctx context.Context, query PriceHistoryQuery) ([]types.PriceBucket, error) {
+	db := s.db.WithContext(ctx).Model(&models.PriceSnapshot{}).
+		Select(priceBucketColumns, sql.Named("interval", int64(query.Interval.Seconds()))).
+		Where("gpu_model_key = ? AND captured_at >= ? AND captured_at < ? AND gpu_count > 0", models.GPUModelKey(query.GPUModel), query.From, query.To)
+	if query.Provider != "" {
+		db = db.Where("provider = ?", query.Provider)
+	}
+
+	buckets := []types.PriceBucket{}
+	if err := db.Group("start").Order("start").Scan(&buckets).Error; err != nil {
+		return nil, fmt.Errorf("error loading price history: %v", err)
+	}
+	for i := range buckets {
+		buckets[i].Start = buckets[i].Start.UTC()
+	}
+	return buckets, nil
+}
+
+// ParseHistoryInterval parses a bucket size such as "15m", "1h" or "1d"
+func ParseHistoryInterval(value string) (time.Duration, error) {
+	interval, err := parseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid interval %q", ErrInvalidHistoryQuery, value)
+	}
+	return interval, nil
+}
+
+// parseDuration parses a Go duration, or a whole number of days such as "2d"
+func parseDuration(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid number of days %q", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
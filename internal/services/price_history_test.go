@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseHistoryInterval(t *testing.T) {
+	valid := map[string]time.Duration{
+		"15m": 15 * time.Minute,
+		"1h":  time.Hour,
+		"1d":  24 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+	}
+	for value, expected := range valid {
+		interval, err := ParseHistoryInterval(value)
+		if err != nil || interval != expected {
+			t.Errorf("expected %s to parse as %s, got %s, %v", value, expected, interval, err)
+		}
+	}
+
+	for _, value := range []string{"", "d", "0d", "-1d", "hourly"} {
+		if _, err := ParseHistoryInterval(value); !errors.Is(err, ErrInvalidHistoryQuery) {
+			t.Errorf("expected %q to be rejected, got %v", value, err)
+		}
+	}
+}
+
+func TestHistoryRejectsInvalidQueries(t *testing.T) {
+	s := &PriceHistoryService{}
+	now := time.Now()
+
+	queries := map[string]PriceHistoryQuery{
+		"missing model":  {Interval: time.Hour},
+		"tiny interval":  {GPUModel: "H100", Interval: time.Second},
+		"inverted range": {GPUModel: "H100", Interval: time.Hour, From: now, To: now.Add(-time.Hour)},
+		"too many":       {GPUModel: "H100", Interval: time.Minute, From: now.Add(-30 * 24 * time.Hour), To: now},
+	}
+	for name, query := range queries {
+		if _, err := s.History(context.Background(), query); !errors.Is(err, ErrInvalidHistoryQuery) {
+			t.Errorf("%s: expected an invalid query error, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// providerCacheTTL bounds how long a user's provider clients are reused, so
+// credential changes made by another server instance are eventually picked up
+const providerCacheTTL = 5 * time.Minute
+
+// cachedRegistry is a user's provider registry and when it was built
+type cachedRegistry struct {
+	registry *providers.Registry
+	builtAt  time.Time
+}
+
+// ProviderPool builds and caches provider clients per user from user_providers
+type ProviderPool struct {
+	db      *gorm.DB
+	config  *config.Config
+	metrics *metrics.Metrics
+
+	mu        sync.Mutex
+	cache     map[uint]cachedRegistry
+	lastSweep time.Time
+}
+
+// NewProviderPool creates a provider pool whose providers record their calls in m
+func NewProviderPool(db *gorm.DB, cfg *config.Config, m *metrics.Metrics) *ProviderPool {
+	return &ProviderPool{
+		db:      db,
+		config:  cfg,
+		metrics: m,
+		cache:   make(map[uint]cachedRegistry),
+	}
+}
+
+// ForUser returns the providers the user has enabled credentials for
+func (p *ProviderPool) ForUser(ctx context.Context, userID uint) (*providers.Registry, error) {
+	p.mu.Lock()
+	cached, ok := p.cache[userID]
+	p.mu.Unlock()
+
+	if ok && time.Since(cached.builtAt) < providerCacheTTL {
+		p.metrics.CacheLookup("provider_clients", metrics.CacheHit)
+		return cached.registry, nil
+	}
+	p.metrics.CacheLookup("provider_clients", metrics.CacheMiss)
+
+	keys, err := p.KeysForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	registry := providers.NewRegistryFromKeys(keys, p.metrics)
+
+	now := time.Now()
+	p.mu.Lock()
+	p.sweep(now)
+	p.cache[userID] = cachedRegistry{registry: registry, builtAt: now}
+	p.mu.Unlock()
+
+	return registry, nil
+}
+
+// newProvider creates a provider for apiKey whose calls are recorded in the pool's
+// metrics, like those of the providers it caches
+func (p *ProviderPool) newProvider(d providers.Descriptor, apiKey string) providers.Provider {
+	return d.NewInstrumented(apiKey, p.metrics)
+}
+
+// sweep drops expired registries, at most once per providerCacheTTL, so users who
+// stopped making requests don't keep theirs forever. Must be called with p.mu held.
+func (p *ProviderPool) sweep(now time.Time) {
+	if now.Sub(p.lastSweep) < providerCacheTTL {
+		return
+	}
+	p.lastSweep = now
+	for userID, cached := range p.cache {
+		if now.Sub(cached.builtAt) >= providerCacheTTL {
+			delete(p.cache, userID)
+		}
+	}
+}
+
+// KeysForUser returns the API key to use for each provider on behalf of the user.
+// Global keys fill the gaps only when PROVIDER_KEY_FALLBACK is enabled.
+func (p *ProviderPool) KeysForUser(ctx context.Context, userID uint) (map[types.GPUProvider]string, error) {
+	var credentials []models.UserProvider
+	err := p.db.WithContext(ctx).Where("user_id = ? AND is_enabled = ?", userID, true).Find(&credentials).Error
+	if err != nil {
+		return nil, fmt.Errorf("error loading provider credentials: %v", err)
+	}
+
+	keys := make(map[types.GPUProvider]string)
+	if p.config.ProviderKeyFallback {
+		for name, key := range globalProviderKeys(p.config) {
+			keys[name] = key
+		}
+	}
+	for _, credential := range credentials {
+		keys[types.GPUProvider(credential.Provider)] = credential.APIKey
+	}
+
+	return keys, nil
+}
+
+// Invalidate drops the cached providers for a user after their credentials change
+func (p *ProviderPool) Invalidate(userID uint) {
+	p.mu.Lock()
+	delete(p.cache, userID)
+	p.mu.Unlock()
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/providers"
+)
+
+func TestProviderPoolSweep(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	pool := &ProviderPool{cache: map[uint]cachedRegistry{
+		1: {registry: providers.NewRegistry(), builtAt: now.Add(-providerCacheTTL)},
+		2: {registry: providers.NewRegistry(), builtAt: now.Add(-time.Minute)},
+	}}
+
+	pool.sweep(now)
+	if _, ok := pool.cache[1]; ok {
+		t.Error("expected the expired registry to be dropped")
+	}
+	if _, ok := pool.cache[2]; !ok {
+		t.Error("expected the fresh registry to be kept")
+	}
+
+	// Sweeps run at most once per TTL
+	pool.cache[3] = cachedRegistry{registry: providers.NewRegistry(), builtAt: now.Add(-providerCacheTTL)}
+	pool.sweep(now.Add(time.Minute))
+	if _, ok := pool.cache[3]; !ok {
+		t.Error("expected no sweep before the TTL passed")
+	}
+}
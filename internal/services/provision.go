@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoMatchingOffers is returned when provisioning finds no available offer for the filter
+	ErrNoMatchingOffers = apperr.New(apperr.NotFound, "no available offers match the filter")
+
+	// ErrProvisionFailed is returned when every offer tried while provisioning was refused
+	ErrProvisionFailed = apperr.New(apperr.Upstream, "no offer could be rented")
+)
+
+const (
+	// defaultProvisionAttempts is how many offers are tried when the request doesn't say
+	defaultProvisionAttempts = 3
+
+	// maxProvisionAttempts bounds how many offers one request may try
+	maxProvisionAttempts = 10
+)
+
+// launchFunc creates an instance from an offer
+type launchFunc func(ctx context.Context, offer types.GPUInstance) (*types.GPUInstance, error)
+
+// Provision searches for offers matching the request's filter and rents the
+// best-ranked one that accepts, trying the next offer whenever one is refused. Offers
+// that would break one of the user's budgets are skipped. The result lists every offer
+// tried, and is returned alongside ErrProvisionFailed too.
+func (s *GPUService) Provision(ctx context.Context, userID uint, req *types.ProvisionRequest) (*types.ProvisionResult, error) {
+	now := time.Now()
+	expiresAt, action, err := launchExpiry(req.ExpiresAt, req.MaxRuntime, req.ExpiryAction, now)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := req.Filter
+	filter.Available = true
+
+	// Cached offers may have been rented out since, so provisioning asks the providers
+	offers, _, err := s.searchOffers(ctx, userID, &filter, nil)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil {
+		offers = expirableOffers(offers, action)
+	}
+
+	budgets, err := s.userBudgets(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgets) > 0 {
+		if offers, err = affordableOffers(offers, budgets, expiresAt, now); err != nil {
+			return nil, err
+		}
+	}
+
+	attempts := req.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultProvisionAttempts
+	}
+	if attempts > maxProvisionAttempts {
+		attempts = maxProvisionAttempts
+	}
+
+	launch := func(ctx context.Context, offer types.GPUInstance) (*types.GPUInstance, error) {
+		createReq := provisionCreateRequest(req, offer)
+		createReq.ExpiresAt, createReq.ExpiryAction = expiresAt, action
+
+		p, err := s.provider(ctx, userID, offer.Provider)
+		if err != nil {
+			return nil, err
+		}
+
+		// Budgets are checked again as the offer is launched, since other launches may
+		// have used them up since the search
+		costOf := func(now time.Time) (launchCost, error) {
+			return newLaunchCost(offer.GPUCount, offer.PricePerHour, expiresAt, now), nil
+		}
+		var instance, result *types.GPUInstance
+		err = s.launchWithinBudgets(ctx, userID, costOf, func(db *gorm.DB) error {
+			upstreamCtx, cancel := s.upstreamContext(ctx)
+			defer cancel()
+
+			created, err := p.CreateInstance(upstreamCtx, createReq)
+			if err != nil {
+				return launchFailure(upstreamCtx, p.Capabilities().DisplayName, err)
+			}
+			instance = created
+			if result, err = s.recordInstance(db, userID, createReq, created); err != nil {
+				return haltError{err}
+			}
+			return nil
+		})
+		if err != nil {
+			if instance != nil {
+				return nil, haltError{unrecordedInstance(instance, result, err)}
+			}
+			return nil, err
+		}
+		return result, nil
+	}
+
+	return provisionOffers(ctx, offers, attempts, launch)
+}
+
+// haltError wraps a launch failure after which trying another offer could rent a second instance
+type haltError struct {
+	err error
+}
+
+func (e haltError) Error() string { return e.err.Error() }
+func (e haltError) Unwrap() error { return e.err }
+
+// launchFailure classifies an error from creating an instance at provider. Unless the
+// provider definitely refused the request, it may have rented the offer before the
+// call failed, so the error is wrapped in haltError to keep another offer from being rented.
+func launchFailure(ctx context.Context, provider string, err error) error {
+	if ctx.Err() != nil {
+		// The provider may have accepted the request before the deadline
+		return haltError{apperr.Errorf(apperr.Upstream, "timed out waiting for %s, the offer may still have been rented: %v", provider, err)}
+	}
+
+	switch apperr.KindOf(err) {
+	case apperr.InvalidArgument, apperr.ProviderUnauthorized, apperr.PermissionDenied, apperr.NotFound, apperr.Conflict, apperr.RateLimited:
+		return err
+	default:
+		// Dropped connections, unreadable responses and provider failures
+		return haltError{apperr.Errorf(apperr.Upstream, "%s failed while renting the offer, it may still have been rented: %v", provider, err)}
+	}
+}
+
+// provisionOffers tries to launch the first attempts rentable offers in order until one succeeds
+func provisionOffers(ctx context.Context, offers []types.GPUInstance, attempts int, launch launchFunc) (*types.ProvisionResult, error) {
+	result := &types.ProvisionResult{Attempts: []types.ProvisionAttempt{}}
+
+	for _, offer := range offers {
+		if len(result.Attempts) == attempts {
+			break
+		}
+		if offer.Status == types.StatusUnavailable {
+			continue
+		}
+
+		attempt := types.ProvisionAttempt{
+			Provider:     offer.Provider,
+			OfferID:      offer.ProviderID,
+			PricePerHour: offer.PricePerHour,
+		}
+
+		instance, err := launch(ctx, offer)
+		if err == nil {
+			result.Attempts = append(result.Attempts, attempt)
+			result.Instance = instance
+			return result, nil
+		}
+
+		attempt.Error = err.Error()
+		result.Attempts = append(result.Attempts, attempt)
+
+		var halt haltError
+		if ctx.Err() != nil || errors.As(err, &halt) {
+			break
+		}
+	}
+
+	if len(result.Attempts) == 0 {
+		return result, ErrNoMatchingOffers
+	}
+	return result, ErrProvisionFailed
+}
+
+// expirableOffers drops offers whose instances can't be handled as action on expiry
+func expirableOffers(offers []types.GPUInstance, action types.ExpiryAction) []types.GPUInstance {
+	kept := make([]types.GPUInstance, 0, len(offers))
+	for _, offer := range offers {
+		if checkExpiryAction(offer.Provider, action) == nil {
+			kept = append(kept, offer)
+		}
+	}
+	return kept
+}
+
+// provisionCreateRequest builds the create request for renting an offer
+func provisionCreateRequest(req *types.ProvisionRequest, offer types.GPUInstance) *types.CreateInstanceRequest {
+	return &types.CreateInstanceRequest{
+		Provider:      offer.Provider,
+		OfferID:       offer.ProviderID,
+		Image:         req.Image,
+		OnStartScript: req.OnStartScript,
+		SSHKey:        req.SSHKey,
+		Label:         req.Label,
+		Environment:   req.Environment,
+		Ports:         req.Ports,
+		Resources:     req.Resources,
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestProvisionOffersTriesInOrder(t *testing.T) {
+	offers := []types.GPUInstance{
+		{ID: "vast_1", Provider: types.VastAI, ProviderID: "1", PricePerHour: 0.5},
+		{ID: "lambda_labs_x", Provider: types.LambdaLabs, ProviderID: "x", Status: types.StatusUnavailable},
+		{ID: "runpod_2", Provider: types.RunPod, ProviderID: "2", PricePerHour: 0.6},
+		{ID: "vast_3", Provider: types.VastAI, ProviderID: "3", PricePerHour: 0.7},
+	}
+
+	var tried []string
+	launch := func(ctx context.Context, offer types.GPUInstance) (*types.GPUInstance, error) {
+		tried = append(tried, offer.ID)
+		if offer.ID == "vast_1" {
+			return nil, errors.New("offer no longer available")
+		}
+		return &types.GPUInstance{ID: "runpod_pod1"}, nil
+	}
+
+	result, err := provisionOffers(context.Background(), offers, 3, launch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Instance == nil || result.Instance.ID != "runpod_pod1" {
+		t.Errorf("expected the runpod instance, got %+v", result.Instance)
+	}
+	if len(tried) != 2 || tried[0] != "vast_1" || tried[1] != "runpod_2" {
+		t.Errorf("expected unavailable offers to be skipped, tried %v", tried)
+	}
+	if len(result.Attempts) != 2 || result.Attempts[0].Error != "offer no longer available" || result.Attempts[1].Error != "" {
+		t.Errorf("unexpected attempts %+v", result.Attempts)
+	}
+}
+
+func TestProvisionOffersGivesUp(t *testing.T) {
+	offers := []types.GPUInstance{{ID: "vast_1"}, {ID: "vast_2"}, {ID: "vast_3"}}
+	refuse := func(ctx context.Context, offer types.GPUInstance) (*types.GPUInstance, error) {
+		return nil, errors.New("refused")
+	}
+
+	result, err := provisionOffers(context.Background(), offers, 2, refuse)
+	if !errors.Is(err, ErrProvisionFailed) || len(result.Attempts) != 2 {
+		t.Errorf("expected 2 failed attempts, got %+v, %v", result.Attempts, err)
+	}
+
+	if _, err := provisionOffers(context.Background(), nil, 2, refuse); !errors.Is(err, ErrNoMatchingOffers) {
+		t.Errorf("expected no matching offers, got %v", err)
+	}
+}
+
+func TestProvisionOffersHaltsOnAmbiguousFailure(t *testing.T) {
+	offers := []types.GPUInstance{{ID: "vast_1"}, {ID: "vast_2"}}
+	calls := 0
+	launch := func(ctx context.Context, offer types.GPUInstance) (*types.GPUInstance, error) {
+		calls++
+		return nil, haltError{errors.New("timed out")}
+	}
+
+	result, err := provisionOffers(context.Background(), offers, 3, launch)
+	if !errors.Is(err, ErrProvisionFailed) || calls != 1 || len(result.Attempts) != 1 {
+		t.Errorf("expected provisioning to stop after the first attempt, got %d calls, %v", calls, err)
+	}
+}
+
+func TestProvisionOffersHaltsOnTransportFailure(t *testing.T) {
+	offers := []types.GPUInstance{{ID: "vast_1"}, {ID: "runpod_2"}}
+
+	failures := map[string]error{
+		"connection reset": apperr.Wrap(apperr.Unavailable, errors.New("error making request: connection reset by peer")),
+		"provider error":   apperr.Wrap(apperr.Upstream, errors.New("API error 500")),
+		"unclassified":     errors.New("unexpected EOF"),
+	}
+	for name, failure := range failures {
+		calls := 0
+		launch := func(ctx context.Context, offer types.GPUInstance) (*types.GPUInstance, error) {
+			calls++
+			return nil, launchFailure(ctx, "Vast.ai", failure)
+		}
+
+		result, err := provisionOffers(context.Background(), offers, 3, launch)
+		if !errors.Is(err, ErrProvisionFailed) || calls != 1 || len(result.Attempts) != 1 {
+			t.Errorf("%s: expected the second offer not to be tried, got %d calls, %v", name, calls, err)
+		}
+	}
+}
+
+func TestLaunchFailureMovesOnAfterRefusals(t *testing.T) {
+	refusals := []error{
+		apperr.Errorf(apperr.Conflict, "offer 1 was not accepted"),
+		apperr.Errorf(apperr.InvalidArgument, "invalid offer ID"),
+		apperr.Wrap(apperr.NotFound, errors.New("API error 404")),
+	}
+	for _, refusal := range refusals {
+		var halt haltError
+		if err := launchFailure(context.Background(), "Vast.ai", refusal); errors.As(err, &halt) || err != refusal {
+			t.Errorf("expected %v to let the next offer be tried, got %v", refusal, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var halt haltError
+	if err := launchFailure(ctx, "Vast.ai", apperr.Errorf(apperr.Conflict, "offer 1 was not accepted")); !errors.As(err, &halt) {
+		t.Errorf("expected a failure after the deadline to halt, got %v", err)
+	}
+}
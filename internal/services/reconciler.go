@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gpu-cloud-manager/internal/config"
+	"gpu-cloud-manager/internal/metrics"
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// Reconciler periodically syncs provider state into the instances table
+type Reconciler struct {
+	db       *gorm.DB
+	pool     *ProviderPool
+	interval time.Duration
+	timeout  time.Duration // Upstream deadline for listing one account's instances
+	metrics  *metrics.Metrics
+}
+
+// NewReconciler creates a reconciler that uses each user's provider credentials
+func NewReconciler(db *gorm.DB, cfg *config.Config, pool *ProviderPool, m *metrics.Metrics) *Reconciler {
+	return &Reconciler{
+		db:       db,
+		pool:     pool,
+		interval: cfg.ReconcileInterval,
+		timeout:  cfg.UpstreamTimeout,
+		metrics:  m,
+	}
+}
+
+// Run reconciles on every interval until ctx is cancelled
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		r.ReconcileOnce(ctx)
+		r.metrics.ObserveJob("reconcile", time.Since(start))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// credentialGroup is one provider account and the users whose instances live on it
+type credentialGroup struct {
+	provider providers.Provider
+	userIDs  []uint
+}
+
+// ReconcileOnce syncs every provider account once. An account that fails to list
+// its instances is skipped so an outage never marks its instances as missing.
+func (r *Reconciler) ReconcileOnce(ctx context.Context) {
+	groups, skippedUsers, err := r.credentialGroups(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Reconciler: error loading provider accounts", "error", err)
+		return
+	}
+
+	now := time.Now()
+	failed := make(map[types.GPUProvider]bool)
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := r.reconcileGroup(ctx, group, now); err != nil {
+			slog.ErrorContext(ctx, "Reconciler: error reconciling provider account", "provider", group.provider.Name(), "error", err)
+			failed[group.provider.Name()] = true
+		}
+	}
+
+	// Orphans not seen in this pass were destroyed or adopted, unless we couldn't look.
+	// Accounts of skipped users weren't listed, and could be on any provider.
+	for _, d := range providers.Catalog {
+		if failed[d.Name] || skippedUsers {
+			continue
+		}
+		err := r.db.WithContext(ctx).Where("provider = ? AND last_seen_at < ?", d.Name, now).Delete(&models.OrphanInstance{}).Error
+		if err != nil {
+			slog.ErrorContext(ctx, "Reconciler: error pruning orphans", "provider", d.Name, "error", err)
+		}
+	}
+}
+
+// credentialGroups groups users that own instances or have credentials by the
+// provider API key in effect for them, so each account is listed once per pass.
+// Users whose credentials can't be loaded are left out, which skippedUsers reports.
+func (r *Reconciler) credentialGroups(ctx context.Context) (result []credentialGroup, skippedUsers bool, err error) {
+	var instanceOwners, credentialOwners []uint
+	if err := r.db.WithContext(ctx).Model(&models.Instance{}).Distinct().Pluck("user_id", &instanceOwners).Error; err != nil {
+		return nil, false, fmt.Errorf("error loading instance owners: %v", err)
+	}
+	err = r.db.WithContext(ctx).Model(&models.UserProvider{}).Where("is_enabled = ?", true).Distinct().Pluck("user_id", &credentialOwners).Error
+	if err != nil {
+		return nil, false, fmt.Errorf("error loading credential owners: %v", err)
+	}
+
+	type accountKey struct {
+		provider types.GPUProvider
+		apiKey   string
+	}
+
+	var groups []*credentialGroup
+	byAccount := make(map[accountKey]*credentialGroup)
+	seenUsers := make(map[uint]bool)
+
+	for _, userID := range append(instanceOwners, credentialOwners...) {
+		if seenUsers[userID] {
+			continue
+		}
+		seenUsers[userID] = true
+
+		// One user's unreadable credentials shouldn't stop everyone else's instances being reconciled
+		keys, err := r.pool.KeysForUser(ctx, userID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Reconciler: skipping user whose credentials can't be loaded", "user_id", userID, "error", err)
+			skippedUsers = true
+			continue
+		}
+
+		for _, d := range providers.Catalog {
+			apiKey := keys[d.Name]
+			if apiKey == "" {
+				continue
+			}
+
+			account := accountKey{provider: d.Name, apiKey: apiKey}
+			group, ok := byAccount[account]
+			if !ok {
+				group = &credentialGroup{provider: r.pool.newProvider(d, apiKey)}
+				byAccount[account] = group
+				groups = append(groups, group)
+			}
+			group.userIDs = append(group.userIDs, userID)
+		}
+	}
+
+	result = make([]credentialGroup, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, *group)
+	}
+	return result, skippedUsers, nil
+}
+
+// reconcileGroup diffs one provider account's live instances against stored rows
+func (r *Reconciler) reconcileGroup(ctx context.Context, group credentialGroup, now time.Time) error {
+	p := group.provider
+
+	listCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	live, err := p.ListInstances(listCtx)
+	if err != nil {
+		return fmt.Errorf("error listing instances: %v", err)
+	}
+
+	// Every row for the provider is loaded, including destroyed ones, so instances
+	// owned by other accounts or lagging behind a destroy aren't reported as orphans
+	var rows []models.Instance
+	err = r.db.WithContext(ctx).Unscoped().Where("provider = ?", p.Name()).Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("error loading instances: %v", err)
+	}
+
+	// Newly created instances can take a moment to show up in provider listings
+	plan := planReconciliation(rows, group.userIDs, live, now.Add(-r.interval))
+
+	for _, update := range plan.updates {
+		if err := r.applyUpdate(ctx, update.row, update.live, now); err != nil {
+			slog.ErrorContext(ctx, "Reconciler: error updating instance", "instance_id", update.row.ToGPUInstance().ID, "error", err)
+		}
+	}
+
+	for _, row := range plan.missing {
+		if err := r.markMissing(ctx, row, now); err != nil {
+			slog.ErrorContext(ctx, "Reconciler: error flagging missing instance", "instance_id", row.ToGPUInstance().ID, "error", err)
+		}
+	}
+
+	return r.recordOrphans(ctx, p.Name(), plan.orphans, now)
+}
+
+// applyUpdate copies live provider state onto a stored instance
+func (r *Reconciler) applyUpdate(ctx context.Context, row *models.Instance, live types.GPUInstance, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"price_per_hour": live.PricePerHour,
+			"last_synced_at": now,
+			"missing_since":  nil,
+		}
+		if live.SSH != nil {
+			updates["ssh_host"] = live.SSH.Host
+			updates["ssh_port"] = live.SSH.Port
+		}
+		if live.ProviderData != nil {
+			updates["provider_data"] = models.ProviderData(live.ProviderData)
+		}
+		if err := tx.Model(row).Updates(updates).Error; err != nil {
+			return err
+		}
+		row.PricePerHour = live.PricePerHour
+
+		if live.Utilization != nil && live.Status == types.StatusRunning {
+			sample := models.UtilizationSample{
+				InstanceID:    row.ID,
+				GPUPercent:    live.Utilization.GPUPercent,
+				MemoryPercent: live.Utilization.MemoryPercent,
+				SampledAt:     now,
+			}
+			if err := tx.Create(&sample).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := transitionStatus(tx, row, live.Status, transitionSourceReconciler, now); err != nil {
+			return err
+		}
+
+		// Also picks up price changes, and instances recorded before costs were tracked
+		return syncCostSegment(tx, row, now)
+	})
+}
+
+// markMissing flags an instance whose provider instance disappeared
+func (r *Reconciler) markMissing(ctx context.Context, row *models.Instance, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(row).Update("missing_since", now).Error; err != nil {
+			return err
+		}
+		return transitionStatus(tx, row, types.StatusTerminated, transitionSourceReconciler, now)
+	})
+}
+
+// recordOrphans upserts instances unknown to us
+func (r *Reconciler) recordOrphans(ctx context.Context, provider types.GPUProvider, orphans []types.GPUInstance, now time.Time) error {
+	for _, live := range orphans {
+		var orphan models.OrphanInstance
+		err := r.db.WithContext(ctx).Where(models.OrphanInstance{Provider: provider, ProviderID: live.ProviderID}).
+			Attrs(models.OrphanInstance{FirstSeenAt: now}).
+			FirstOrInit(&orphan).Error
+		if err != nil {
+			return fmt.Errorf("error loading orphan %s: %v", live.ProviderID, err)
+		}
+
+		if orphan.ID == 0 {
+			slog.WarnContext(ctx, "Reconciler: found orphaned instance", "provider", provider, "provider_id", live.ProviderID)
+		}
+
+		orphan.Name = live.Name
+		orphan.Status = live.Status
+		orphan.GPUModel = live.GPUModel
+		orphan.PricePerHour = live.PricePerHour
+		orphan.LastSeenAt = now
+		if err := r.db.WithContext(ctx).Save(&orphan).Error; err != nil {
+			return fmt.Errorf("error saving orphan %s: %v", live.ProviderID, err)
+		}
+	}
+
+	return nil
+}
+
+// reconcileUpdate pairs a stored instance with its live provider state
+type reconcileUpdate struct {
+	row  *models.Instance
+	live types.GPUInstance
+}
+
+// reconcilePlan is the diff between stored rows and live provider instances
+type reconcilePlan struct {
+	updates []reconcileUpdate
+	missing []*models.Instance
+	orphans []types.GPUInstance
+}
+
+// planReconciliation matches rows to live instances by provider ID. Only rows owned
+// by owners are updated or reported missing; destroyed rows and rows of other users
+// are just used to recognise instances. Rows created after createdBefore are never
+// reported missing.
+func planReconciliation(rows []models.Instance, owners []uint, live []types.GPUInstance, createdBefore time.Time) reconcilePlan {
+	var plan reconcilePlan
+
+	owned := make(map[uint]bool, len(owners))
+	for _, userID := range owners {
+		owned[userID] = true
+	}
+
+	liveByID := make(map[string]types.GPUInstance, len(live))
+	for _, instance := range live {
+		liveByID[instance.ProviderID] = instance
+	}
+
+	known := make(map[string]bool, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		known[row.ProviderID] = true
+
+		if row.DeletedAt.Valid || !owned[row.UserID] {
+			continue
+		}
+
+		instance, found := liveByID[row.ProviderID]
+		switch {
+		case found && (row.Status != types.StatusTerminated || row.MissingSince != nil):
+			// Rows flagged missing come back if the provider reports them again
+			plan.updates = append(plan.updates, reconcileUpdate{row: row, live: instance})
+		case !found && row.Status != types.StatusTerminated && row.CreatedAt.Before(createdBefore):
+			plan.missing = append(plan.missing, row)
+		}
+	}
+
+	for _, instance := range live {
+		if !known[instance.ProviderID] {
+			plan.orphans = append(plan.orphans, instance)
+		}
+	}
+
+	return plan
+}
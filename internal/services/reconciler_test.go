@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+func TestPlanReconciliation(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-time.Hour)
+	missingSince := now.Add(-10 * time.Minute)
+
+	rows := []models.Instance{
+		{ID: 1, UserID: 1, ProviderID: "100", Status: types.StatusRunning, CreatedAt: old},
+		{ID: 2, UserID: 1, ProviderID: "200", Status: types.StatusRunning, CreatedAt: old},
+		{ID: 3, UserID: 1, ProviderID: "300", Status: types.StatusStarting, CreatedAt: now},
+		{ID: 4, UserID: 1, ProviderID: "400", Status: types.StatusTerminated, CreatedAt: old, DeletedAt: gorm.DeletedAt{Time: old, Valid: true}},
+		{ID: 5, UserID: 1, ProviderID: "500", Status: types.StatusTerminated, CreatedAt: old, MissingSince: &missingSince},
+		{ID: 6, UserID: 1, ProviderID: "600", Status: types.StatusTerminated, CreatedAt: old},
+		{ID: 7, UserID: 2, ProviderID: "700", Status: types.StatusRunning, CreatedAt: old},
+		{ID: 8, UserID: 2, ProviderID: "800", Status: types.StatusRunning, CreatedAt: old},
+	}
+	live := []types.GPUInstance{
+		{ProviderID: "100", Status: types.StatusOffline},
+		{ProviderID: "400", Status: types.StatusRunning},
+		{ProviderID: "500", Status: types.StatusRunning},
+		{ProviderID: "700", Status: types.StatusRunning},
+		{ProviderID: "900", Status: types.StatusRunning},
+	}
+
+	plan := planReconciliation(rows, []uint{1}, live, now.Add(-time.Minute))
+
+	if len(plan.updates) != 2 || plan.updates[0].row.ID != 1 || plan.updates[1].row.ID != 5 {
+		t.Errorf("expected updates for instances 1 and 5, got %+v", plan.updates)
+	}
+	if plan.updates[0].live.Status != types.StatusOffline {
+		t.Errorf("expected live status offline, got %s", plan.updates[0].live.Status)
+	}
+
+	// Instance 3 is too new to be considered missing, 6 is already terminated and 8 belongs to another account
+	if len(plan.missing) != 1 || plan.missing[0].ID != 2 {
+		t.Errorf("expected only instance 2 missing, got %+v", plan.missing)
+	}
+
+	// Instance 400 was destroyed through the API and 700 belongs to another user, so only 900 is an orphan
+	if len(plan.orphans) != 1 || plan.orphans[0].ProviderID != "900" {
+		t.Errorf("expected only 900 orphaned, got %+v", plan.orphans)
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidReportQuery is returned when a spend report query is rejected
+var ErrInvalidReportQuery = apperr.New(apperr.InvalidArgument, "invalid report query")
+
+// Spend report groupings
+const (
+	SpendByUser     = "user"
+	SpendByProvider = "provider"
+	SpendByGPUModel = "gpu_model"
+	SpendByLabel    = "label"
+)
+
+// SpendQuery selects the spend to report
+type SpendQuery struct {
+	GroupBy string
+	From    time.Time // Defaults to the start of the current month (UTC)
+	To      time.Time // Defaults to now
+}
+
+// ReportService reports spend from the cost segments recorded for instances
+type ReportService struct {
+	db *gorm.DB
+}
+
+// NewReportService creates a new report service
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{
+		db: db,
+	}
+}
+
+// Spend reports what was spent on instances between query.From and query.To. Admins
+// see every user's instances, other users only their own.
+func (s *ReportService) Spend(ctx context.Context, user *models.User, query SpendQuery) (*types.SpendReport, error) {
+	now := time.Now()
+
+	if query.GroupBy == "" {
+		query.GroupBy = SpendByUser
+	}
+	switch query.GroupBy {
+	case SpendByUser, SpendByProvider, SpendByGPUModel, SpendByLabel:
+	default:
+		return nil, fmt.Errorf("%w: group_by must be user, provider, gpu_model or label", ErrInvalidReportQuery)
+	}
+	if query.From.IsZero() {
+		year, month, _ := now.UTC().Date()
+		query.From = time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if query.To.IsZero() || query.To.After(now) {
+		query.To = now
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidReportQuery)
+	}
+
+	db := s.db.WithContext(ctx).Where("started_at < ? AND (ended_at IS NULL OR ended_at > ?)", query.To, query.From)
+	if !user.IsAdmin {
+		db = db.Where("user_id = ?", user.ID)
+	}
+
+	var segments []models.CostSegment
+	if err := db.Find(&segments).Error; err != nil {
+		return nil, fmt.Errorf("error loading cost segments: %v", err)
+	}
+
+	keyOf, err := s.spendKey(ctx, query.GroupBy, segments)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, total := aggregateSpend(segments, keyOf, query.From, query.To)
+	return &types.SpendReport{
+		GroupBy:   query.GroupBy,
+		From:      query.From,
+		To:        query.To,
+		TotalCost: roundCents(total),
+		Groups:    groups,
+	}, nil
+}
+
+// spendKey returns the function naming the group a segment belongs to
+func (s *ReportService) spendKey(ctx context.Context, groupBy string, segments []models.CostSegment) (func(models.CostSegment) string, error) {
+	switch groupBy {
+	case SpendByProvider:
+		return func(segment models.CostSegment) string { return string(segment.Provider) }, nil
+	case SpendByGPUModel:
+		return func(segment models.CostSegment) string { return segment.GPUModel }, nil
+	case SpendByLabel:
+		return func(segment models.CostSegment) string { return segment.Label }, nil
+	}
+
+	// Users are named by email, including users that have since been deleted
+	seen := make(map[uint]bool)
+	var userIDs []uint
+	for _, segment := range segments {
+		if !seen[segment.UserID] {
+			seen[segment.UserID] = true
+			userIDs = append(userIDs, segment.UserID)
+		}
+	}
+
+	emails := make(map[uint]string, len(userIDs))
+	if len(userIDs) > 0 {
+		var users []models.User
+		if err := s.db.WithContext(ctx).Unscoped().Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("error loading users: %v", err)
+		}
+		for _, user := range users {
+			emails[user.ID] = user.Email
+		}
+	}
+
+	return func(segment models.CostSegment) string {
+		if email, ok := emails[segment.UserID]; ok {
+			return email
+		}
+		return fmt.Sprintf("user %d", segment.UserID)
+	}, nil
+}
+
+// aggregateSpend totals the parts of segments between from and to by group, most
+// expensive group first, and returns the overall cost
+func aggregateSpend(segments []models.CostSegment, keyOf func(models.CostSegment) string, from, to time.Time) ([]types.SpendGroup, float64) {
+	type totals struct {
+		cost, running, stopped float64
+		instances              map[uint]bool
+	}
+
+	byKey := make(map[string]*totals)
+	var total float64
+	for _, segment := range segments {
+		overlap := segmentOverlap(segment, from, to)
+		if overlap <= 0 {
+			continue
+		}
+
+		key := keyOf(segment)
+		group, ok := byKey[key]
+		if !ok {
+			group = &totals{instances: make(map[uint]bool)}
+			byKey[key] = group
+		}
+
+		group.instances[segment.InstanceID] = true
+		if segment.State == models.BillingRunning {
+			cost := segmentCost(segment, from, to)
+			group.cost += cost
+			group.running += overlap.Hours()
+			total += cost
+		} else {
+			group.stopped += overlap.Hours()
+		}
+	}
+
+	groups := make([]types.SpendGroup, 0, len(byKey))
+	for key, group := range byKey {
+		groups = append(groups, types.SpendGroup{
+			Key:          key,
+			Cost:         roundCents(group.cost),
+			RunningHours: roundCents(group.running),
+			StoppedHours: roundCents(group.stopped),
+			Instances:    len(group.instances),
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Cost != groups[j].Cost {
+			return groups[i].Cost > groups[j].Cost
+		}
+		return groups[i].Key < groups[j].Key
+	})
+
+	return groups, total
+}
+
+// roundCents rounds to two decimal places
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/models"
+	"gpu-cloud-manager/pkg/types"
+)
+
+func TestAggregateSpend(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	at := func(hours float64) *time.Time {
+		t := from.Add(time.Duration(hours * float64(time.Hour)))
+		return &t
+	}
+
+	segments := []models.CostSegment{
+		// Started before the period: only the 2 hours inside it count
+		{InstanceID: 1, Label: "training", State: models.BillingRunning, PricePerHour: 2.0, StartedAt: from.Add(-3 * time.Hour), EndedAt: at(2)},
+		{InstanceID: 1, Label: "training", State: models.BillingStopped, StartedAt: *at(2), EndedAt: at(10)},
+		// Price change splits the segment
+		{InstanceID: 1, Label: "training", State: models.BillingRunning, PricePerHour: 2.5, StartedAt: *at(10), EndedAt: at(12)},
+		// Still open: runs until the end of the period
+		{InstanceID: 2, Label: "inference", State: models.BillingRunning, PricePerHour: 0.5, StartedAt: *at(20)},
+		// Ended before the period
+		{InstanceID: 3, Label: "inference", State: models.BillingRunning, PricePerHour: 9.0, StartedAt: from.Add(-5 * time.Hour), EndedAt: at(-1)},
+	}
+
+	groups, total := aggregateSpend(segments, func(segment models.CostSegment) string { return segment.Label }, from, to)
+	if total != 11.0 {
+		t.Errorf("expected a total of 11, got %f", total)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+
+	expected := []types.SpendGroup{
+		{Key: "training", Cost: 9.0, RunningHours: 4, StoppedHours: 8, Instances: 1},
+		{Key: "inference", Cost: 2.0, RunningHours: 4, Instances: 1},
+	}
+	for i := range expected {
+		if groups[i] != expected[i] {
+			t.Errorf("expected group %d to be %+v, got %+v", i, expected[i], groups[i])
+		}
+	}
+}
+
+func TestBillingState(t *testing.T) {
+	states := map[types.InstanceStatus]string{
+		types.StatusRunning:    models.BillingRunning,
+		types.StatusStarting:   models.BillingRunning,
+		types.StatusStopping:   models.BillingRunning,
+		types.StatusOffline:    models.BillingStopped,
+		types.StatusTerminated: "",
+		types.StatusError:      "",
+	}
+	for status, expected := range states {
+		if got := billingState(status); got != expected {
+			t.Errorf("expected %s to bill as %q, got %q", status, expected, got)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// ErrAllProvidersFailed is returned when no queried provider answered an offer search
+var ErrAllProvidersFailed = apperr.New(apperr.Upstream, "no provider returned offers")
+
+// providerSearchResult is one provider's answer to a fan-out search
+type providerSearchResult struct {
+	offers []types.GPUInstance
+	status types.ProviderSearchStatus
+}
+
+// fanOutSearch queries every provider concurrently, each under its own timeout, and
+// merges the offers as they arrive. A failing provider only contributes its status.
+// Providers are read through cache when one is given.
+func fanOutSearch(ctx context.Context, cache *OfferCache, targets []providers.Provider, filter *types.AdvancedSearchFilter, timeout time.Duration) ([]types.GPUInstance, []types.ProviderSearchStatus) {
+	results := make(chan providerSearchResult, len(targets))
+
+	for _, p := range targets {
+		go func(p providers.Provider) {
+			results <- searchProvider(ctx, cache, p, filter, timeout)
+		}(p)
+	}
+
+	var offers []types.GPUInstance
+	statuses := make([]types.ProviderSearchStatus, 0, len(targets))
+	for range targets {
+		result := <-results
+		offers = append(offers, result.offers...)
+		statuses = append(statuses, result.status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Provider < statuses[j].Provider
+	})
+
+	return offers, statuses
+}
+
+// searchProvider queries a single provider and reports how it went
+func searchProvider(ctx context.Context, cache *OfferCache, p providers.Provider, filter *types.AdvancedSearchFilter, timeout time.Duration) providerSearchResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	var offers []types.GPUInstance
+	var fetchedAt time.Time
+	var err error
+	if cache != nil {
+		offers, fetchedAt, err = cache.Search(ctx, p, filter)
+	} else {
+		offers, err = p.SearchOffers(ctx, filter)
+		fetchedAt = time.Now()
+	}
+
+	status := types.ProviderSearchStatus{
+		Provider:  p.Name(),
+		Status:    types.ProviderQueryOK,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Count:     len(offers),
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		status.Status = types.ProviderQueryTimeout
+		status.Error = "timed out after " + timeout.String()
+		status.Count = 0
+		offers = nil
+	case err != nil:
+		status.Status = types.ProviderQueryError
+		status.Error = err.Error()
+		status.Count = 0
+		offers = nil
+	default:
+		// Offers fetched before this search started came out of the cache
+		status.Cached = fetchedAt.Before(start)
+		status.AgeSeconds = int64(time.Since(fetchedAt).Seconds())
+	}
+
+	return providerSearchResult{offers: offers, status: status}
+}
+
+// DataAge returns the age of the oldest offers among the providers that answered
+func DataAge(statuses []types.ProviderSearchStatus) time.Duration {
+	var age time.Duration
+	for _, status := range statuses {
+		if status.Status != types.ProviderQueryOK {
+			continue
+		}
+		if providerAge := time.Duration(status.AgeSeconds) * time.Second; providerAge > age {
+			age = providerAge
+		}
+	}
+	return age
+}
+
+// allFailed reports whether providers were queried and none of them answered
+func allFailed(statuses []types.ProviderSearchStatus) bool {
+	for _, status := range statuses {
+		if status.Status == types.ProviderQueryOK {
+			return false
+		}
+	}
+	return len(statuses) > 0
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/internal/providers"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// stubProvider answers offer searches with fixed offers, an error, or by blocking
+type stubProvider struct {
+	name   types.GPUProvider
+	offers []types.GPUInstance
+	err    error
+	block  bool
+}
+
+func (p *stubProvider) Name() types.GPUProvider              { return p.name }
+func (p *stubProvider) Capabilities() providers.Capabilities { return providers.Capabilities{} }
+
+func (p *stubProvider) SearchOffers(ctx context.Context, filter *types.AdvancedSearchFilter) ([]types.GPUInstance, error) {
+	if p.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return p.offers, p.err
+}
+
+func (p *stubProvider) GetOffer(ctx context.Context, offerID string) (*types.GPUInstance, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) ListInstances(ctx context.Context) ([]types.GPUInstance, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) GetInstance(ctx context.Context, providerID string) (*types.GPUInstance, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) CreateInstance(ctx context.Context, req *types.CreateInstanceRequest) (*types.GPUInstance, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) StartInstance(ctx context.Context, providerID string) error   { return nil }
+func (p *stubProvider) StopInstance(ctx context.Context, providerID string) error    { return nil }
+func (p *stubProvider) DestroyInstance(ctx context.Context, providerID string) error { return nil }
+
+func TestFanOutSearchReturnsPartialResults(t *testing.T) {
+	targets := []providers.Provider{
+		&stubProvider{name: types.VastAI, offers: []types.GPUInstance{{ID: "vast_1"}, {ID: "vast_2"}}},
+		&stubProvider{name: types.RunPod, err: errors.New("503 service unavailable")},
+		&stubProvider{name: types.LambdaLabs, block: true},
+	}
+
+	start := time.Now()
+	offers, statuses := fanOutSearch(context.Background(), nil, targets, &types.AdvancedSearchFilter{}, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the slow provider to be cut off, search took %s", elapsed)
+	}
+
+	if len(offers) != 2 {
+		t.Errorf("expected 2 offers from the healthy provider, got %d", len(offers))
+	}
+
+	expected := map[types.GPUProvider]types.ProviderQueryStatus{
+		types.VastAI:     types.ProviderQueryOK,
+		types.RunPod:     types.ProviderQueryError,
+		types.LambdaLabs: types.ProviderQueryTimeout,
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %d statuses, got %d", len(expected), len(statuses))
+	}
+	for i, status := range statuses {
+		if i > 0 && statuses[i-1].Provider > status.Provider {
+			t.Errorf("expected statuses ordered by provider, got %v", statuses)
+		}
+		if status.Status != expected[status.Provider] {
+			t.Errorf("expected %s to be %s, got %s", status.Provider, expected[status.Provider], status.Status)
+		}
+		if status.Status != types.ProviderQueryOK && status.Error == "" {
+			t.Errorf("expected an error message for %s", status.Provider)
+		}
+	}
+
+	if allFailed(statuses) {
+		t.Errorf("expected a partial result not to count as all failed")
+	}
+}
+
+func TestAllFailed(t *testing.T) {
+	if allFailed(nil) {
+		t.Errorf("expected no providers queried not to count as all failed")
+	}
+
+	statuses := []types.ProviderSearchStatus{
+		{Provider: types.VastAI, Status: types.ProviderQueryTimeout},
+		{Provider: types.RunPod, Status: types.ProviderQueryError},
+	}
+	if !allFailed(statuses) {
+		t.Errorf("expected all failed")
+	}
+}
@@ -0,0 +1,126 @@
+// Package apperr classifies errors by kind so the API can answer each with the right
+// HTTP status and a machine-readable code.
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind is the class of an error. Its value is the code reported to API clients.
+type Kind string
+
+const (
+	InvalidArgument      Kind = "invalid_argument"      // The request is malformed or asks for something impossible
+	Unauthenticated      Kind = "unauthenticated"       // The caller's API key was missing or rejected
+	PermissionDenied     Kind = "permission_denied"     // The caller may not do this, e.g. over budget
+	NotFound             Kind = "not_found"             // The resource doesn't exist
+	Conflict             Kind = "conflict"              // The request clashes with the resource's current state
+	RateLimited          Kind = "rate_limited"          // Too many requests, to this API or by it to a provider
+	Internal             Kind = "internal"              // A failure of this service
+	ProviderUnauthorized Kind = "provider_unauthorized" // A provider rejected the stored API key
+	Upstream             Kind = "upstream_error"        // A provider failed or answered unexpectedly
+	Unavailable          Kind = "unavailable"           // A provider couldn't be reached or is temporarily down
+)
+
+// HTTPStatus returns the status code API responses use for errors of kind k
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case InvalidArgument:
+		return http.StatusBadRequest
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case PermissionDenied:
+		return http.StatusForbidden
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case RateLimited:
+		return http.StatusTooManyRequests
+	case ProviderUnauthorized, Upstream:
+		return http.StatusBadGateway
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is an error of a known kind
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error // The underlying error, if any
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	default:
+		return e.Message + ": " + e.Err.Error()
+	}
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error of kind with message. Sentinel errors created with New keep
+// working with errors.Is.
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Errorf creates an error of kind with a formatted message
+func Errorf(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap classifies err as kind, keeping its message. It returns nil for a nil err.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of the outermost *Error in err's chain. Deadlines that
+// passed while waiting on a provider count as Unavailable and anything unclassified
+// as Internal.
+func KindOf(err error) Kind {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, context.DeadlineExceeded):
+		return Unavailable
+	default:
+		return Internal
+	}
+}
+
+// FromHTTPStatus returns the kind of an error response a provider answered with status
+func FromHTTPStatus(status int) Kind {
+	switch {
+	case status == http.StatusBadRequest, status == http.StatusUnprocessableEntity:
+		return InvalidArgument
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return ProviderUnauthorized
+	case status == http.StatusNotFound, status == http.StatusGone:
+		return NotFound
+	case status == http.StatusConflict:
+		return Conflict
+	case status == http.StatusTooManyRequests:
+		return RateLimited
+	case status == http.StatusBadGateway, status == http.StatusServiceUnavailable, status == http.StatusGatewayTimeout:
+		return Unavailable
+	default:
+		return Upstream
+	}
+}
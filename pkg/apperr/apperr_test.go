@@ -0,0 +1,89 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	sentinel := New(NotFound, "instance not found")
+	tests := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{"sentinel", sentinel, NotFound},
+		{"wrapped sentinel", fmt.Errorf("error stopping instance: %w", sentinel), NotFound},
+		{"outermost kind wins", Wrap(InvalidArgument, sentinel), InvalidArgument},
+		{"deadline", fmt.Errorf("error listing instances: %w", context.DeadlineExceeded), Unavailable},
+		{"unclassified", errors.New("database is locked"), Internal},
+	}
+	for _, tt := range tests {
+		if got := KindOf(tt.err); got != tt.expected {
+			t.Errorf("%s: Expected kind %s, got %s", tt.name, tt.expected, got)
+		}
+	}
+
+	if !errors.Is(fmt.Errorf("lookup: %w", sentinel), sentinel) {
+		t.Error("Expected wrapped sentinels to match with errors.Is")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if Wrap(NotFound, nil) != nil {
+		t.Error("Expected wrapping nil to return nil")
+	}
+
+	cause := errors.New("connection refused")
+	err := Wrap(Unavailable, cause)
+	if err.Error() != "connection refused" {
+		t.Errorf("Expected the wrapped message to be kept, got %q", err.Error())
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected the cause to be unwrappable")
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := map[Kind]int{
+		InvalidArgument:      http.StatusBadRequest,
+		Unauthenticated:      http.StatusUnauthorized,
+		PermissionDenied:     http.StatusForbidden,
+		NotFound:             http.StatusNotFound,
+		Conflict:             http.StatusConflict,
+		RateLimited:          http.StatusTooManyRequests,
+		Internal:             http.StatusInternalServerError,
+		ProviderUnauthorized: http.StatusBadGateway,
+		Upstream:             http.StatusBadGateway,
+		Unavailable:          http.StatusServiceUnavailable,
+		Kind("unknown"):      http.StatusInternalServerError,
+	}
+	for kind, expected := range tests {
+		if got := kind.HTTPStatus(); got != expected {
+			t.Errorf("Expected status %d for %s, got %d", expected, kind, got)
+		}
+	}
+}
+
+func TestFromHTTPStatus(t *testing.T) {
+	tests := map[int]Kind{
+		http.StatusBadRequest:          InvalidArgument,
+		http.StatusUnprocessableEntity: InvalidArgument,
+		http.StatusUnauthorized:        ProviderUnauthorized,
+		http.StatusForbidden:           ProviderUnauthorized,
+		http.StatusNotFound:            NotFound,
+		http.StatusConflict:            Conflict,
+		http.StatusTooManyRequests:     RateLimited,
+		http.StatusInternalServerError: Upstream,
+		http.StatusServiceUnavailable:  Unavailable,
+		http.StatusGatewayTimeout:      Unavailable,
+	}
+	for status, expected := range tests {
+		if got := FromHTTPStatus(status); got != expected {
+			t.Errorf("Expected kind %s for status %d, got %s", expected, status, got)
+		}
+	}
+}
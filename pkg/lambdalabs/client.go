@@ -0,0 +1,362 @@
+package lambdalabs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/logging"
+	"gpu-cloud-manager/pkg/types"
+)
+
+const (
+	BaseURL = "https://cloud.lambdalabs.com/api/v1"
+)
+
+// Client represents a Lambda Cloud API client
+type Client struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient creates a new Lambda Cloud API client
+func NewClient(apiKey string) *Client {
+	return &Client{
+		apiKey:  apiKey,
+		baseURL: BaseURL,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// InstanceType describes a Lambda Cloud machine configuration
+type InstanceType struct {
+	Name              string            `json:"name"`
+	Description       string            `json:"description"`
+	GPUDescription    string            `json:"gpu_description"`
+	PriceCentsPerHour int               `json:"price_cents_per_hour"`
+	Specs             InstanceTypeSpecs `json:"specs"`
+}
+
+// InstanceTypeSpecs describes the hardware of an instance type
+type InstanceTypeSpecs struct {
+	VCPUs      int `json:"vcpus"`
+	MemoryGiB  int `json:"memory_gib"`
+	StorageGiB int `json:"storage_gib"`
+	GPUs       int `json:"gpus"`
+}
+
+// Region represents a Lambda Cloud region
+type Region struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// InstanceTypeAvailability is an instance type and the regions that currently have capacity for it
+type InstanceTypeAvailability struct {
+	InstanceType                 InstanceType `json:"instance_type"`
+	RegionsWithCapacityAvailable []Region     `json:"regions_with_capacity_available"`
+}
+
+// LambdaInstance represents a running Lambda Cloud instance
+type LambdaInstance struct {
+	ID              string       `json:"id"`
+	Name            string       `json:"name"`
+	IP              string       `json:"ip"`
+	Status          string       `json:"status"` // booting, active, unhealthy, terminating, terminated
+	SSHKeyNames     []string     `json:"ssh_key_names"`
+	FileSystemNames []string     `json:"file_system_names"`
+	Region          Region       `json:"region"`
+	InstanceType    InstanceType `json:"instance_type"`
+	Hostname        string       `json:"hostname"`
+	JupyterURL      string       `json:"jupyter_url"`
+}
+
+// SSHKey represents an SSH key registered with Lambda Cloud
+type SSHKey struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key,omitempty"` // Only returned when Lambda generates the key
+}
+
+// LaunchRequest represents parameters for launching instances
+type LaunchRequest struct {
+	RegionName       string   `json:"region_name"`
+	InstanceTypeName string   `json:"instance_type_name"`
+	SSHKeyNames      []string `json:"ssh_key_names"`
+	FileSystemNames  []string `json:"file_system_names,omitempty"`
+	Quantity         int      `json:"quantity,omitempty"`
+	Name             string   `json:"name,omitempty"`
+}
+
+// GetInstanceTypes retrieves instance types keyed by name, with the regions that have capacity
+func (c *Client) GetInstanceTypes(ctx context.Context) (map[string]InstanceTypeAvailability, error) {
+	var response struct {
+		Data map[string]InstanceTypeAvailability `json:"data"`
+	}
+
+	err := c.makeRequest(ctx, "GET", "/instance-types", nil, &response)
+	return response.Data, err
+}
+
+// LaunchInstances launches instances and returns their IDs
+func (c *Client) LaunchInstances(ctx context.Context, request *LaunchRequest) ([]string, error) {
+	var response struct {
+		Data struct {
+			InstanceIDs []string `json:"instance_ids"`
+		} `json:"data"`
+	}
+
+	if err := c.makeRequest(ctx, "POST", "/instance-operations/launch", request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Data.InstanceIDs) == 0 {
+		return nil, fmt.Errorf("launch of %s in %s returned no instances", request.InstanceTypeName, request.RegionName)
+	}
+
+	return response.Data.InstanceIDs, nil
+}
+
+// TerminateInstances terminates the given instances
+func (c *Client) TerminateInstances(ctx context.Context, instanceIDs []string) error {
+	payload := map[string][]string{"instance_ids": instanceIDs}
+	return c.makeRequest(ctx, "POST", "/instance-operations/terminate", payload, nil)
+}
+
+// GetInstances retrieves the account's instances
+func (c *Client) GetInstances(ctx context.Context) ([]LambdaInstance, error) {
+	var response struct {
+		Data []LambdaInstance `json:"data"`
+	}
+
+	err := c.makeRequest(ctx, "GET", "/instances", nil, &response)
+	return response.Data, err
+}
+
+// GetInstance retrieves details of a specific instance
+func (c *Client) GetInstance(ctx context.Context, instanceID string) (*LambdaInstance, error) {
+	var response struct {
+		Data LambdaInstance `json:"data"`
+	}
+
+	if err := c.makeRequest(ctx, "GET", "/instances/"+instanceID, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// ListSSHKeys retrieves the SSH keys registered on the account
+func (c *Client) ListSSHKeys(ctx context.Context) ([]SSHKey, error) {
+	var response struct {
+		Data []SSHKey `json:"data"`
+	}
+
+	err := c.makeRequest(ctx, "GET", "/ssh-keys", nil, &response)
+	return response.Data, err
+}
+
+// AddSSHKey registers a public key. When publicKey is empty Lambda generates a key pair
+// and returns the private key, which is not retrievable later.
+func (c *Client) AddSSHKey(ctx context.Context, name, publicKey string) (*SSHKey, error) {
+	payload := map[string]string{"name": name}
+	if publicKey != "" {
+		payload["public_key"] = publicKey
+	}
+
+	var response struct {
+		Data SSHKey `json:"data"`
+	}
+
+	if err := c.makeRequest(ctx, "POST", "/ssh-keys", payload, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// DeleteSSHKey removes an SSH key from the account
+func (c *Client) DeleteSSHKey(ctx context.Context, keyID string) error {
+	return c.makeRequest(ctx, "DELETE", "/ssh-keys/"+keyID, nil, nil)
+}
+
+// makeRequest performs HTTP requests to the Lambda Cloud API
+func (c *Client) makeRequest(ctx context.Context, method, endpoint string, payload interface{}, result interface{}) error {
+	var body io.Reader
+
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("error marshaling request body: %v", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	// Make the request
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		logging.Upstream(ctx, string(types.LambdaLabs), method, endpoint, 0, start, err, c.apiKey)
+		return apperr.Wrap(apperr.Unavailable, fmt.Errorf("error making request: %v", err))
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	respBody, err := io.ReadAll(resp.Body)
+	logging.Upstream(ctx, string(types.LambdaLabs), method, endpoint, resp.StatusCode, start, err, c.apiKey)
+	if err != nil {
+		return apperr.Wrap(apperr.Unavailable, fmt.Errorf("error reading response body: %v", err))
+	}
+
+	// Check for HTTP errors, preferring Lambda's structured error message
+	if resp.StatusCode >= 400 {
+		var apiError struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &apiError) == nil && apiError.Error.Message != "" {
+			return apperr.Wrap(apperr.FromHTTPStatus(resp.StatusCode), fmt.Errorf("API error %d (%s): %s", resp.StatusCode, apiError.Error.Code, apiError.Error.Message))
+		}
+		return apperr.Wrap(apperr.FromHTTPStatus(resp.StatusCode), fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody)))
+	}
+
+	// Parse response if result is provided
+	if result != nil {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("error parsing response: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Helper functions to convert between Lambda Cloud types and our internal types
+
+// OfferID identifies an instance type in a region, the unit Lambda Cloud launches
+func OfferID(instanceType, region string) string {
+	return instanceType + ":" + region
+}
+
+// ParseOfferID splits an offer ID produced by OfferID
+func ParseOfferID(offerID string) (instanceType, region string, err error) {
+	instanceType, region, ok := strings.Cut(offerID, ":")
+	if !ok || instanceType == "" || region == "" {
+		return "", "", apperr.Errorf(apperr.InvalidArgument, "invalid Lambda Labs offer ID %q, expected instance_type:region", offerID)
+	}
+	return instanceType, region, nil
+}
+
+// GPUModelName extracts the GPU model from a description such as "A100 (40 GB SXM4)"
+func GPUModelName(gpuDescription string) string {
+	name, _, _ := strings.Cut(gpuDescription, " (")
+	return strings.TrimSpace(name)
+}
+
+// ConvertInstanceTypeToGPUInstances converts an instance type into one offer per region with
+// capacity. Instance types without capacity yield a single unavailable offer.
+func ConvertInstanceTypeToGPUInstances(availability InstanceTypeAvailability) []types.GPUInstance {
+	instanceType := availability.InstanceType
+
+	base := types.GPUInstance{
+		Provider:     types.LambdaLabs,
+		Name:         "Lambda " + instanceType.Description,
+		Status:       types.StatusOffline,
+		GPUModel:     GPUModelName(instanceType.GPUDescription),
+		GPUCount:     instanceType.Specs.GPUs,
+		CPUCount:     instanceType.Specs.VCPUs,
+		RAM:          instanceType.Specs.MemoryGiB,
+		Storage:      instanceType.Specs.StorageGiB,
+		PricePerHour: float64(instanceType.PriceCentsPerHour) / 100,
+	}
+
+	if len(availability.RegionsWithCapacityAvailable) == 0 {
+		offer := base
+		offer.ID = types.LambdaLabs.InstanceIDPrefix() + instanceType.Name
+		offer.ProviderID = instanceType.Name
+		offer.Status = types.StatusUnavailable
+		offer.ProviderData = map[string]interface{}{
+			"instance_type":   instanceType.Name,
+			"gpu_description": instanceType.GPUDescription,
+		}
+		return []types.GPUInstance{offer}
+	}
+
+	offers := make([]types.GPUInstance, 0, len(availability.RegionsWithCapacityAvailable))
+	for _, region := range availability.RegionsWithCapacityAvailable {
+		offer := base
+		offer.ProviderID = OfferID(instanceType.Name, region.Name)
+		offer.ID = types.LambdaLabs.InstanceIDPrefix() + offer.ProviderID
+		offer.Region = region.Name
+		offer.ProviderData = map[string]interface{}{
+			"instance_type":      instanceType.Name,
+			"gpu_description":    instanceType.GPUDescription,
+			"region_description": region.Description,
+		}
+		offers = append(offers, offer)
+	}
+
+	return offers
+}
+
+// ConvertInstanceToGPUInstance converts a Lambda Cloud instance to our internal type
+func ConvertInstanceToGPUInstance(instance LambdaInstance) types.GPUInstance {
+	status := types.StatusOffline
+	switch instance.Status {
+	case "active":
+		status = types.StatusRunning
+	case "booting":
+		status = types.StatusStarting
+	case "unhealthy":
+		status = types.StatusError
+	case "terminating":
+		status = types.StatusStopping
+	case "terminated":
+		status = types.StatusTerminated
+	}
+
+	var ssh *types.SSHEndpoint
+	if instance.IP != "" {
+		ssh = &types.SSHEndpoint{Host: instance.IP, Port: 22}
+	}
+
+	return types.GPUInstance{
+		ID:           types.LambdaLabs.InstanceIDPrefix() + instance.ID,
+		Provider:     types.LambdaLabs,
+		ProviderID:   instance.ID,
+		Name:         instance.Name,
+		Status:       status,
+		GPUModel:     GPUModelName(instance.InstanceType.GPUDescription),
+		GPUCount:     instance.InstanceType.Specs.GPUs,
+		CPUCount:     instance.InstanceType.Specs.VCPUs,
+		RAM:          instance.InstanceType.Specs.MemoryGiB,
+		Storage:      instance.InstanceType.Specs.StorageGiB,
+		PricePerHour: float64(instance.InstanceType.PriceCentsPerHour) / 100,
+		Region:       instance.Region.Name,
+		SSH:          ssh,
+		ProviderData: map[string]interface{}{
+			"instance_type": instance.InstanceType.Name,
+			"hostname":      instance.Hostname,
+			"ssh_key_names": instance.SSHKeyNames,
+			"jupyter_url":   instance.JupyterURL,
+			"lambda_status": instance.Status,
+		},
+	}
+}
@@ -0,0 +1,266 @@
+package lambdalabs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// newTestClient returns a client pointed at an httptest server running handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+	return client
+}
+
+const instanceTypesResponse = `{
+	"data": {
+		"gpu_1x_a100": {
+			"instance_type": {
+				"name": "gpu_1x_a100",
+				"description": "1x A100 (40 GB SXM4)",
+				"gpu_description": "A100 (40 GB SXM4)",
+				"price_cents_per_hour": 129,
+				"specs": {"vcpus": 30, "memory_gib": 200, "storage_gib": 512, "gpus": 1}
+			},
+			"regions_with_capacity_available": [
+				{"name": "us-west-1", "description": "California, USA"},
+				{"name": "us-east-1", "description": "Virginia, USA"}
+			]
+		},
+		"gpu_8x_h100": {
+			"instance_type": {
+				"name": "gpu_8x_h100",
+				"description": "8x H100 (80 GB SXM5)",
+				"gpu_description": "H100 (80 GB SXM5)",
+				"price_cents_per_hour": 2392,
+				"specs": {"vcpus": 208, "memory_gib": 1800, "storage_gib": 24780, "gpus": 8}
+			},
+			"regions_with_capacity_available": []
+		}
+	}
+}`
+
+func TestGetInstanceTypes(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/instance-types" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test_api_key" {
+			t.Errorf("Expected bearer auth, got %q", got)
+		}
+		w.Write([]byte(instanceTypesResponse))
+	})
+
+	instanceTypes, err := client.GetInstanceTypes(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	a100, ok := instanceTypes["gpu_1x_a100"]
+	if !ok {
+		t.Fatalf("Expected gpu_1x_a100 in %v", instanceTypes)
+	}
+	if a100.InstanceType.PriceCentsPerHour != 129 {
+		t.Errorf("Expected price 129 cents, got %d", a100.InstanceType.PriceCentsPerHour)
+	}
+	if len(a100.RegionsWithCapacityAvailable) != 2 {
+		t.Errorf("Expected 2 regions with capacity, got %d", len(a100.RegionsWithCapacityAvailable))
+	}
+}
+
+func TestLaunchAndTerminateInstances(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/instance-operations/launch":
+			var req LaunchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Error decoding launch request: %v", err)
+			}
+			if req.RegionName != "us-west-1" || req.InstanceTypeName != "gpu_1x_a100" {
+				t.Errorf("Unexpected launch request %+v", req)
+			}
+			if len(req.SSHKeyNames) != 1 || req.SSHKeyNames[0] != "laptop" {
+				t.Errorf("Expected ssh key laptop, got %v", req.SSHKeyNames)
+			}
+			w.Write([]byte(`{"data": {"instance_ids": ["0920582c7ff041399e34823a0be62549"]}}`))
+		case "/instance-operations/terminate":
+			var req map[string][]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if len(req["instance_ids"]) != 1 || req["instance_ids"][0] != "0920582c7ff041399e34823a0be62549" {
+				t.Errorf("Unexpected terminate request %v", req)
+			}
+			w.Write([]byte(`{"data": {"terminated_instances": []}}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	ids, err := client.LaunchInstances(context.Background(), &LaunchRequest{
+		RegionName:       "us-west-1",
+		InstanceTypeName: "gpu_1x_a100",
+		SSHKeyNames:      []string{"laptop"},
+		Quantity:         1,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("Expected one instance ID, got %v", ids)
+	}
+
+	if err := client.TerminateInstances(context.Background(), ids); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestGetInstances(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{
+			"id": "abc123",
+			"name": "training",
+			"ip": "198.51.100.2",
+			"status": "active",
+			"ssh_key_names": ["laptop"],
+			"region": {"name": "us-west-1", "description": "California, USA"},
+			"instance_type": {
+				"name": "gpu_1x_a100",
+				"gpu_description": "A100 (40 GB SXM4)",
+				"price_cents_per_hour": 129,
+				"specs": {"vcpus": 30, "memory_gib": 200, "storage_gib": 512, "gpus": 1}
+			}
+		}]}`))
+	})
+
+	instances, err := client.GetInstances(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(instances) != 1 || instances[0].ID != "abc123" || instances[0].Status != "active" {
+		t.Errorf("Unexpected instances %+v", instances)
+	}
+}
+
+func TestSSHKeys(t *testing.T) {
+	deleted := false
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/ssh-keys":
+			w.Write([]byte(`{"data": [{"id": "k1", "name": "laptop", "public_key": "ssh-ed25519 AAAA laptop"}]}`))
+		case r.Method == "POST" && r.URL.Path == "/ssh-keys":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			w.Write([]byte(`{"data": {"id": "k2", "name": "` + req["name"] + `", "public_key": "` + req["public_key"] + `"}}`))
+		case r.Method == "DELETE" && r.URL.Path == "/ssh-keys/k2":
+			deleted = true
+			w.Write([]byte(`{"data": {}}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	keys, err := client.ListSSHKeys(context.Background())
+	if err != nil || len(keys) != 1 || keys[0].Name != "laptop" {
+		t.Fatalf("Unexpected keys %v, err %v", keys, err)
+	}
+
+	key, err := client.AddSSHKey(context.Background(), "ci", "ssh-ed25519 BBBB ci")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if key.ID != "k2" || key.Name != "ci" {
+		t.Errorf("Unexpected key %+v", key)
+	}
+
+	if err := client.DeleteSSHKey(context.Background(), "k2"); err != nil || !deleted {
+		t.Errorf("Expected key to be deleted, err %v", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"code": "instance-operations/launch/insufficient-capacity", "message": "Not enough capacity to fulfill launch request."}}`))
+	})
+
+	_, err := client.LaunchInstances(context.Background(), &LaunchRequest{RegionName: "us-west-1", InstanceTypeName: "gpu_1x_a100"})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !strings.Contains(err.Error(), "insufficient-capacity") || !strings.Contains(err.Error(), "Not enough capacity") {
+		t.Errorf("Expected the structured error to be surfaced, got %v", err)
+	}
+}
+
+func TestParseOfferID(t *testing.T) {
+	instanceType, region, err := ParseOfferID(OfferID("gpu_1x_a100", "us-west-1"))
+	if err != nil || instanceType != "gpu_1x_a100" || region != "us-west-1" {
+		t.Errorf("Unexpected result %s, %s, %v", instanceType, region, err)
+	}
+
+	for _, invalid := range []string{"gpu_1x_a100", ":us-west-1", "gpu_1x_a100:"} {
+		if _, _, err := ParseOfferID(invalid); apperr.KindOf(err) != apperr.InvalidArgument {
+			t.Errorf("Expected an invalid argument error for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestConvertInstanceTypeToGPUInstances(t *testing.T) {
+	var response struct {
+		Data map[string]InstanceTypeAvailability `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(instanceTypesResponse), &response); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	offers := ConvertInstanceTypeToGPUInstances(response.Data["gpu_1x_a100"])
+	if len(offers) != 2 {
+		t.Fatalf("Expected one offer per region, got %d", len(offers))
+	}
+
+	offer := offers[0]
+	if offer.ID != "lambda_labs_gpu_1x_a100:us-west-1" || offer.ProviderID != "gpu_1x_a100:us-west-1" {
+		t.Errorf("Unexpected IDs %s, %s", offer.ID, offer.ProviderID)
+	}
+	if offer.GPUModel != "A100" || offer.GPUCount != 1 || offer.PricePerHour != 1.29 || offer.Region != "us-west-1" {
+		t.Errorf("Unexpected offer %+v", offer)
+	}
+
+	unavailable := ConvertInstanceTypeToGPUInstances(response.Data["gpu_8x_h100"])
+	if len(unavailable) != 1 || unavailable[0].Status != types.StatusUnavailable {
+		t.Errorf("Expected a single unavailable offer, got %+v", unavailable)
+	}
+}
+
+func TestConvertInstanceToGPUInstance(t *testing.T) {
+	statuses := map[string]types.InstanceStatus{
+		"booting":     types.StatusStarting,
+		"active":      types.StatusRunning,
+		"unhealthy":   types.StatusError,
+		"terminating": types.StatusStopping,
+		"terminated":  types.StatusTerminated,
+	}
+
+	for lambdaStatus, expected := range statuses {
+		instance := ConvertInstanceToGPUInstance(LambdaInstance{ID: "abc123", Status: lambdaStatus, IP: "198.51.100.2"})
+		if instance.Status != expected {
+			t.Errorf("Expected %s to map to %s, got %s", lambdaStatus, expected, instance.Status)
+		}
+		if instance.ID != "lambda_labs_abc123" {
+			t.Errorf("Expected ID lambda_labs_abc123, got %s", instance.ID)
+		}
+		if instance.SSH == nil || instance.SSH.Host != "198.51.100.2" || instance.SSH.Port != 22 {
+			t.Errorf("Expected SSH endpoint 198.51.100.2:22, got %+v", instance.SSH)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package logging configures the service's structured JSON logs. Records logged with a
+// context carrying a request ID are tagged with it, and values of sensitive fields
+// such as API keys are redacted.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Redacted replaces sensitive values in log records
+const Redacted = "[REDACTED]"
+
+// RequestIDKey is the log field holding the request ID
+const RequestIDKey = "request_id"
+
+// sensitiveKeys are field and query parameter names whose values are never logged
+var sensitiveKeys = []string{"api_key", "apikey", "authorization", "password", "secret", "token"}
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// ParseLevel converts a LOG_LEVEL value (debug, info, warn or error) to a slog level.
+// Unknown values fall back to info and report false.
+func ParseLevel(level string) (slog.Level, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info", "":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
+// New creates a logger writing JSON records at level and above to w
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	})
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// contextHandler adds the request ID carried by the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(RequestIDKey, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+// redactAttr hides the values of sensitive fields
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if isSensitive(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+// isSensitive reports whether values named key must not be logged
+func isSensitive(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactURL returns rawURL with the values of sensitive query parameters replaced
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+	query := u.Query()
+	for key := range query {
+		if isSensitive(key) {
+			query.Set(key, Redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Redact replaces every occurrence of the given secrets in s
+func Redact(s string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+// Upstream logs a call to a provider's API made with ctx. Failed calls are logged as
+// warnings and successful ones at debug level. apiKey is redacted from the error.
+func Upstream(ctx context.Context, provider, method, endpoint string, status int, start time.Time, err error, apiKey string) {
+	level := slog.LevelDebug
+	attrs := []slog.Attr{
+		slog.String("provider", provider),
+		slog.String("method", method),
+		slog.String("endpoint", RedactURL(endpoint)),
+		slog.Int("status", status),
+		slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+	}
+	if err != nil || status >= 400 {
+		level = slog.LevelWarn
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", Redact(err.Error(), apiKey)))
+	}
+	slog.LogAttrs(ctx, level, "Provider request", attrs...)
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected slog.Level
+		ok       bool
+	}{
+		{"debug", slog.LevelDebug, true},
+		{"INFO", slog.LevelInfo, true},
+		{"warning", slog.LevelWarn, true},
+		{"error", slog.LevelError, true},
+		{"verbose", slog.LevelInfo, false},
+	}
+
+	for _, tt := range tests {
+		level, ok := ParseLevel(tt.value)
+		if level != tt.expected || ok != tt.ok {
+			t.Errorf("Expected %q to parse as %v %v, got %v %v", tt.value, tt.expected, tt.ok, level, ok)
+		}
+	}
+}
+
+func TestLoggerAddsRequestIDAndRedacts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	logger.InfoContext(ctx, "Provider request", "api_key", "secret-key", "Authorization", "Bearer secret-key", "status", 200)
+	logger.Debug("Hidden")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	if record[RequestIDKey] != "req-123" {
+		t.Errorf("Expected request ID req-123, got %v", record[RequestIDKey])
+	}
+	if record["api_key"] != Redacted || record["Authorization"] != Redacted {
+		t.Errorf("Expected sensitive fields to be redacted, got %v", record)
+	}
+	if record["status"] != float64(200) {
+		t.Errorf("Expected status 200, got %v", record["status"])
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	got := RedactURL("/bundles?api_key=secret&limit=10")
+	if strings.Contains(got, "secret") || !strings.Contains(got, "limit=10") {
+		t.Errorf("Expected only the API key to be redacted, got %s", got)
+	}
+
+	if got := RedactURL("/instances/123"); got != "/instances/123" {
+		t.Errorf("Expected a URL without a query to be unchanged, got %s", got)
+	}
+}
+
+func TestUpstream(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(New(&buf, slog.LevelInfo))
+	defer slog.SetDefault(previous)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	Upstream(ctx, "vast_ai", "GET", "/instances", 200, time.Now(), nil, "secret-key")
+	if buf.Len() != 0 {
+		t.Errorf("Expected successful calls to be logged at debug level, got %s", buf.String())
+	}
+
+	Upstream(ctx, "vast_ai", "PUT", "/asks/1/", 0, time.Now(), errors.New("dial failed for secret-key"), "secret-key")
+	out := buf.String()
+	if !strings.Contains(out, `"level":"WARN"`) || !strings.Contains(out, `"request_id":"req-123"`) || strings.Contains(out, "secret-key") {
+		t.Errorf("Expected a redacted warning with the request ID, got %s", out)
+	}
+}
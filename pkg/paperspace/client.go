@@ -0,0 +1,358 @@
+package paperspace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/logging"
+	"gpu-cloud-manager/pkg/types"
+)
+
+const (
+	BaseURL = "https://api.paperspace.io"
+)
+
+// Regions lists the Paperspace regions machines can be created in
+var Regions = []string{"East Coast (NY2)", "West Coast (CA1)", "Europe (AMS1)"}
+
+// MachineType describes a Paperspace GPU machine type. Paperspace doesn't expose
+// prices through its API, so they are kept here from the public price list.
+type MachineType struct {
+	Name         string
+	GPUModel     string
+	GPUMemoryGB  int
+	GPUCount     int
+	CPUCount     int
+	RAMGB        int
+	PricePerHour float64
+}
+
+// MachineTypes lists the GPU machine types offered, keyed by machine type name
+var MachineTypes = map[string]MachineType{
+	"P4000":    {Name: "P4000", GPUModel: "Quadro P4000", GPUMemoryGB: 8, GPUCount: 1, CPUCount: 8, RAMGB: 30, PricePerHour: 0.51},
+	"P5000":    {Name: "P5000", GPUModel: "Quadro P5000", GPUMemoryGB: 16, GPUCount: 1, CPUCount: 8, RAMGB: 30, PricePerHour: 0.78},
+	"P6000":    {Name: "P6000", GPUModel: "Quadro P6000", GPUMemoryGB: 24, GPUCount: 1, CPUCount: 8, RAMGB: 30, PricePerHour: 1.10},
+	"RTX4000":  {Name: "RTX4000", GPUModel: "Quadro RTX 4000", GPUMemoryGB: 8, GPUCount: 1, CPUCount: 8, RAMGB: 30, PricePerHour: 0.56},
+	"RTX5000":  {Name: "RTX5000", GPUModel: "Quadro RTX 5000", GPUMemoryGB: 16, GPUCount: 1, CPUCount: 8, RAMGB: 30, PricePerHour: 0.82},
+	"A4000":    {Name: "A4000", GPUModel: "A4000", GPUMemoryGB: 16, GPUCount: 1, CPUCount: 8, RAMGB: 45, PricePerHour: 0.76},
+	"A5000":    {Name: "A5000", GPUModel: "A5000", GPUMemoryGB: 24, GPUCount: 1, CPUCount: 8, RAMGB: 45, PricePerHour: 1.38},
+	"A6000":    {Name: "A6000", GPUModel: "A6000", GPUMemoryGB: 48, GPUCount: 1, CPUCount: 8, RAMGB: 45, PricePerHour: 1.89},
+	"V100":     {Name: "V100", GPUModel: "V100", GPUMemoryGB: 16, GPUCount: 1, CPUCount: 8, RAMGB: 30, PricePerHour: 2.30},
+	"V100-32G": {Name: "V100-32G", GPUModel: "V100", GPUMemoryGB: 32, GPUCount: 1, CPUCount: 8, RAMGB: 30, PricePerHour: 2.30},
+	"A100":     {Name: "A100", GPUModel: "A100", GPUMemoryGB: 40, GPUCount: 1, CPUCount: 12, RAMGB: 90, PricePerHour: 3.09},
+	"A100-80G": {Name: "A100-80G", GPUModel: "A100", GPUMemoryGB: 80, GPUCount: 1, CPUCount: 12, RAMGB: 90, PricePerHour: 3.18},
+}
+
+// SortedMachineTypes returns the machine types ordered by price
+func SortedMachineTypes() []MachineType {
+	machineTypes := make([]MachineType, 0, len(MachineTypes))
+	for _, machineType := range MachineTypes {
+		machineTypes = append(machineTypes, machineType)
+	}
+	sort.Slice(machineTypes, func(i, j int) bool {
+		if machineTypes[i].PricePerHour != machineTypes[j].PricePerHour {
+			return machineTypes[i].PricePerHour < machineTypes[j].PricePerHour
+		}
+		return machineTypes[i].Name < machineTypes[j].Name
+	})
+	return machineTypes
+}
+
+// Client represents a Paperspace API client
+type Client struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient creates a new Paperspace API client
+func NewClient(apiKey string) *Client {
+	return &Client{
+		apiKey:  apiKey,
+		baseURL: BaseURL,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Machine represents a Paperspace machine
+type Machine struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	OS               string   `json:"os"`
+	RAM              quantity `json:"ram"` // Bytes
+	CPUs             int      `json:"cpus"`
+	GPU              string   `json:"gpu"`
+	StorageTotal     quantity `json:"storageTotal"` // Bytes
+	UsageRate        string   `json:"usageRate"`    // e.g. "P4000 hourly"
+	MachineType      string   `json:"machineType"`
+	State            string   `json:"state"`
+	PublicIPAddress  string   `json:"publicIpAddress"`
+	PrivateIPAddress string   `json:"privateIpAddress"`
+	Region           string   `json:"region"`
+	DtCreated        string   `json:"dtCreated"`
+}
+
+// quantity decodes numbers Paperspace sends either as JSON numbers or strings
+type quantity int64
+
+// UnmarshalJSON implements json.Unmarshaler
+func (q *quantity) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*q = 0
+		return nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid quantity %s: %v", data, err)
+	}
+	*q = quantity(n)
+	return nil
+}
+
+// CreateMachineRequest represents parameters for creating a machine
+type CreateMachineRequest struct {
+	Region         string `json:"region"`
+	MachineType    string `json:"machineType"`
+	Size           int    `json:"size"` // Storage in GB
+	BillingType    string `json:"billingType"`
+	MachineName    string `json:"machineName"`
+	TemplateID     string `json:"templateId"`
+	AssignPublicIP bool   `json:"assignPublicIp"`
+}
+
+// GetAvailability reports whether a machine type can currently be created in a region
+func (c *Client) GetAvailability(ctx context.Context, region, machineType string) (bool, error) {
+	params := url.Values{}
+	params.Set("region", region)
+	params.Set("machineType", machineType)
+
+	var response struct {
+		Available bool `json:"available"`
+	}
+
+	err := c.makeRequest(ctx, "GET", "/machines/getAvailability?"+params.Encode(), nil, &response)
+	return response.Available, err
+}
+
+// CreateMachine creates a new machine
+func (c *Client) CreateMachine(ctx context.Context, request *CreateMachineRequest) (*Machine, error) {
+	var machine Machine
+	if err := c.makeRequest(ctx, "POST", "/machines/createSingleMachinePublic", request, &machine); err != nil {
+		return nil, err
+	}
+	return &machine, nil
+}
+
+// StartMachine starts a stopped machine
+func (c *Client) StartMachine(ctx context.Context, machineID string) error {
+	return c.makeRequest(ctx, "POST", "/machines/"+url.PathEscape(machineID)+"/start", nil, nil)
+}
+
+// StopMachine stops a running machine
+func (c *Client) StopMachine(ctx context.Context, machineID string) error {
+	return c.makeRequest(ctx, "POST", "/machines/"+url.PathEscape(machineID)+"/stop", nil, nil)
+}
+
+// DestroyMachine permanently destroys a machine
+func (c *Client) DestroyMachine(ctx context.Context, machineID string) error {
+	return c.makeRequest(ctx, "POST", "/machines/"+url.PathEscape(machineID)+"/destroyMachine", nil, nil)
+}
+
+// GetMachines retrieves the account's machines
+func (c *Client) GetMachines(ctx context.Context) ([]Machine, error) {
+	var machines []Machine
+	err := c.makeRequest(ctx, "GET", "/machines/getMachines", nil, &machines)
+	return machines, err
+}
+
+// GetMachine retrieves details of a specific machine
+func (c *Client) GetMachine(ctx context.Context, machineID string) (*Machine, error) {
+	params := url.Values{}
+	params.Set("machineId", machineID)
+
+	var machine Machine
+	if err := c.makeRequest(ctx, "GET", "/machines/getMachinePublic?"+params.Encode(), nil, &machine); err != nil {
+		return nil, err
+	}
+	return &machine, nil
+}
+
+// makeRequest performs HTTP requests to the Paperspace API
+func (c *Client) makeRequest(ctx context.Context, method, endpoint string, payload interface{}, result interface{}) error {
+	var body io.Reader
+
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("error marshaling request body: %v", err)
+		}
+		body = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, body)
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+
+	// Set headers
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Api-Key", c.apiKey)
+
+	// Make the request
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		logging.Upstream(ctx, string(types.Paperspace), method, endpoint, 0, start, err, c.apiKey)
+		return apperr.Wrap(apperr.Unavailable, fmt.Errorf("error making request: %v", err))
+	}
+	defer resp.Body.Close()
+
+	// Read response body
+	respBody, err := io.ReadAll(resp.Body)
+	logging.Upstream(ctx, string(types.Paperspace), method, endpoint, resp.StatusCode, start, err, c.apiKey)
+	if err != nil {
+		return apperr.Wrap(apperr.Unavailable, fmt.Errorf("error reading response body: %v", err))
+	}
+
+	// Check for HTTP errors, preferring Paperspace's structured error message
+	if resp.StatusCode >= 400 {
+		var apiError struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(respBody, &apiError) == nil {
+			if apiError.Error.Message != "" {
+				return apperr.Wrap(apperr.FromHTTPStatus(resp.StatusCode), fmt.Errorf("API error %d: %s", resp.StatusCode, apiError.Error.Message))
+			}
+			if apiError.Message != "" {
+				return apperr.Wrap(apperr.FromHTTPStatus(resp.StatusCode), fmt.Errorf("API error %d: %s", resp.StatusCode, apiError.Message))
+			}
+		}
+		return apperr.Wrap(apperr.FromHTTPStatus(resp.StatusCode), fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody)))
+	}
+
+	// Parse response if result is provided
+	if result != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err != nil {
+			return fmt.Errorf("error parsing response: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Helper functions to convert between Paperspace types and our internal types
+
+// OfferID identifies a machine type in a region
+func OfferID(machineType, region string) string {
+	return machineType + ":" + region
+}
+
+// ParseOfferID splits an offer ID produced by OfferID
+func ParseOfferID(offerID string) (machineType, region string, err error) {
+	machineType, region, ok := strings.Cut(offerID, ":")
+	if !ok || machineType == "" || region == "" {
+		return "", "", apperr.Errorf(apperr.InvalidArgument, "invalid Paperspace offer ID %q, expected machine_type:region", offerID)
+	}
+	return machineType, region, nil
+}
+
+// ConvertMachineTypeToGPUInstance converts a machine type in a region to a searchable offer
+func ConvertMachineTypeToGPUInstance(machineType MachineType, region string, available bool) types.GPUInstance {
+	status := types.StatusOffline
+	if !available {
+		status = types.StatusUnavailable
+	}
+
+	offerID := OfferID(machineType.Name, region)
+	return types.GPUInstance{
+		ID:           types.Paperspace.InstanceIDPrefix() + offerID,
+		Provider:     types.Paperspace,
+		ProviderID:   offerID,
+		Name:         "Paperspace " + machineType.Name,
+		Status:       status,
+		GPUModel:     machineType.GPUModel,
+		GPUCount:     machineType.GPUCount,
+		CPUCount:     machineType.CPUCount,
+		RAM:          machineType.RAMGB,
+		PricePerHour: machineType.PricePerHour,
+		Region:       region,
+		ProviderData: map[string]interface{}{
+			"machine_type":  machineType.Name,
+			"gpu_memory_gb": machineType.GPUMemoryGB,
+		},
+	}
+}
+
+// ConvertMachineToGPUInstance converts a Paperspace machine to our internal type
+func ConvertMachineToGPUInstance(machine Machine) types.GPUInstance {
+	status := types.StatusOffline
+	switch machine.State {
+	case "ready", "serviceready":
+		status = types.StatusRunning
+	case "provisioning", "starting", "restarting":
+		status = types.StatusStarting
+	case "stopping":
+		status = types.StatusStopping
+	case "upgrading":
+		status = types.StatusLoading
+	case "off":
+		status = types.StatusOffline
+	}
+
+	machineTypeName := machine.MachineType
+	if machineTypeName == "" {
+		// usageRate reads like "P4000 hourly"
+		machineTypeName, _, _ = strings.Cut(machine.UsageRate, " ")
+	}
+
+	var ssh *types.SSHEndpoint
+	if machine.PublicIPAddress != "" {
+		ssh = &types.SSHEndpoint{Host: machine.PublicIPAddress, Port: 22}
+	}
+
+	instance := types.GPUInstance{
+		ID:         types.Paperspace.InstanceIDPrefix() + machine.ID,
+		Provider:   types.Paperspace,
+		ProviderID: machine.ID,
+		Name:       machine.Name,
+		Status:     status,
+		GPUModel:   machine.GPU,
+		CPUCount:   machine.CPUs,
+		RAM:        int(machine.RAM >> 30),
+		Storage:    int(machine.StorageTotal >> 30),
+		Region:     machine.Region,
+		SSH:        ssh,
+		ProviderData: map[string]interface{}{
+			"machine_type": machineTypeName,
+			"os":           machine.OS,
+			"state":        machine.State,
+			"usage_rate":   machine.UsageRate,
+			"private_ip":   machine.PrivateIPAddress,
+			"dt_created":   machine.DtCreated,
+		},
+	}
+
+	if machineType, ok := MachineTypes[machineTypeName]; ok {
+		instance.GPUModel = machineType.GPUModel
+		instance.GPUCount = machineType.GPUCount
+		instance.PricePerHour = machineType.PricePerHour
+	}
+
+	return instance
+}
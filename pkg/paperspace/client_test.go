@@ -0,0 +1,218 @@
+package paperspace
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/types"
+)
+
+// newTestClient returns a client pointed at an httptest server running handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+	return client
+}
+
+const machineResponse = `{
+	"id": "psabc123",
+	"name": "training",
+	"os": "Ubuntu 20.04",
+	"ram": "32212254720",
+	"cpus": 8,
+	"gpu": "Quadro P4000",
+	"storageTotal": 53687091200,
+	"usageRate": "P4000 hourly",
+	"state": "ready",
+	"publicIpAddress": "203.0.113.7",
+	"region": "East Coast (NY2)"
+}`
+
+func TestGetAvailability(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/machines/getAvailability" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-Api-Key"); got != "test_api_key" {
+			t.Errorf("Expected X-Api-Key header, got %q", got)
+		}
+		if r.URL.Query().Get("region") != "East Coast (NY2)" || r.URL.Query().Get("machineType") != "A100" {
+			t.Errorf("Unexpected query %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{"available": true}`))
+	})
+
+	available, err := client.GetAvailability(context.Background(), "East Coast (NY2)", "A100")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !available {
+		t.Error("Expected A100 to be available")
+	}
+}
+
+func TestMachineLifecycle(t *testing.T) {
+	var calls []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/machines/createSingleMachinePublic":
+			var req CreateMachineRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("Error decoding create request: %v", err)
+			}
+			if req.MachineType != "P4000" || req.TemplateID != "tkni3aa4" || req.BillingType != "hourly" {
+				t.Errorf("Unexpected create request %+v", req)
+			}
+			w.Write([]byte(`{"id": "psabc123", "name": "training", "state": "provisioning"}`))
+		case "/machines/getMachinePublic":
+			if r.URL.Query().Get("machineId") != "psabc123" {
+				t.Errorf("Unexpected machine ID %s", r.URL.Query().Get("machineId"))
+			}
+			w.Write([]byte(machineResponse))
+		case "/machines/getMachines":
+			w.Write([]byte("[" + machineResponse + "]"))
+		default:
+			// start, stop and destroy answer with an empty body
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	ctx := context.Background()
+
+	machine, err := client.CreateMachine(ctx, &CreateMachineRequest{
+		Region:      "East Coast (NY2)",
+		MachineType: "P4000",
+		Size:        50,
+		BillingType: "hourly",
+		TemplateID:  "tkni3aa4",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if machine.ID != "psabc123" || machine.State != "provisioning" {
+		t.Errorf("Unexpected machine %+v", machine)
+	}
+
+	machine, err = client.GetMachine(ctx, "psabc123")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if machine.RAM != 32212254720 || machine.StorageTotal != 53687091200 {
+		t.Errorf("Expected string and numeric sizes to decode, got %d and %d", machine.RAM, machine.StorageTotal)
+	}
+
+	machines, err := client.GetMachines(ctx)
+	if err != nil || len(machines) != 1 {
+		t.Fatalf("Unexpected machines %v, err %v", machines, err)
+	}
+
+	for _, op := range []func(context.Context, string) error{client.StartMachine, client.StopMachine, client.DestroyMachine} {
+		if err := op(ctx, "psabc123"); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := []string{
+		"POST /machines/createSingleMachinePublic",
+		"GET /machines/getMachinePublic",
+		"GET /machines/getMachines",
+		"POST /machines/psabc123/start",
+		"POST /machines/psabc123/stop",
+		"POST /machines/psabc123/destroyMachine",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Expected call %d to be %s, got %s", i, expected[i], calls[i])
+		}
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"name": "Error", "status": 400, "message": "Machine type is not available in this region"}}`))
+	})
+
+	_, err := client.CreateMachine(context.Background(), &CreateMachineRequest{})
+	if err == nil || err.Error() != "API error 400: Machine type is not available in this region" {
+		t.Errorf("Expected the structured error to be surfaced, got %v", err)
+	}
+}
+
+func TestConvertMachineToGPUInstance(t *testing.T) {
+	var machine Machine
+	if err := json.Unmarshal([]byte(machineResponse), &machine); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	instance := ConvertMachineToGPUInstance(machine)
+	if instance.ID != "paperspace_psabc123" || instance.Provider != types.Paperspace {
+		t.Errorf("Unexpected ID %s provider %s", instance.ID, instance.Provider)
+	}
+	if instance.Status != types.StatusRunning {
+		t.Errorf("Expected running, got %s", instance.Status)
+	}
+	if instance.RAM != 30 || instance.Storage != 50 {
+		t.Errorf("Expected 30 GB RAM and 50 GB storage, got %d and %d", instance.RAM, instance.Storage)
+	}
+	if instance.PricePerHour != MachineTypes["P4000"].PricePerHour {
+		t.Errorf("Expected the P4000 price from the usage rate, got %f", instance.PricePerHour)
+	}
+	if instance.SSH == nil || instance.SSH.Host != "203.0.113.7" {
+		t.Errorf("Expected SSH on the public IP, got %+v", instance.SSH)
+	}
+
+	states := map[string]types.InstanceStatus{
+		"off":          types.StatusOffline,
+		"provisioning": types.StatusStarting,
+		"starting":     types.StatusStarting,
+		"stopping":     types.StatusStopping,
+		"serviceready": types.StatusRunning,
+		"upgrading":    types.StatusLoading,
+	}
+	for state, expected := range states {
+		if got := ConvertMachineToGPUInstance(Machine{State: state}).Status; got != expected {
+			t.Errorf("Expected %s to map to %s, got %s", state, expected, got)
+		}
+	}
+}
+
+func TestOfferIDs(t *testing.T) {
+	offer := ConvertMachineTypeToGPUInstance(MachineTypes["A100"], "Europe (AMS1)", false)
+	if offer.Status != types.StatusUnavailable {
+		t.Errorf("Expected unavailable offer, got %s", offer.Status)
+	}
+
+	machineType, region, err := ParseOfferID(offer.ProviderID)
+	if err != nil || machineType != "A100" || region != "Europe (AMS1)" {
+		t.Errorf("Unexpected result %s, %s, %v", machineType, region, err)
+	}
+
+	if _, _, err := ParseOfferID("A100"); apperr.KindOf(err) != apperr.InvalidArgument {
+		t.Errorf("Expected an invalid argument error for offer ID without region, got %v", err)
+	}
+}
+
+func TestSortedMachineTypes(t *testing.T) {
+	machineTypes := SortedMachineTypes()
+	if len(machineTypes) != len(MachineTypes) {
+		t.Fatalf("Expected %d machine types, got %d", len(MachineTypes), len(machineTypes))
+	}
+	for i := 1; i < len(machineTypes); i++ {
+		if machineTypes[i-1].PricePerHour > machineTypes[i].PricePerHour {
+			t.Errorf("Expected machine types ordered by price, got %s before %s", machineTypes[i-1].Name, machineTypes[i].Name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Package retry retries calls to provider APIs with jittered exponential backoff.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy controls how often and how long a failed call is retried
+type Policy struct {
+	MaxRetries int           // Retries after the first attempt; 0 disables retrying
+	BaseDelay  time.Duration // Delay before the first retry, doubled for each one after
+	MaxDelay   time.Duration // Longest delay waited, including one asked for with Retry-After
+}
+
+// DefaultPolicy retries twice, after about 0.5s and 1s
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries: 2,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// Delay returns the delay before retry n (starting at 1): the exponential delay capped
+// at MaxDelay, less a random jitter of up to half of it
+func (p Policy) Delay(n int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < n && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// retryableError marks an error the call may be retried after
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// Retryable marks err as worth retrying, after the given delay when it is positive
+// (e.g. from a Retry-After header) or after the policy's backoff otherwise
+func Retryable(err error, after time.Duration) error {
+	return &retryableError{err: err, after: after}
+}
+
+// Do calls attempt until it succeeds or fails with an error not marked Retryable. A
+// retryable failure is given up on, and its error returned, once the retries are used
+// up or when the delay would exceed MaxDelay or ctx's deadline.
+func (p Policy) Do(ctx context.Context, attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		var retryable *retryableError
+		if err == nil || !errors.As(err, &retryable) {
+			return err
+		}
+		if n > p.MaxRetries || ctx.Err() != nil {
+			return retryable.err
+		}
+
+		delay := retryable.after
+		if delay <= 0 {
+			delay = p.Delay(n)
+		} else if p.MaxDelay > 0 && delay > p.MaxDelay {
+			return retryable.err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return retryable.err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retryable.err
+		case <-timer.C:
+		}
+	}
+}
+
+// RetryableStatus reports whether a response with status may succeed when repeated:
+// 429 Too Many Requests and server errors other than 501 Not Implemented
+func RetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || (status >= 500 && status != http.StatusNotImplemented)
+}
+
+// RetryAfter parses a Retry-After header given in seconds or as an HTTP date. It
+// returns 0 when the header is missing or invalid.
+func RetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		retry int
+		max   time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{10, time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := p.Delay(tt.retry); d < tt.max/2 || d > tt.max {
+				t.Errorf("Expected retry %d to wait between %s and %s, got %s", tt.retry, tt.max/2, tt.max, d)
+			}
+		}
+	}
+}
+
+func TestDoRetriesRetryableErrors(t *testing.T) {
+	p := Policy{MaxRetries: 2, BaseDelay: time.Millisecond}
+	failure := errors.New("API error 503")
+
+	attempts := 0
+	err := p.Do(context.Background(), func() error {
+		attempts++
+		return Retryable(failure, 0)
+	})
+	if err != failure || attempts != 3 {
+		t.Errorf("Expected the unwrapped error after 3 attempts, got %v after %d", err, attempts)
+	}
+
+	attempts = 0
+	err = p.Do(context.Background(), func() error {
+		attempts++
+		if attempts < 2 {
+			return Retryable(failure, 0)
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("Expected success on the second attempt, got %v after %d", err, attempts)
+	}
+
+	attempts = 0
+	err = p.Do(context.Background(), func() error {
+		attempts++
+		return failure
+	})
+	if err != failure || attempts != 1 {
+		t.Errorf("Expected errors not marked retryable to be returned at once, got %v after %d", err, attempts)
+	}
+}
+
+func TestDoGivesUpOnLongRetryAfter(t *testing.T) {
+	p := Policy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+	attempts := 0
+	p.Do(context.Background(), func() error {
+		attempts++
+		return Retryable(errors.New("API error 429"), time.Minute)
+	})
+	if attempts != 1 {
+		t.Errorf("Expected no retry when Retry-After exceeds the max delay, got %d attempts", attempts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	attempts = 0
+	p.Do(ctx, func() error {
+		attempts++
+		return Retryable(errors.New("API error 429"), 500*time.Millisecond)
+	})
+	if attempts != 1 {
+		t.Errorf("Expected no retry past the context deadline, got %d attempts", attempts)
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	for status, expected := range map[int]bool{
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusNotImplemented:      false,
+		http.StatusNotFound:            false,
+		http.StatusBadRequest:          false,
+	} {
+		if got := RetryableStatus(status); got != expected {
+			t.Errorf("Expected RetryableStatus(%d) to be %v", status, expected)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if d := RetryAfter("3", now); d != 3*time.Second {
+		t.Errorf("Expected 3s, got %s", d)
+	}
+	if d := RetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now); d != 10*time.Second {
+		t.Errorf("Expected 10s from an HTTP date, got %s", d)
+	}
+	for _, value := range []string{"", "soon", "-1"} {
+		if d := RetryAfter(value, now); d != 0 {
+			t.Errorf("Expected no delay for %q, got %s", value, d)
+		}
+	}
+}
@@ -2,14 +2,18 @@ package runpod
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
-	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/logging"
+	"gpu-cloud-manager/pkg/retry"
 	"gpu-cloud-manager/pkg/types"
 )
 
@@ -19,9 +23,10 @@ const (
 
 // Client represents a RunPod API client
 type Client struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
+	apiKey      string
+	baseURL     string
+	client      *http.Client
+	retryPolicy retry.Policy
 }
 
 // NewClient creates a new RunPod API client
@@ -32,9 +37,15 @@ func NewClient(apiKey string) *Client {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retryPolicy: retry.DefaultPolicy(),
 	}
 }
 
+// SetRetryPolicy sets how operations that are safe to repeat are retried
+func (c *Client) SetRetryPolicy(policy retry.Policy) {
+	c.retryPolicy = policy
+}
+
 // RunPodTemplate represents a GPU template from RunPod
 type RunPodTemplate struct {
 	ID            string  `json:"id"`
@@ -96,7 +107,7 @@ type Machine struct {
 }
 
 // SearchPods searches for available GPU pods
-func (c *Client) SearchPods() ([]RunPodPod, error) {
+func (c *Client) SearchPods(ctx context.Context) ([]RunPodPod, error) {
 	query := `
 	query {
 		myself {
@@ -145,7 +156,7 @@ func (c *Client) SearchPods() ([]RunPodPod, error) {
 		} `json:"data"`
 	}
 
-	err := c.makeGraphQLRequest(query, nil, &response)
+	err := c.makeGraphQLRequest(ctx, query, nil, &response)
 	if err != nil {
 		return nil, err
 	}
@@ -154,7 +165,7 @@ func (c *Client) SearchPods() ([]RunPodPod, error) {
 }
 
 // GetGPUTypes retrieves available GPU types
-func (c *Client) GetGPUTypes() ([]GPUType, error) {
+func (c *Client) GetGPUTypes(ctx context.Context) ([]GPUType, error) {
 	query := `
 	query {
 		gpuTypes {
@@ -176,7 +187,7 @@ func (c *Client) GetGPUTypes() ([]GPUType, error) {
 		} `json:"data"`
 	}
 
-	err := c.makeGraphQLRequest(query, nil, &response)
+	err := c.makeGraphQLRequest(ctx, query, nil, &response)
 	if err != nil {
 		return nil, err
 	}
@@ -223,7 +234,7 @@ type EnvVar struct {
 }
 
 // CreatePod creates a new GPU pod
-func (c *Client) CreatePod(req *CreatePodRequest) (*RunPodPod, error) {
+func (c *Client) CreatePod(ctx context.Context, req *CreatePodRequest) (*RunPodPod, error) {
 	mutation := `
 	mutation createPod($input: PodCreateInput!) {
 		podCreate(input: $input) {
@@ -256,7 +267,7 @@ func (c *Client) CreatePod(req *CreatePodRequest) (*RunPodPod, error) {
 		} `json:"data"`
 	}
 
-	err := c.makeGraphQLRequest(mutation, variables, &response)
+	err := c.makeGraphQLRequestOnce(ctx, mutation, variables, &response)
 	if err != nil {
 		return nil, err
 	}
@@ -265,7 +276,7 @@ func (c *Client) CreatePod(req *CreatePodRequest) (*RunPodPod, error) {
 }
 
 // StopPod stops a running pod
-func (c *Client) StopPod(podID string) error {
+func (c *Client) StopPod(ctx context.Context, podID string) error {
 	mutation := `
 	mutation stopPod($input: PodStopInput!) {
 		podStop(input: $input) {
@@ -289,11 +300,11 @@ func (c *Client) StopPod(podID string) error {
 		} `json:"data"`
 	}
 
-	return c.makeGraphQLRequest(mutation, variables, &response)
+	return c.makeGraphQLRequest(ctx, mutation, variables, &response)
 }
 
 // ResumePod resumes a stopped pod
-func (c *Client) ResumePod(podID string) error {
+func (c *Client) ResumePod(ctx context.Context, podID string) error {
 	mutation := `
 	mutation resumePod($input: PodResumeInput!) {
 		podResume(input: $input) {
@@ -317,11 +328,11 @@ func (c *Client) ResumePod(podID string) error {
 		} `json:"data"`
 	}
 
-	return c.makeGraphQLRequest(mutation, variables, &response)
+	return c.makeGraphQLRequest(ctx, mutation, variables, &response)
 }
 
 // TerminatePod terminates a pod
-func (c *Client) TerminatePod(podID string) error {
+func (c *Client) TerminatePod(ctx context.Context, podID string) error {
 	mutation := `
 	mutation terminatePod($input: PodTerminateInput!) {
 		podTerminate(input: $input) {
@@ -343,11 +354,64 @@ func (c *Client) TerminatePod(podID string) error {
 		} `json:"data"`
 	}
 
-	return c.makeGraphQLRequest(mutation, variables, &response)
+	return c.makeGraphQLRequest(ctx, mutation, variables, &response)
+}
+
+// graphQLError is one entry of the errors array RunPod returns, often with status 200
+type graphQLError struct {
+	Message    string `json:"message"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+// graphQLErrors reports the errors listed in a GraphQL response body, if any
+func graphQLErrors(respBody []byte) error {
+	var response struct {
+		Errors []graphQLError `json:"errors"`
+	}
+	if json.Unmarshal(respBody, &response) != nil || len(response.Errors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(response.Errors))
+	for i, e := range response.Errors {
+		messages[i] = e.Message
+		if e.Extensions.Code != "" {
+			messages[i] = e.Extensions.Code + ": " + e.Message
+		}
+	}
+	return apperr.Wrap(graphQLErrorKind(response.Errors[0].Extensions.Code), fmt.Errorf("GraphQL error: %s", strings.Join(messages, "; ")))
 }
 
-// makeGraphQLRequest performs GraphQL requests to RunPod API
-func (c *Client) makeGraphQLRequest(query string, variables interface{}, result interface{}) error {
+// graphQLErrorKind classifies a GraphQL error by its extensions code
+func graphQLErrorKind(code string) apperr.Kind {
+	switch code {
+	case "BAD_USER_INPUT", "GRAPHQL_VALIDATION_FAILED":
+		return apperr.InvalidArgument
+	case "UNAUTHENTICATED", "FORBIDDEN":
+		return apperr.ProviderUnauthorized
+	case "NOT_FOUND":
+		return apperr.NotFound
+	default:
+		return apperr.Upstream
+	}
+}
+
+// makeGraphQLRequest performs GraphQL requests to RunPod API, retrying network errors,
+// 429s and server errors. It must only be used for operations that are safe to repeat.
+func (c *Client) makeGraphQLRequest(ctx context.Context, query string, variables interface{}, result interface{}) error {
+	return c.send(ctx, c.retryPolicy, query, variables, result)
+}
+
+// makeGraphQLRequestOnce performs an operation that is never retried, such as creating
+// a pod, which could create a second pod if repeated after a lost response
+func (c *Client) makeGraphQLRequestOnce(ctx context.Context, query string, variables interface{}, result interface{}) error {
+	return c.send(ctx, retry.Policy{}, query, variables, result)
+}
+
+// send makes the request, retrying it as policy allows
+func (c *Client) send(ctx context.Context, policy retry.Policy, query string, variables interface{}, result interface{}) error {
 	requestBody := map[string]interface{}{
 		"query": query,
 	}
@@ -361,7 +425,15 @@ func (c *Client) makeGraphQLRequest(query string, variables interface{}, result
 		return fmt.Errorf("error marshaling request body: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL, bytes.NewBuffer(jsonData))
+	operation := graphQLOperation(query)
+	return policy.Do(ctx, func() error {
+		return c.attempt(ctx, operation, jsonData, result)
+	})
+}
+
+// attempt makes one request, marking errors that may go away on a retry
+func (c *Client) attempt(ctx context.Context, operation string, jsonData []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewReader(jsonData))
 	if err != nil {
 		return fmt.Errorf("error creating request: %v", err)
 	}
@@ -371,21 +443,31 @@ func (c *Client) makeGraphQLRequest(query string, variables interface{}, result
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
 	// Make the request
+	start := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error making request: %v", err)
+		logging.Upstream(ctx, string(types.RunPod), "POST", operation, 0, start, err, c.apiKey)
+		return retry.Retryable(apperr.Wrap(apperr.Unavailable, fmt.Errorf("error making request: %v", err)), 0)
 	}
 	defer resp.Body.Close()
 
-	// Read response body
+	// Read response body and check for HTTP errors. RunPod reports failed operations in
+	// an errors array, usually with status 200.
 	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response body: %v", err)
+	switch {
+	case err != nil:
+		err = retry.Retryable(apperr.Wrap(apperr.Unavailable, fmt.Errorf("error reading response body: %v", err)), 0)
+	case resp.StatusCode >= 400:
+		err = apperr.Wrap(apperr.FromHTTPStatus(resp.StatusCode), fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody)))
+		if retry.RetryableStatus(resp.StatusCode) {
+			err = retry.Retryable(err, retry.RetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+		}
+	default:
+		err = graphQLErrors(respBody)
 	}
-
-	// Check for HTTP errors
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+	logging.Upstream(ctx, string(types.RunPod), "POST", operation, resp.StatusCode, start, err, c.apiKey)
+	if err != nil {
+		return err
 	}
 
 	// Parse response
@@ -452,15 +534,36 @@ func ConvertPodToGPUInstance(pod RunPodPod) types.GPUInstance {
 		if len(pod.Runtime.Ports) > 0 {
 			instance.ProviderData["ports"] = pod.Runtime.Ports
 		}
+
+		for _, port := range pod.Runtime.Ports {
+			if port.PrivatePort == 22 && port.IsIpPublic {
+				instance.SSH = &types.SSHEndpoint{Host: port.IP, Port: port.PublicPort}
+				break
+			}
+		}
 		
 		if len(pod.Runtime.GPUs) > 0 {
 			instance.ProviderData["gpu_utilization"] = pod.Runtime.GPUs
+			instance.Utilization = averageUtilization(pod.Runtime.GPUs)
 		}
 	}
 
 	return instance
 }
 
+// averageUtilization averages the utilization reported for each of a pod's GPUs
+func averageUtilization(gpus []GPUInfo) *types.GPUUtilization {
+	var gpu, memory int
+	for _, info := range gpus {
+		gpu += info.GPUUtilPercent
+		memory += info.MemoryUtilPercent
+	}
+	return &types.GPUUtilization{
+		GPUPercent:    float64(gpu) / float64(len(gpus)),
+		MemoryPercent: float64(memory) / float64(len(gpus)),
+	}
+}
+
 // ConvertGPUTypeToGPUInstance converts a RunPod GPU type to a searchable instance
 func ConvertGPUTypeToGPUInstance(gpuType GPUType) types.GPUInstance {
 	instance := types.GPUInstance{
@@ -487,3 +590,15 @@ func ConvertGPUTypeToGPUInstance(gpuType GPUType) types.GPUInstance {
 
 	return instance
 }
+
+// graphQLOperation names the operation of query for logging, e.g. "mutation stopPod",
+// from its type and its name, or its first field when it is anonymous
+func graphQLOperation(query string) string {
+	fields := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if len(fields) < 2 {
+		return "graphql"
+	}
+	return fields[0] + " " + fields[1]
+}
@@ -0,0 +1,80 @@
+package runpod
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/retry"
+)
+
+// newTestClient returns a client for server that retries without waiting
+func newTestClient(server *httptest.Server) *Client {
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+	client.SetRetryPolicy(retry.Policy{MaxRetries: 2, BaseDelay: time.Millisecond})
+	return client
+}
+
+func TestGraphQLErrorsAreReported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": null, "errors": [{"message": "Pod not found", "extensions": {"code": "NOT_FOUND"}}]}`))
+	}))
+	defer server.Close()
+
+	err := newTestClient(server).StopPod(context.Background(), "abc123")
+	if err == nil || !strings.Contains(err.Error(), "NOT_FOUND: Pod not found") {
+		t.Errorf("Expected the GraphQL error to be returned, got %v", err)
+	}
+	if kind := apperr.KindOf(err); kind != apperr.NotFound {
+		t.Errorf("Expected a %s error, got %s", apperr.NotFound, kind)
+	}
+}
+
+func TestRequestsRetryServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"data": {"podStop": {"id": "abc123", "desiredStatus": "EXITED"}}}`))
+	}))
+	defer server.Close()
+
+	if err := newTestClient(server).StopPod(context.Background(), "abc123"); err != nil || attempts != 2 {
+		t.Errorf("Expected success on the second attempt, got %v after %d", err, attempts)
+	}
+}
+
+func TestCreatePodIsNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server).CreatePod(context.Background(), &CreatePodRequest{Name: "test"})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected a single failed attempt, got %v after %d", err, attempts)
+	}
+}
+
+func TestGraphQLOperation(t *testing.T) {
+	tests := map[string]string{
+		"\n\tmutation stopPod($input: PodStopInput!) {\n\t\tpodStop(input: $input) { id }\n\t}": "mutation stopPod",
+		"query {\n\t\tmyself { pods { id } }\n\t}":                                              "query myself",
+		"": "graphql",
+	}
+	for query, expected := range tests {
+		if got := graphQLOperation(query); got != expected {
+			t.Errorf("Expected operation %q, got %q", expected, got)
+		}
+	}
+}
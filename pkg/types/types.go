@@ -15,6 +15,14 @@ const (
 	Azure      GPUProvider = "azure"
 )
 
+// InstanceIDPrefix returns the prefix used for external instance IDs of this provider
+func (p GPUProvider) InstanceIDPrefix() string {
+	if p == VastAI {
+		return "vast_"
+	}
+	return string(p) + "_"
+}
+
 // GPUModel represents different GPU models with their specifications
 type GPUModel struct {
 	Name        string  `json:"name"`
@@ -83,6 +91,23 @@ type GPUInstance struct {
 	Performance    int                    `json:"performance_score,omitempty"`
 	Reliability    float64                `json:"reliability,omitempty"`
 	NetworkSpeed   *NetworkInfo           `json:"network_info,omitempty"`
+	SSH            *SSHEndpoint           `json:"ssh,omitempty"`
+	Utilization    *GPUUtilization        `json:"utilization,omitempty"`
+	ExpiresAt      *time.Time             `json:"expires_at,omitempty"`
+	ExpiryAction   ExpiryAction           `json:"expiry_action,omitempty"`
+	AccruedCost    float64                `json:"accrued_cost,omitempty"` // Cost of the time spent running so far, for rented instances
+}
+
+// GPUUtilization is the load on an instance's GPUs, averaged across them
+type GPUUtilization struct {
+	GPUPercent    float64 `json:"gpu_percent"`
+	MemoryPercent float64 `json:"memory_percent"`
+}
+
+// SSHEndpoint represents where an instance accepts SSH connections
+type SSHEndpoint struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
 }
 
 // NetworkInfo represents network capabilities
@@ -103,6 +128,7 @@ const (
 	StatusStarting    InstanceStatus = "starting"
 	StatusStopping    InstanceStatus = "stopping"
 	StatusError       InstanceStatus = "error"
+	StatusTerminated  InstanceStatus = "terminated"
 )
 
 // CreateInstanceRequest represents a request to create a new GPU instance
@@ -116,6 +142,78 @@ type CreateInstanceRequest struct {
 	Environment   map[string]string  `json:"environment,omitempty"`
 	Ports         []PortMapping      `json:"ports,omitempty"`
 	Resources     *ResourceRequests  `json:"resources,omitempty"`
+	ExpiresAt     *time.Time         `json:"expires_at,omitempty"`    // When the instance is stopped or destroyed automatically
+	MaxRuntime    string             `json:"max_runtime,omitempty"`   // Alternative to expires_at, counted from launch (e.g. "8h", "2d")
+	ExpiryAction  ExpiryAction       `json:"expiry_action,omitempty"` // What happens on expiry (default stop)
+}
+
+// ExpiryAction is what happens to an instance when it expires
+type ExpiryAction string
+
+const (
+	ExpiryStop    ExpiryAction = "stop"
+	ExpiryDestroy ExpiryAction = "destroy"
+)
+
+// ExpiryRequest changes when an instance expires or what happens then
+type ExpiryRequest struct {
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"` // New expiry time
+	ExtendBy     string       `json:"extend_by,omitempty"`  // Or push the current expiry back, counting from now if it has none (e.g. "4h")
+	ExpiryAction ExpiryAction `json:"expiry_action,omitempty"`
+	Clear        bool         `json:"clear,omitempty"` // Remove the expiry instead
+}
+
+// ProvisionRequest rents the best-ranked offer matching Filter, launched like CreateInstanceRequest
+type ProvisionRequest struct {
+	Filter        AdvancedSearchFilter `json:"filter"`
+	Image         string               `json:"image"`
+	OnStartScript string               `json:"onstart_script,omitempty"`
+	SSHKey        string               `json:"ssh_key,omitempty"`
+	Label         string               `json:"label,omitempty"`
+	Environment   map[string]string    `json:"environment,omitempty"`
+	Ports         []PortMapping        `json:"ports,omitempty"`
+	Resources     *ResourceRequests    `json:"resources,omitempty"`
+	ExpiresAt     *time.Time           `json:"expires_at,omitempty"`
+	MaxRuntime    string               `json:"max_runtime,omitempty"`
+	ExpiryAction  ExpiryAction         `json:"expiry_action,omitempty"`
+	MaxAttempts   int                  `json:"max_attempts,omitempty"` // Offers to try before giving up (default 3, at most 10)
+}
+
+// ProvisionAttempt records one offer tried while provisioning
+type ProvisionAttempt struct {
+	Provider     GPUProvider `json:"provider"`
+	OfferID      string      `json:"offer_id"`
+	PricePerHour float64     `json:"price_per_hour"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// ProvisionResult is the instance provisioning created and the offers it tried on the way
+type ProvisionResult struct {
+	Instance *GPUInstance       `json:"instance,omitempty"`
+	Attempts []ProvisionAttempt `json:"attempts"`
+}
+
+// ProviderCredentialRequest represents a request to add provider credentials
+type ProviderCredentialRequest struct {
+	Provider  GPUProvider            `json:"provider" binding:"required"`
+	APIKey    string                 `json:"api_key" binding:"required"`
+	IsEnabled *bool                  `json:"is_enabled,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+}
+
+// UpdateProviderCredentialRequest represents a request to rotate, enable or disable provider credentials
+type UpdateProviderCredentialRequest struct {
+	APIKey    string                 `json:"api_key,omitempty"`
+	IsEnabled *bool                  `json:"is_enabled,omitempty"`
+	Config    map[string]interface{} `json:"config,omitempty"`
+}
+
+// CredentialValidation represents the result of checking provider credentials against the provider
+type CredentialValidation struct {
+	Provider  GPUProvider `json:"provider"`
+	Valid     bool        `json:"valid"`
+	Error     string      `json:"error,omitempty"`
+	CheckedAt time.Time   `json:"checked_at"`
 }
 
 // PortMapping represents port forwarding configuration
@@ -191,6 +289,34 @@ type APIResponse struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"` // Machine-readable error code, see apperr.Kind
+	Meta    interface{} `json:"meta,omitempty"`
+}
+
+// ProviderQueryStatus is the outcome of querying one provider
+type ProviderQueryStatus string
+
+const (
+	ProviderQueryOK      ProviderQueryStatus = "ok"
+	ProviderQueryError   ProviderQueryStatus = "error"
+	ProviderQueryTimeout ProviderQueryStatus = "timeout"
+)
+
+// ProviderSearchStatus reports how a single provider responded to an offer search
+type ProviderSearchStatus struct {
+	Provider   GPUProvider         `json:"provider"`
+	Status     ProviderQueryStatus `json:"status"`
+	LatencyMS  int64               `json:"latency_ms"`
+	Count      int                 `json:"count"`
+	Cached     bool                `json:"cached"`      // Offers were served from the offer cache
+	AgeSeconds int64               `json:"age_seconds"` // Time since the offers were fetched from the provider
+	Error      string              `json:"error,omitempty"`
+}
+
+// SearchMeta accompanies offer search results
+type SearchMeta struct {
+	Providers      []ProviderSearchStatus `json:"providers"`
+	DataAgeSeconds int64                  `json:"data_age_seconds"` // Age of the oldest offers in the results
 }
 
 // PaginatedResponse represents a paginated API response
@@ -223,3 +349,112 @@ type PriceRange struct {
 	Max float64 `json:"max"`
 	Avg float64 `json:"avg"`
 }
+
+// AlertRuleRequest creates or replaces a price alert rule
+type AlertRuleRequest struct {
+	Name           string               `json:"name" binding:"required"`
+	Filter         AdvancedSearchFilter `json:"filter"`
+	PriceThreshold float64              `json:"price_threshold" binding:"required,gt=0"`
+	WebhookURL     string               `json:"webhook_url" binding:"required"`
+	IsEnabled      *bool                `json:"is_enabled,omitempty"`
+}
+
+// PriceAlertEvent is the webhook payload sent when an offer matches an alert rule
+type PriceAlertEvent struct {
+	Event          string      `json:"event"`
+	RuleID         uint        `json:"rule_id"`
+	RuleName       string      `json:"rule_name"`
+	PriceThreshold float64     `json:"price_threshold"`
+	Offer          GPUInstance `json:"offer"`
+	TriggeredAt    time.Time   `json:"triggered_at"`
+}
+
+// PriceHistory is a time series of recorded marketplace prices for a GPU model
+type PriceHistory struct {
+	GPUModel string        `json:"gpu_model"`
+	Provider GPUProvider   `json:"provider,omitempty"`
+	Interval int64         `json:"interval_seconds"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Buckets  []PriceBucket `json:"buckets"`
+}
+
+// PriceBucket summarizes the hourly prices per GPU recorded within one interval
+type PriceBucket struct {
+	Start   time.Time `json:"start"`
+	Samples int       `json:"samples"`
+	Min     float64   `json:"min"`
+	P10     float64   `json:"p10"`
+	P25     float64   `json:"p25"`
+	Median  float64   `json:"median"`
+	P75     float64   `json:"p75"`
+	P90     float64   `json:"p90"`
+	Max     float64   `json:"max"`
+}
+
+// IdlePolicyRequest sets when an instance is stopped or destroyed automatically
+type IdlePolicyRequest struct {
+	UtilizationThreshold     float64 `json:"utilization_threshold_percent" binding:"gte=0,lte=100"` // GPU utilization below which the instance counts as idle
+	IdleMinutes              int     `json:"idle_minutes" binding:"gte=0"`                          // Stop after this long idle; 0 never stops
+	DestroyAfterStoppedHours int     `json:"destroy_after_stopped_hours" binding:"gte=0"`           // Destroy after this long stopped; 0 never destroys
+	GraceMinutes             int     `json:"grace_minutes" binding:"gte=0"`                         // How long before acting a warning event is recorded
+	IsEnabled                *bool   `json:"is_enabled,omitempty"`
+}
+
+// SpendReport is what was spent on instances over a period, grouped by one attribute
+type SpendReport struct {
+	GroupBy   string       `json:"group_by"`
+	From      time.Time    `json:"from"`
+	To        time.Time    `json:"to"`
+	TotalCost float64      `json:"total_cost"`
+	Groups    []SpendGroup `json:"groups"`
+}
+
+// SpendGroup is the spend of the instances sharing one user, provider, GPU model or label
+type SpendGroup struct {
+	Key          string  `json:"key"`
+	Cost         float64 `json:"cost"`
+	RunningHours float64 `json:"running_hours"`
+	StoppedHours float64 `json:"stopped_hours"` // Storage billed while stopped isn't included in cost
+	Instances    int     `json:"instances"`
+}
+
+// TeamRequest creates a team
+type TeamRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// BudgetRequest creates or replaces the budget of a user or a team. Limits left at 0
+// don't apply.
+type BudgetRequest struct {
+	UserID         *uint   `json:"user_id,omitempty"` // Set exactly one of user_id and team_id
+	TeamID         *uint   `json:"team_id,omitempty"`
+	MonthlyLimit   float64 `json:"monthly_limit" binding:"gte=0"`
+	MaxGPUs        int     `json:"max_gpus" binding:"gte=0"`
+	MaxHourlySpend float64 `json:"max_hourly_spend" binding:"gte=0"`
+	HardCap        bool    `json:"hard_cap"` // Stop running instances once monthly_limit is reached
+	WebhookURL     string  `json:"webhook_url,omitempty"`
+}
+
+// BudgetUsage is how much of a budget is used in the current month
+type BudgetUsage struct {
+	Period         string  `json:"period"` // Month, as YYYY-MM (UTC)
+	MonthToDate    float64 `json:"month_to_date"`
+	ProjectedSpend float64 `json:"projected_spend"` // Month to date plus running instances until they expire or the month ends
+	GPUsInUse      int     `json:"gpus_in_use"`
+	HourlySpend    float64 `json:"hourly_spend"`
+}
+
+// BudgetThresholdEvent is the webhook payload sent when spend crosses a share of a budget's monthly limit
+type BudgetThresholdEvent struct {
+	Event            string    `json:"event"`
+	BudgetID         uint      `json:"budget_id"`
+	UserID           *uint     `json:"user_id,omitempty"`
+	TeamID           *uint     `json:"team_id,omitempty"`
+	Period           string    `json:"period"`
+	ThresholdPercent int       `json:"threshold_percent"`
+	MonthlyLimit     float64   `json:"monthly_limit"`
+	Spend            float64   `json:"spend"`
+	HardCap          bool      `json:"hard_cap"`
+	TriggeredAt      time.Time `json:"triggered_at"`
+}
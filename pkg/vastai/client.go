@@ -2,6 +2,7 @@ package vastai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,9 @@ import (
 	"strconv"
 	"time"
 
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/logging"
+	"gpu-cloud-manager/pkg/retry"
 	"gpu-cloud-manager/pkg/types"
 )
 
@@ -19,9 +23,10 @@ const (
 
 // Client represents a Vast.ai API client
 type Client struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
+	apiKey      string
+	baseURL     string
+	client      *http.Client
+	retryPolicy retry.Policy
 }
 
 // NewClient creates a new Vast.ai API client
@@ -32,9 +37,15 @@ func NewClient(apiKey string) *Client {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retryPolicy: retry.DefaultPolicy(),
 	}
 }
 
+// SetRetryPolicy sets how calls that are safe to repeat are retried
+func (c *Client) SetRetryPolicy(policy retry.Policy) {
+	c.retryPolicy = policy
+}
+
 // VastOffer represents a GPU offer from Vast.ai
 type VastOffer struct {
 	ID                int     `json:"id"`
@@ -81,7 +92,7 @@ type VastInstance struct {
 }
 
 // SearchOffers searches for available GPU offers
-func (c *Client) SearchOffers(filter *SearchOffersRequest) ([]VastOffer, error) {
+func (c *Client) SearchOffers(ctx context.Context, filter *SearchOffersRequest) ([]VastOffer, error) {
 	endpoint := "/bundles"
 	
 	// Build query parameters
@@ -110,24 +121,39 @@ func (c *Client) SearchOffers(filter *SearchOffersRequest) ([]VastOffer, error)
 	}
 
 	var offers []VastOffer
-	err := c.makeRequest("GET", endpoint+"?"+params.Encode(), nil, &offers)
+	err := c.makeRequest(ctx, "GET", endpoint+"?"+params.Encode(), nil, &offers)
 	return offers, err
 }
 
+// GetOffer retrieves a single offer by ID
+func (c *Client) GetOffer(ctx context.Context, offerID int) (*VastOffer, error) {
+	params := url.Values{}
+	params.Set("q", fmt.Sprintf("id:%d", offerID))
+
+	var offers []VastOffer
+	if err := c.makeRequest(ctx, "GET", "/bundles?"+params.Encode(), nil, &offers); err != nil {
+		return nil, err
+	}
+	if len(offers) == 0 {
+		return nil, apperr.Errorf(apperr.NotFound, "offer %d not found", offerID)
+	}
+	return &offers[0], nil
+}
+
 // GetInstances retrieves user's rented instances
-func (c *Client) GetInstances() ([]VastInstance, error) {
+func (c *Client) GetInstances(ctx context.Context) ([]VastInstance, error) {
 	endpoint := "/instances"
 	
 	var response struct {
 		Instances []VastInstance `json:"instances"`
 	}
 	
-	err := c.makeRequest("GET", endpoint, nil, &response)
+	err := c.makeRequest(ctx, "GET", endpoint, nil, &response)
 	return response.Instances, err
 }
 
 // CreateInstance creates a new GPU instance
-func (c *Client) CreateInstance(request *CreateInstanceRequest) (*VastInstance, error) {
+func (c *Client) CreateInstance(ctx context.Context, request *CreateInstanceRequest) (*VastInstance, error) {
 	endpoint := "/asks/" + strconv.Itoa(request.OfferID) + "/"
 	
 	payload := map[string]interface{}{
@@ -141,53 +167,94 @@ func (c *Client) CreateInstance(request *CreateInstanceRequest) (*VastInstance,
 		payload["onstart"] = request.OnStartScript
 	}
 	
-	var instance VastInstance
-	err := c.makeRequest("PUT", endpoint, payload, &instance)
-	return &instance, err
+	// Vast.ai answers with the new contract ID rather than the full instance
+	var response struct {
+		Success     bool `json:"success"`
+		NewContract int  `json:"new_contract"`
+	}
+	
+	if err := c.makeRequestOnce(ctx, "PUT", endpoint, payload, &response); err != nil {
+		return nil, err
+	}
+	if !response.Success || response.NewContract == 0 {
+		return nil, apperr.Errorf(apperr.Conflict, "offer %d was not accepted", request.OfferID)
+	}
+	
+	return &VastInstance{
+		ID:             response.NewContract,
+		Label:          request.Label,
+		Image:          request.Image,
+		OnStartScript:  request.OnStartScript,
+		ActualStatus:   "loading",
+		IntendedStatus: "running",
+	}, nil
 }
 
 // DestroyInstance terminates a GPU instance
-func (c *Client) DestroyInstance(instanceID int) error {
+func (c *Client) DestroyInstance(ctx context.Context, instanceID int) error {
 	endpoint := fmt.Sprintf("/instances/%d/", instanceID)
-	return c.makeRequest("DELETE", endpoint, nil, nil)
+	return c.makeRequest(ctx, "DELETE", endpoint, nil, nil)
 }
 
 // StartInstance starts a stopped instance
-func (c *Client) StartInstance(instanceID int) error {
+func (c *Client) StartInstance(ctx context.Context, instanceID int) error {
 	endpoint := fmt.Sprintf("/instances/%d/", instanceID)
 	payload := map[string]string{"state": "running"}
-	return c.makeRequest("PUT", endpoint, payload, nil)
+	return c.makeRequest(ctx, "PUT", endpoint, payload, nil)
 }
 
 // StopInstance stops a running instance
-func (c *Client) StopInstance(instanceID int) error {
+func (c *Client) StopInstance(ctx context.Context, instanceID int) error {
 	endpoint := fmt.Sprintf("/instances/%d/", instanceID)
 	payload := map[string]string{"state": "stopped"}
-	return c.makeRequest("PUT", endpoint, payload, nil)
+	return c.makeRequest(ctx, "PUT", endpoint, payload, nil)
 }
 
 // GetInstance retrieves details of a specific instance
-func (c *Client) GetInstance(instanceID int) (*VastInstance, error) {
+func (c *Client) GetInstance(ctx context.Context, instanceID int) (*VastInstance, error) {
 	endpoint := fmt.Sprintf("/instances/%d/", instanceID)
 	
 	var instance VastInstance
-	err := c.makeRequest("GET", endpoint, nil, &instance)
+	err := c.makeRequest(ctx, "GET", endpoint, nil, &instance)
 	return &instance, err
 }
 
-// makeRequest performs HTTP requests to Vast.ai API
-func (c *Client) makeRequest(method, endpoint string, payload interface{}, result interface{}) error {
-	var body io.Reader
-	
+// makeRequest performs HTTP requests to Vast.ai API, retrying network errors, 429s and
+// server errors. It must only be used for calls that are safe to repeat.
+func (c *Client) makeRequest(ctx context.Context, method, endpoint string, payload interface{}, result interface{}) error {
+	return c.send(ctx, c.retryPolicy, method, endpoint, payload, result)
+}
+
+// makeRequestOnce performs a request that is never retried, such as renting an offer,
+// which could rent a second instance if repeated after a lost response
+func (c *Client) makeRequestOnce(ctx context.Context, method, endpoint string, payload interface{}, result interface{}) error {
+	return c.send(ctx, retry.Policy{}, method, endpoint, payload, result)
+}
+
+// send makes the request, retrying it as policy allows
+func (c *Client) send(ctx context.Context, policy retry.Policy, method, endpoint string, payload interface{}, result interface{}) error {
+	var jsonData []byte
 	if payload != nil {
-		jsonData, err := json.Marshal(payload)
+		var err error
+		jsonData, err = json.Marshal(payload)
 		if err != nil {
 			return fmt.Errorf("error marshaling request body: %v", err)
 		}
-		body = bytes.NewBuffer(jsonData)
 	}
 	
-	req, err := http.NewRequest(method, c.baseURL+endpoint, body)
+	return policy.Do(ctx, func() error {
+		return c.attempt(ctx, method, endpoint, jsonData, result)
+	})
+}
+
+// attempt makes one request, marking errors that may go away on a retry
+func (c *Client) attempt(ctx context.Context, method, endpoint string, jsonData []byte, result interface{}) error {
+	var body io.Reader
+	if jsonData != nil {
+		body = bytes.NewReader(jsonData)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, body)
 	if err != nil {
 		return fmt.Errorf("error creating request: %v", err)
 	}
@@ -197,21 +264,28 @@ func (c *Client) makeRequest(method, endpoint string, payload interface{}, resul
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	
 	// Make the request
+	start := time.Now()
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error making request: %v", err)
+		logging.Upstream(ctx, string(types.VastAI), method, endpoint, 0, start, err, c.apiKey)
+		return retry.Retryable(apperr.Wrap(apperr.Unavailable, fmt.Errorf("error making request: %v", err)), 0)
 	}
 	defer resp.Body.Close()
 	
-	// Read response body
+	// Read response body and check for HTTP errors
 	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("error reading response body: %v", err)
+	switch {
+	case err != nil:
+		err = retry.Retryable(apperr.Wrap(apperr.Unavailable, fmt.Errorf("error reading response body: %v", err)), 0)
+	case resp.StatusCode >= 400:
+		err = apperr.Wrap(apperr.FromHTTPStatus(resp.StatusCode), fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody)))
+		if retry.RetryableStatus(resp.StatusCode) {
+			err = retry.Retryable(err, retry.RetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+		}
 	}
-	
-	// Check for HTTP errors
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(respBody))
+	logging.Upstream(ctx, string(types.VastAI), method, endpoint, resp.StatusCode, start, err, c.apiKey)
+	if err != nil {
+		return err
 	}
 	
 	// Parse response if result is provided
@@ -299,11 +373,17 @@ func ConvertInstanceToGPUInstance(instance VastInstance) types.GPUInstance {
 		status = types.StatusOffline
 	}
 
+	var ssh *types.SSHEndpoint
+	if instance.SSHHost != "" && instance.SSHPort > 0 {
+		ssh = &types.SSHEndpoint{Host: instance.SSHHost, Port: instance.SSHPort}
+	}
+
 	return types.GPUInstance{
 		ID:           fmt.Sprintf("vast_%d", instance.ID),
 		Provider:     types.VastAI,
 		ProviderID:   strconv.Itoa(instance.ID),
 		Name:         instance.Label,
+		SSH:          ssh,
 		Status:       status,
 		PricePerHour: instance.PricePerHour,
 		ProviderData: map[string]interface{}{
@@ -1,8 +1,14 @@
 package vastai
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"gpu-cloud-manager/pkg/apperr"
+	"gpu-cloud-manager/pkg/retry"
 	"gpu-cloud-manager/pkg/types"
 )
 
@@ -146,6 +152,10 @@ func TestConvertInstanceToGPUInstance(t *testing.T) {
 	if instance.ProviderData["ssh_port"] != 12345 {
 		t.Errorf("Expected SSH port in provider data to be 12345, got %v", instance.ProviderData["ssh_port"])
 	}
+
+	if instance.SSH == nil || instance.SSH.Host != "ssh5.vast.ai" || instance.SSH.Port != 12345 {
+		t.Errorf("Expected SSH endpoint ssh5.vast.ai:12345, got %+v", instance.SSH)
+	}
 }
 
 func TestSearchOffersRequest(t *testing.T) {
@@ -193,3 +203,132 @@ func TestCreateInstanceRequest(t *testing.T) {
 		t.Errorf("Expected image to be 'pytorch/pytorch:latest', got %s", req.Image)
 	}
 }
+
+func TestRequestsHonourContextCancellation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GetInstances(ctx)
+	if err == nil {
+		t.Fatal("Expected an error when the context deadline passes")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected request to stop at the context deadline, took %s", elapsed)
+	}
+}
+
+func TestRequestsRetryServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch attempts {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`{"instances": [{"id": 42}]}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+	client.SetRetryPolicy(retry.Policy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second})
+
+	instances, err := client.GetInstances(context.Background())
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got %v", err)
+	}
+	if attempts != 3 || len(instances) != 1 || instances[0].ID != 42 {
+		t.Errorf("Expected instance 42 after 3 attempts, got %v after %d", instances, attempts)
+	}
+}
+
+func TestRequestsDontRetryClientErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+	client.SetRetryPolicy(retry.Policy{MaxRetries: 2, BaseDelay: time.Millisecond})
+
+	_, err := client.GetInstance(context.Background(), 1)
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected a single failed attempt, got %v after %d", err, attempts)
+	}
+	if kind := apperr.KindOf(err); kind != apperr.NotFound {
+		t.Errorf("Expected a %s error, got %s", apperr.NotFound, kind)
+	}
+}
+
+func TestCreateInstanceIsNotRetried(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+	client.SetRetryPolicy(retry.Policy{MaxRetries: 2, BaseDelay: time.Millisecond})
+
+	_, err := client.CreateInstance(context.Background(), &CreateInstanceRequest{OfferID: 1, Image: "pytorch/pytorch:latest"})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected a single failed attempt, got %v after %d", err, attempts)
+	}
+}
+
+func TestCreateInstanceNotAccepted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success": false}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+
+	_, err := client.CreateInstance(context.Background(), &CreateInstanceRequest{OfferID: 1, Image: "pytorch/pytorch:latest"})
+	if kind := apperr.KindOf(err); kind != apperr.Conflict {
+		t.Errorf("Expected a %s error, got %s (%v)", apperr.Conflict, kind, err)
+	}
+}
+
+func TestGetOffer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "id:12345" {
+			w.Write([]byte(`[{"id": 12345, "num_gpus": 2, "dph_total": 1.5}]`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test_api_key")
+	client.baseURL = server.URL
+
+	offer, err := client.GetOffer(context.Background(), 12345)
+	if err != nil || offer.ID != 12345 {
+		t.Fatalf("Expected offer 12345, got %+v (%v)", offer, err)
+	}
+
+	_, err = client.GetOffer(context.Background(), 1)
+	if kind := apperr.KindOf(err); kind != apperr.NotFound {
+		t.Errorf("Expected a %s error, got %s (%v)", apperr.NotFound, kind, err)
+	}
+}
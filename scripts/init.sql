@@ -4,16 +4,8 @@
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 CREATE EXTENSION IF NOT EXISTS "pg_trgm";
 
--- Create a default admin user (optional)
-INSERT INTO users (email, name, api_key, is_active, created_at, updated_at)
-VALUES (
-    'admin@gpu-cloud-manager.com',
-    'System Administrator',
-    'admin-api-key-' || uuid_generate_v4(),
-    true,
-    NOW(),
-    NOW()
-) ON CONFLICT (email) DO NOTHING;
+-- The default admin user is created by the application on startup when
+-- ADMIN_API_KEY is set. Only a SHA-256 hash of API keys is ever stored.
 
 -- Create indexes for better performance
 CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_instances_user_provider_status 